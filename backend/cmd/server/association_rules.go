@@ -0,0 +1,285 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/bits"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Association rules over response patterns. Every student is a transaction holding the item
+// "Qj=0" for each missed question and/or "Qj=1" for each correct one; itemsets are bit masks
+// over those 2·numQuestions items.
+
+// ruleItemLabel returns the label of item bit b: bits 0..9 are "Q1=0".."Q10=0" and bits 10..19
+// are "Q1=1".."Q10=1"
+func ruleItemLabel(b int) string {
+	return fmt.Sprintf("Q%d=%d", b%numQuestions+1, b/numQuestions)
+}
+
+// parseRuleItems parses "q3=0,q5=0" into an itemset mask
+func parseRuleItems(s string) (uint32, error) {
+	var mask uint32
+	for _, part := range strings.Split(s, ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 || (kv[1] != "0" && kv[1] != "1") {
+			return 0, fmt.Errorf("item %q must have the form q1=0 or q1=1", part)
+		}
+		q, err := parseQuestion(kv[0])
+		if err != nil {
+			return 0, err
+		}
+		b := q - 1
+		if kv[1] == "1" {
+			b += numQuestions
+		}
+		mask |= 1 << uint(b)
+	}
+	return mask, nil
+}
+
+// maskLabels returns the labels of the items in a mask in bit order
+func maskLabels(mask uint32) []string {
+	labels := []string{}
+	for b := 0; b < 2*numQuestions; b++ {
+		if mask&(1<<uint(b)) != 0 {
+			labels = append(labels, ruleItemLabel(b))
+		}
+	}
+	return labels
+}
+
+// aprioriItemsets returns the support count of every itemset of at most maxLength items whose
+// support count is at least minCount, growing candidates level by level and pruning any
+// candidate with an infrequent subset (Agrawal & Srikant 1994)
+func aprioriItemsets(transactions []uint32, allowed uint32, minCount, maxLength int) map[uint32]int {
+	support := func(mask uint32) int {
+		count := 0
+		for _, t := range transactions {
+			if t&mask == mask {
+				count++
+			}
+		}
+		return count
+	}
+
+	frequent := make(map[uint32]int)
+	var level []uint32
+	for b := 0; b < 2*numQuestions; b++ {
+		mask := uint32(1) << uint(b)
+		if allowed&mask == 0 {
+			continue
+		}
+		if count := support(mask); count >= minCount {
+			frequent[mask] = count
+			level = append(level, mask)
+		}
+	}
+
+	for length := 2; length <= maxLength && len(level) > 1; length++ {
+		seen := make(map[uint32]bool)
+		var next []uint32
+		for i := 0; i < len(level); i++ {
+			for j := i + 1; j < len(level); j++ {
+				candidate := level[i] | level[j]
+				if bits.OnesCount32(candidate) != length || seen[candidate] {
+					continue
+				}
+				seen[candidate] = true
+				pruned := false
+				for rest := candidate; rest != 0; rest &= rest - 1 {
+					if _, ok := frequent[candidate&^(rest&-rest)]; !ok {
+						pruned = true
+						break
+					}
+				}
+				if pruned {
+					continue
+				}
+				if count := support(candidate); count >= minCount {
+					frequent[candidate] = count
+					next = append(next, candidate)
+				}
+			}
+		}
+		level = next
+	}
+	return frequent
+}
+
+// AssociationRule represents a rule antecedent ⇒ consequent
+type AssociationRule struct {
+	Antecedent []string `json:"antecedent"`
+	Consequent []string `json:"consequent"`
+	Count      int      `json:"count"`
+	Support    float64  `json:"support"`
+	Confidence float64  `json:"confidence"`
+	Lift       float64  `json:"lift"`
+}
+
+// AssociationRulesResponse represents mined association rules
+type AssociationRulesResponse struct {
+	Items            string            `json:"items"`
+	N                int               `json:"n"`
+	MinSupport       float64           `json:"min_support"`
+	MinConfidence    float64           `json:"min_confidence"`
+	MinLift          float64           `json:"min_lift"`
+	MaxLength        int               `json:"max_length"`
+	Antecedent       []string          `json:"antecedent,omitempty"`
+	FrequentItemsets int               `json:"frequent_itemsets"`
+	TotalRules       int               `json:"total_rules"`
+	Rules            []AssociationRule `json:"rules"`
+}
+
+// Handler: Get association rules
+// Mines rules such as {Q3=0, Q5=0} ⇒ {Q7=0} over missed and/or correct items with Apriori,
+// optionally keeping only rules whose antecedent contains the given items
+func getAssociationRules(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	items := r.URL.Query().Get("items")
+	if items == "" {
+		items = "incorrect"
+	}
+	var allowed uint32
+	switch items {
+	case "incorrect":
+		allowed = 1<<numQuestions - 1
+	case "correct":
+		allowed = (1<<numQuestions - 1) << numQuestions
+	case "both":
+		allowed = 1<<(2*numQuestions) - 1
+	default:
+		http.Error(w, "Invalid 'items' parameter (must be incorrect, correct or both)", http.StatusBadRequest)
+		return
+	}
+	minSupport, err := floatParam(r, "min_support", 0.1)
+	if err != nil || minSupport <= 0 || minSupport > 1 {
+		http.Error(w, "Invalid 'min_support' parameter (must be in (0, 1])", http.StatusBadRequest)
+		return
+	}
+	minConfidence, err := floatParam(r, "min_confidence", 0.6)
+	if err != nil || minConfidence < 0 || minConfidence > 1 {
+		http.Error(w, "Invalid 'min_confidence' parameter (must be between 0 and 1)", http.StatusBadRequest)
+		return
+	}
+	minLift, err := floatParam(r, "min_lift", 1)
+	if err != nil || minLift < 0 {
+		http.Error(w, "Invalid 'min_lift' parameter (must be non-negative)", http.StatusBadRequest)
+		return
+	}
+	maxLength, err := intParam(r, "max_length", 4)
+	if err != nil || maxLength < 2 || maxLength > 6 {
+		http.Error(w, "Invalid 'max_length' parameter (must be between 2 and 6)", http.StatusBadRequest)
+		return
+	}
+	limit, err := intParam(r, "limit", 100)
+	if err != nil || limit < 1 {
+		http.Error(w, "Invalid 'limit' parameter (must be positive)", http.StatusBadRequest)
+		return
+	}
+	var antecedentFilter uint32
+	if s := r.URL.Query().Get("antecedent"); s != "" {
+		antecedentFilter, err = parseRuleItems(s)
+		if err != nil {
+			http.Error(w, "Invalid 'antecedent' parameter: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if antecedentFilter&^allowed != 0 {
+			http.Error(w, "Invalid 'antecedent' parameter: items must match the 'items' setting", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if len(grades) == 0 {
+		http.Error(w, "No data available", http.StatusInternalServerError)
+		return
+	}
+
+	responses, err := binaryResponseMatrix()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	transactions := make([]uint32, len(grades))
+	for i, row := range responses {
+		for j, x := range row {
+			b := j
+			if x == 1 {
+				b += numQuestions
+			}
+			transactions[i] |= 1 << uint(b)
+		}
+	}
+	n := float64(len(grades))
+	// At least one student, so that every antecedent of a rule has a non-zero count
+	minCount := int(math.Ceil(minSupport*n - 1e-9))
+	if minCount < 1 {
+		minCount = 1
+	}
+	frequent := aprioriItemsets(transactions, allowed, minCount, maxLength)
+
+	response := AssociationRulesResponse{
+		Items:            items,
+		N:                len(grades),
+		MinSupport:       minSupport,
+		MinConfidence:    minConfidence,
+		MinLift:          minLift,
+		MaxLength:        maxLength,
+		FrequentItemsets: len(frequent),
+		Rules:            []AssociationRule{},
+	}
+	if antecedentFilter != 0 {
+		response.Antecedent = maskLabels(antecedentFilter)
+	}
+
+	for itemset, count := range frequent {
+		if bits.OnesCount32(itemset) < 2 {
+			continue
+		}
+		// Every non-empty proper subset of a frequent itemset is frequent, so its count is known
+		for antecedent := (itemset - 1) & itemset; antecedent != 0; antecedent = (antecedent - 1) & itemset {
+			if antecedent&antecedentFilter != antecedentFilter {
+				continue
+			}
+			consequent := itemset &^ antecedent
+			confidence := float64(count) / float64(frequent[antecedent])
+			lift := confidence / (float64(frequent[consequent]) / n)
+			if confidence < minConfidence || lift < minLift {
+				continue
+			}
+			response.Rules = append(response.Rules, AssociationRule{
+				Antecedent: maskLabels(antecedent),
+				Consequent: maskLabels(consequent),
+				Count:      count,
+				Support:    float64(count) / n,
+				Confidence: confidence,
+				Lift:       lift,
+			})
+		}
+	}
+
+	sort.Slice(response.Rules, func(a, b int) bool {
+		ra, rb := response.Rules[a], response.Rules[b]
+		if ra.Lift != rb.Lift {
+			return ra.Lift > rb.Lift
+		}
+		if ra.Confidence != rb.Confidence {
+			return ra.Confidence > rb.Confidence
+		}
+		if ra.Count != rb.Count {
+			return ra.Count > rb.Count
+		}
+		return strings.Join(ra.Antecedent, ",")+">"+strings.Join(ra.Consequent, ",") <
+			strings.Join(rb.Antecedent, ",")+">"+strings.Join(rb.Consequent, ",")
+	})
+	response.TotalRules = len(response.Rules)
+	if len(response.Rules) > limit {
+		response.Rules = response.Rules[:limit]
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
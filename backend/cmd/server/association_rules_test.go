@@ -0,0 +1,172 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// setupAssociationTestData - Q3とQ5を間違えた学生がQ7も間違えるテストデータ
+// 学生1-4: Q3,Q5,Q7を誤答 / 学生5: Q3,Q5のみ誤答 / 学生6-10: 全問正解
+func setupAssociationTestData() {
+	grades = []Grade{}
+	for i := 1; i <= 10; i++ {
+		g := Grade{StudentID: i, Q1: 1, Q2: 1, Q3: 1, Q4: 1, Q5: 1, Q6: 1, Q7: 1, Q8: 1, Q9: 1, Q10: 1}
+		if i <= 5 {
+			g.Q3, g.Q5 = 0, 0
+		}
+		if i <= 4 {
+			g.Q7 = 0
+		}
+		g.Total = g.Q1 + g.Q2 + g.Q3 + g.Q4 + g.Q5 + g.Q6 + g.Q7 + g.Q8 + g.Q9 + g.Q10
+		grades = append(grades, g)
+	}
+}
+
+// findRule - 前件と後件が一致するルールを探す
+func findRule(rules []AssociationRule, antecedent, consequent string) *AssociationRule {
+	for i, rule := range rules {
+		if len(rule.Consequent) == 1 && rule.Consequent[0] == consequent {
+			joined := ""
+			for j, a := range rule.Antecedent {
+				if j > 0 {
+					joined += ","
+				}
+				joined += a
+			}
+			if joined == antecedent {
+				return &rules[i]
+			}
+		}
+	}
+	return nil
+}
+
+// TestAssociationRulesIncorrect - 誤答の組み合わせからのルールのテスト
+func TestAssociationRulesIncorrect(t *testing.T) {
+	setupAssociationTestData()
+
+	var result AssociationRulesResponse
+	serveJSON(t, getAssociationRules, "GET", "/api/association-rules?min_support=0.3&min_confidence=0.5", "", &result)
+
+	// 頻出アイテム集合: {Q3=0}, {Q5=0}, {Q7=0} とそのすべての組み合わせ
+	if result.FrequentItemsets != 7 {
+		t.Errorf("expected 7 frequent itemsets, got %d", result.FrequentItemsets)
+	}
+	rule := findRule(result.Rules, "Q3=0,Q5=0", "Q7=0")
+	if rule == nil {
+		t.Fatalf("expected rule {Q3=0, Q5=0} ⇒ {Q7=0}, got %+v", result.Rules)
+	}
+	// support 4/10, confidence 4/5, lift 0.8/0.4
+	if rule.Count != 4 || math.Abs(rule.Support-0.4) > 1e-9 || math.Abs(rule.Confidence-0.8) > 1e-9 || math.Abs(rule.Lift-2) > 1e-9 {
+		t.Errorf("unexpected rule measures %+v", rule)
+	}
+	for i := 1; i < len(result.Rules); i++ {
+		if result.Rules[i].Lift > result.Rules[i-1].Lift {
+			t.Errorf("rules are not sorted by lift")
+		}
+	}
+}
+
+// TestAssociationRulesAntecedentFilter - 前件による絞り込みと閾値のテスト
+func TestAssociationRulesAntecedentFilter(t *testing.T) {
+	setupAssociationTestData()
+
+	var result AssociationRulesResponse
+	serveJSON(t, getAssociationRules, "GET", "/api/association-rules?min_support=0.3&min_confidence=0.9&antecedent=q7=0", "", &result)
+
+	if len(result.Antecedent) != 1 || result.Antecedent[0] != "Q7=0" {
+		t.Errorf("unexpected antecedent filter %v", result.Antecedent)
+	}
+	if len(result.Rules) == 0 {
+		t.Fatal("expected rules with Q7=0 in the antecedent")
+	}
+	for _, rule := range result.Rules {
+		found := false
+		for _, a := range rule.Antecedent {
+			found = found || a == "Q7=0"
+		}
+		if !found || rule.Confidence < 0.9 {
+			t.Errorf("rule %+v does not match the filter", rule)
+		}
+	}
+	// Q7を間違えた学生は必ずQ3も間違えている
+	if rule := findRule(result.Rules, "Q7=0", "Q3=0"); rule == nil || rule.Confidence != 1 {
+		t.Errorf("expected {Q7=0} ⇒ {Q3=0} with confidence 1, got %+v", rule)
+	}
+}
+
+// TestAssociationRulesBoth - 正答と誤答の両方を使うルールのテスト
+func TestAssociationRulesBoth(t *testing.T) {
+	setupAssociationTestData()
+
+	var result AssociationRulesResponse
+	serveJSON(t, getAssociationRules, "GET", "/api/association-rules?items=both&min_support=0.5&min_confidence=0.9&max_length=2&limit=5", "", &result)
+
+	if len(result.Rules) != 5 || result.TotalRules < 5 {
+		t.Errorf("expected the rule list to be limited to 5, got %d of %d", len(result.Rules), result.TotalRules)
+	}
+	for _, rule := range result.Rules {
+		if len(rule.Antecedent)+len(rule.Consequent) > 2 {
+			t.Errorf("rule %+v exceeds max_length 2", rule)
+		}
+	}
+}
+
+// TestAssociationRulesInvalidParameters - 不正なパラメータのテスト
+func TestAssociationRulesInvalidParameters(t *testing.T) {
+	setupTestData()
+
+	for _, query := range []string{
+		"items=all",
+		"min_support=0",
+		"min_confidence=1.5",
+		"min_lift=-1",
+		"max_length=1",
+		"limit=0",
+		"antecedent=q3",
+		"antecedent=q11=0",
+		"antecedent=q3=1",
+	} {
+		req, _ := http.NewRequest("GET", "/api/association-rules?"+query, nil)
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(getAssociationRules).ServeHTTP(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("query %q: expected 400, got %d", query, rr.Code)
+		}
+	}
+}
+
+// TestAssociationRulesTinySupport - 最小支持度が極小でも支持度0の項目集合が除かれるかのテスト
+func TestAssociationRulesTinySupport(t *testing.T) {
+	setupTestData()
+	// Nobody misses Q1, so "q1=0" has zero support
+	for i := range grades {
+		grades[i].Q1 = 1
+	}
+
+	var result AssociationRulesResponse
+	serveJSON(t, getAssociationRules, "GET", "/api/association-rules?items=both&min_support=1e-12&min_confidence=0&max_length=2&limit=1000", "", &result)
+	if result.TotalRules == 0 {
+		t.Fatal("expected rules from itemsets with non-zero support")
+	}
+	for _, rule := range result.Rules {
+		if rule.Count < 1 || math.IsNaN(rule.Confidence) || math.IsNaN(rule.Lift) {
+			t.Errorf("unexpected rule without support: %+v", rule)
+		}
+	}
+}
+
+// TestAssociationRulesNonBinary - 0/1以外の得点がエラーになるかのテスト
+func TestAssociationRulesNonBinary(t *testing.T) {
+	setupTestData()
+	grades[1].Q1 = 2
+
+	req, _ := http.NewRequest("GET", "/api/association-rules", nil)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(getAssociationRules).ServeHTTP(rr, req)
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500 for a non-binary score, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// maxAttemptsBytes bounds the size of an uploaded attempt log
+	maxAttemptsBytes = 10 << 20
+	// maxAttempts bounds the rows of an attempt log so that BKT stays within maxBKTWork
+	maxAttempts = maxBKTWork / maxBKTIterations
+)
+
+// Attempt is one answer of a student to an item practising a skill. Attempts of a student on a
+// skill are kept in the order in which they were made.
+type Attempt struct {
+	StudentID int    `json:"student_id"`
+	Skill     string `json:"skill"`
+	Correct   int    `json:"correct"`
+}
+
+// AttemptsSummary describes the uploaded attempt log
+type AttemptsSummary struct {
+	Attempts int      `json:"attempts"`
+	Students int      `json:"students"`
+	Skills   []string `json:"skills"`
+}
+
+// attemptStore holds the uploaded attempt log. Like the Q-matrix it is replaced while the
+// server runs, so access goes through the lock.
+var attemptStore struct {
+	sync.RWMutex
+	attempts []Attempt
+}
+
+// currentAttempts returns the uploaded attempts in order
+func currentAttempts() []Attempt {
+	attemptStore.RLock()
+	defer attemptStore.RUnlock()
+	return attemptStore.attempts
+}
+
+// parseAttemptsCSV reads an attempt log with the header student_id,skill,correct and an optional
+// order column. Without order the rows must already be in chronological order; with it each
+// student's attempts are sorted by order, keeping the row order for ties.
+func parseAttemptsCSV(r io.Reader) ([]Attempt, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	var records [][]string
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(records) > maxAttempts {
+			return nil, fmt.Errorf("attempt log must not have more than %d rows", maxAttempts)
+		}
+		records = append(records, record)
+	}
+	if len(records) < 2 {
+		return nil, fmt.Errorf("attempt log must have a header and at least one row")
+	}
+
+	columns := map[string]int{"student_id": -1, "skill": -1, "correct": -1, "order": -1}
+	for i, name := range records[0] {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, ok := columns[name]; ok {
+			columns[name] = i
+		}
+	}
+	for _, name := range []string{"student_id", "skill", "correct"} {
+		if columns[name] < 0 {
+			return nil, fmt.Errorf("missing %q column", name)
+		}
+	}
+
+	attempts := make([]Attempt, 0, len(records)-1)
+	orders := make([]int, 0, len(records)-1)
+	for line, record := range records[1:] {
+		row := line + 2
+		studentID, err := strconv.Atoi(strings.TrimSpace(record[columns["student_id"]]))
+		if err != nil {
+			return nil, fmt.Errorf("row %d: invalid student_id", row)
+		}
+		skill := strings.TrimSpace(record[columns["skill"]])
+		if skill == "" {
+			return nil, fmt.Errorf("row %d: empty skill", row)
+		}
+		correct, err := strconv.Atoi(strings.TrimSpace(record[columns["correct"]]))
+		if err != nil || (correct != 0 && correct != 1) {
+			return nil, fmt.Errorf("row %d: correct must be 0 or 1", row)
+		}
+		order := line
+		if columns["order"] >= 0 {
+			order, err = strconv.Atoi(strings.TrimSpace(record[columns["order"]]))
+			if err != nil {
+				return nil, fmt.Errorf("row %d: invalid order", row)
+			}
+		}
+		attempts = append(attempts, Attempt{StudentID: studentID, Skill: skill, Correct: correct})
+		orders = append(orders, order)
+	}
+
+	index := make([]int, len(attempts))
+	for i := range index {
+		index[i] = i
+	}
+	sort.SliceStable(index, func(a, b int) bool {
+		if attempts[index[a]].StudentID != attempts[index[b]].StudentID {
+			return attempts[index[a]].StudentID < attempts[index[b]].StudentID
+		}
+		return orders[index[a]] < orders[index[b]]
+	})
+	sorted := make([]Attempt, len(attempts))
+	for i, idx := range index {
+		sorted[i] = attempts[idx]
+	}
+	return sorted, nil
+}
+
+// summarizeAttempts counts attempts and students and lists the skills in sorted order
+func summarizeAttempts(attempts []Attempt) AttemptsSummary {
+	students := make(map[int]bool)
+	skills := make(map[string]bool)
+	for _, a := range attempts {
+		students[a.StudentID] = true
+		skills[a.Skill] = true
+	}
+	summary := AttemptsSummary{Attempts: len(attempts), Students: len(students), Skills: []string{}}
+	for skill := range skills {
+		summary.Skills = append(summary.Skills, skill)
+	}
+	sort.Strings(summary.Skills)
+	return summary
+}
+
+// Handler: Get a summary of the uploaded attempt log
+func getAttempts(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summarizeAttempts(currentAttempts()))
+}
+
+// Handler: Upload an attempt log
+// Replaces the attempt log with a CSV body of ordered attempts (student_id,skill,correct[,order])
+func postAttempts(w http.ResponseWriter, r *http.Request) {
+	attempts, err := parseAttemptsCSV(http.MaxBytesReader(w, r.Body, maxAttemptsBytes))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeJSONError(w, http.StatusRequestEntityTooLarge, ErrorResponse{Error: fmt.Sprintf("Attempt log must not exceed %d bytes", maxAttemptsBytes)})
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, ErrorResponse{Error: "Invalid attempt log: " + err.Error()})
+		return
+	}
+
+	attemptStore.Lock()
+	attemptStore.attempts = attempts
+	attemptStore.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summarizeAttempts(attempts))
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// TestParseAttemptsCSVOrder - order列がある場合に学生ごとに並べ替えられることのテスト
+func TestParseAttemptsCSVOrder(t *testing.T) {
+	body := "skill,student_id,correct,order\nfractions,2,1,2\nfractions,1,0,5\nfractions,2,0,1\ndecimals,1,1,3\n"
+	attempts, err := parseAttemptsCSV(strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Attempt{
+		{StudentID: 1, Skill: "decimals", Correct: 1},
+		{StudentID: 1, Skill: "fractions", Correct: 0},
+		{StudentID: 2, Skill: "fractions", Correct: 0},
+		{StudentID: 2, Skill: "fractions", Correct: 1},
+	}
+	for i, a := range attempts {
+		if a != expected[i] {
+			t.Errorf("attempt %d: expected %+v, got %+v", i, expected[i], a)
+		}
+	}
+}
+
+// TestPostAttempts - 解答ログのアップロードと要約のテスト
+func TestPostAttempts(t *testing.T) {
+	var summary AttemptsSummary
+	serveJSON(t, postAttempts, "POST", "/api/attempts", "student_id,skill,correct\n1,add,0\n1,add,1\n2,sub,1\n", &summary)
+	if summary.Attempts != 3 || summary.Students != 2 || len(summary.Skills) != 2 || summary.Skills[0] != "add" {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+	if len(currentAttempts()) != 3 {
+		t.Errorf("expected 3 stored attempts, got %d", len(currentAttempts()))
+	}
+}
+
+// TestPostAttemptsInvalid - 不正な解答ログが拒否されることのテスト
+func TestPostAttemptsInvalid(t *testing.T) {
+	for _, body := range []string{
+		"",
+		"student_id,skill,correct\n",
+		"student_id,correct\n1,1\n",
+		"student_id,skill,correct\nx,add,1\n",
+		"student_id,skill,correct\n1,,1\n",
+		"student_id,skill,correct\n1,add,2\n",
+		"student_id,skill,correct,order\n1,add,1,first\n",
+		"student_id,skill,correct\n1,add\n",
+	} {
+		if rr := serveRequest(t, postAttempts, "POST", "/api/attempts", body); rr.Code != http.StatusBadRequest {
+			t.Errorf("body %q: expected 400, got %d", body, rr.Code)
+		}
+	}
+}
+
+// TestPostAttemptsTooLarge - 行数やサイズが上限を超える解答ログが拒否されることのテスト
+func TestPostAttemptsTooLarge(t *testing.T) {
+	rows := "student_id,skill,correct\n" + strings.Repeat("1,add,1\n", maxAttempts+1)
+	if rr := serveRequest(t, postAttempts, "POST", "/api/attempts", rows); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for more than %d rows, got %d", maxAttempts, rr.Code)
+	}
+	long := "student_id,skill,correct\n1," + strings.Repeat("a", maxAttemptsBytes) + ",1\n"
+	if rr := serveRequest(t, postAttempts, "POST", "/api/attempts", long); rr.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 above %d bytes, got %d", maxAttemptsBytes, rr.Code)
+	}
+}
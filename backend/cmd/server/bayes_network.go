@@ -0,0 +1,512 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// maxNetworkIterations bounds the hill-climbing moves
+const maxNetworkIterations = 1000
+
+// networkData holds the discrete variables of the Bayesian network: the ten items and
+// optionally Total grouped into equal-width bins
+type networkData struct {
+	names       []string
+	states      [][]string
+	cardinality []int
+	rows        [][]int
+}
+
+// newNetworkData builds the network variables from grades. Total is binned by floor(total·bins/11)
+// so that every bin covers about the same range of scores. Item scores must be 0 or 1.
+func newNetworkData(includeTotal bool, bins int) (networkData, error) {
+	d := networkData{}
+	for _, label := range questionLabels() {
+		d.names = append(d.names, label)
+		d.states = append(d.states, []string{"0", "1"})
+		d.cardinality = append(d.cardinality, 2)
+	}
+	binOf := func(total int) int {
+		b := total * bins / (numQuestions + 1)
+		if b >= bins {
+			b = bins - 1
+		}
+		if b < 0 {
+			b = 0
+		}
+		return b
+	}
+	if includeTotal {
+		labels := make([]string, bins)
+		lo := 0
+		for b := 0; b < bins; b++ {
+			hi := lo
+			for hi+1 <= numQuestions && binOf(hi+1) == b {
+				hi++
+			}
+			labels[b] = fmt.Sprintf("%d-%d", lo, hi)
+			lo = hi + 1
+		}
+		d.names = append(d.names, "Total")
+		d.states = append(d.states, labels)
+		d.cardinality = append(d.cardinality, bins)
+	}
+	for _, g := range grades {
+		row := make([]int, len(d.names))
+		for j := 0; j < numQuestions; j++ {
+			row[j] = getQuestionValue(g, j+1)
+			if row[j] != 0 && row[j] != 1 {
+				return networkData{}, fmt.Errorf("student %d has non-binary Q%d", g.StudentID, j+1)
+			}
+		}
+		if includeTotal {
+			row[numQuestions] = binOf(g.Total)
+		}
+		d.rows = append(d.rows, row)
+	}
+	return d, nil
+}
+
+// configIndex returns the mixed-radix index of the parent states of a row
+func (d networkData) configIndex(row []int, parents []int) int {
+	index := 0
+	for _, p := range parents {
+		index = index*d.cardinality[p] + row[p]
+	}
+	return index
+}
+
+// configCount returns the number of parent configurations
+func (d networkData) configCount(parents []int) int {
+	q := 1
+	for _, p := range parents {
+		q *= d.cardinality[p]
+	}
+	return q
+}
+
+// counts returns N[j][k], the number of rows with parent configuration j and node state k
+func (d networkData) counts(node int, parents []int) [][]float64 {
+	n := newMatrix(d.configCount(parents), d.cardinality[node])
+	for _, row := range d.rows {
+		n[d.configIndex(row, parents)][row[node]]++
+	}
+	return n
+}
+
+// localScore returns the BIC or BDeu score of a node given its parents. Both decompose over
+// nodes, so hill climbing only rescores the nodes whose parents change.
+func (d networkData) localScore(node int, parents []int, score string, ess float64) float64 {
+	counts := d.counts(node, parents)
+	q := float64(len(counts))
+	r := float64(d.cardinality[node])
+	var s float64
+	if score == "bdeu" {
+		aj := ess / q
+		ajk := ess / (q * r)
+		lgAj, _ := math.Lgamma(aj)
+		lgAjk, _ := math.Lgamma(ajk)
+		for _, row := range counts {
+			var nj float64
+			for _, c := range row {
+				nj += c
+				lg, _ := math.Lgamma(ajk + c)
+				s += lg - lgAjk
+			}
+			lg, _ := math.Lgamma(aj + nj)
+			s += lgAj - lg
+		}
+		return s
+	}
+	for _, row := range counts {
+		var nj float64
+		for _, c := range row {
+			nj += c
+		}
+		for _, c := range row {
+			if c > 0 {
+				s += c * math.Log(c/nj)
+			}
+		}
+	}
+	return s - 0.5*math.Log(float64(len(d.rows)))*q*(r-1)
+}
+
+// reaches reports whether there is a directed path from a to b
+func reaches(parents [][]int, a, b int) bool {
+	children := make([][]int, len(parents))
+	for child, ps := range parents {
+		for _, p := range ps {
+			children[p] = append(children[p], child)
+		}
+	}
+	seen := make([]bool, len(parents))
+	stack := []int{a}
+	for len(stack) > 0 {
+		v := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if v == b {
+			return true
+		}
+		for _, c := range children[v] {
+			if !seen[c] {
+				seen[c] = true
+				stack = append(stack, c)
+			}
+		}
+	}
+	return false
+}
+
+// withoutParent returns parents with p removed
+func withoutParent(parents []int, p int) []int {
+	result := make([]int, 0, len(parents))
+	for _, v := range parents {
+		if v != p {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// hasParent reports whether p is among parents
+func hasParent(parents []int, p int) bool {
+	for _, v := range parents {
+		if v == p {
+			return true
+		}
+	}
+	return false
+}
+
+// learnStructure runs greedy hill climbing from the empty graph, applying the edge addition,
+// deletion or reversal that most improves the score until none does
+func learnStructure(d networkData, score string, ess float64, maxParents int) ([][]int, float64, int) {
+	nodes := len(d.names)
+	parents := make([][]int, nodes)
+	local := make([]float64, nodes)
+	for v := range local {
+		local[v] = d.localScore(v, nil, score, ess)
+	}
+
+	iterations := 0
+	for iterations < maxNetworkIterations {
+		bestDelta := 1e-9
+		var apply func()
+		for u := 0; u < nodes; u++ {
+			for v := 0; v < nodes; v++ {
+				if u == v {
+					continue
+				}
+				u, v := u, v
+				if hasParent(parents[v], u) {
+					// Delete u → v
+					reduced := withoutParent(parents[v], u)
+					newV := d.localScore(v, reduced, score, ess)
+					if delta := newV - local[v]; delta > bestDelta {
+						bestDelta = delta
+						apply = func() { parents[v], local[v] = reduced, newV }
+					}
+					// Reverse u → v into v → u
+					if len(parents[u]) < maxParents {
+						original := parents[v]
+						parents[v] = reduced
+						cyclic := reaches(parents, u, v)
+						parents[v] = original
+						if !cyclic {
+							extended := append(append([]int(nil), parents[u]...), v)
+							newU := d.localScore(u, extended, score, ess)
+							if delta := newV - local[v] + newU - local[u]; delta > bestDelta {
+								bestDelta = delta
+								apply = func() {
+									parents[v], local[v] = reduced, newV
+									parents[u], local[u] = extended, newU
+								}
+							}
+						}
+					}
+				} else if !hasParent(parents[u], v) && len(parents[v]) < maxParents && !reaches(parents, v, u) {
+					// Add u → v
+					extended := append(append([]int(nil), parents[v]...), u)
+					newV := d.localScore(v, extended, score, ess)
+					if delta := newV - local[v]; delta > bestDelta {
+						bestDelta = delta
+						apply = func() { parents[v], local[v] = extended, newV }
+					}
+				}
+			}
+		}
+		if apply == nil {
+			break
+		}
+		apply()
+		iterations++
+	}
+
+	var total float64
+	for _, s := range local {
+		total += s
+	}
+	return parents, total, iterations
+}
+
+// conditionalTables returns P(node = k | parent configuration j) as posterior means under a
+// BDeu Dirichlet prior with equivalent sample size ess
+func conditionalTables(d networkData, parents [][]int, ess float64) [][][]float64 {
+	tables := make([][][]float64, len(parents))
+	for v, ps := range parents {
+		counts := d.counts(v, ps)
+		q := float64(len(counts))
+		r := float64(d.cardinality[v])
+		tables[v] = newMatrix(len(counts), d.cardinality[v])
+		for j, row := range counts {
+			var nj float64
+			for _, c := range row {
+				nj += c
+			}
+			for k, c := range row {
+				tables[v][j][k] = (c + ess/(q*r)) / (nj + ess/q)
+			}
+		}
+	}
+	return tables
+}
+
+// networkInference returns P(query | evidence) and P(evidence) by enumerating every joint state
+// of the network
+func networkInference(d networkData, parents [][]int, tables [][][]float64, query int, evidence map[int]int) ([]float64, float64) {
+	nodes := len(d.names)
+	state := make([]int, nodes)
+	distribution := make([]float64, d.cardinality[query])
+	var enumerate func(v int)
+	enumerate = func(v int) {
+		if v == nodes {
+			p := 1.0
+			for u := 0; u < nodes; u++ {
+				p *= tables[u][d.configIndex(state, parents[u])][state[u]]
+			}
+			distribution[state[query]] += p
+			return
+		}
+		if s, ok := evidence[v]; ok {
+			state[v] = s
+			enumerate(v + 1)
+			return
+		}
+		for s := 0; s < d.cardinality[v]; s++ {
+			state[v] = s
+			enumerate(v + 1)
+		}
+	}
+	enumerate(0)
+
+	var evidenceProbability float64
+	for _, p := range distribution {
+		evidenceProbability += p
+	}
+	for k := range distribution {
+		distribution[k] /= evidenceProbability
+	}
+	return distribution, evidenceProbability
+}
+
+// parseNetworkNode parses "q1".."q10" or "total" into a node index
+func parseNetworkNode(d networkData, s string) (int, bool) {
+	for v, name := range d.names {
+		if strings.EqualFold(name, s) {
+			return v, true
+		}
+	}
+	return 0, false
+}
+
+// parseEvidence parses "q2=0,q5=1,total=2" into node states; Total states are bin indices
+func parseEvidence(d networkData, s string) (map[int]int, error) {
+	evidence := make(map[int]int)
+	if s == "" {
+		return evidence, nil
+	}
+	for _, part := range strings.Split(s, ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("evidence %q must have the form node=state", part)
+		}
+		v, ok := parseNetworkNode(d, strings.TrimSpace(kv[0]))
+		if !ok {
+			return nil, fmt.Errorf("unknown node %q", kv[0])
+		}
+		state, err := strconv.Atoi(strings.TrimSpace(kv[1]))
+		if err != nil || state < 0 || state >= d.cardinality[v] {
+			return nil, fmt.Errorf("state of %s must be between 0 and %d", d.names[v], d.cardinality[v]-1)
+		}
+		if _, dup := evidence[v]; dup {
+			return nil, fmt.Errorf("%s appears twice in the evidence", d.names[v])
+		}
+		evidence[v] = state
+	}
+	return evidence, nil
+}
+
+// NetworkCPTRow represents one row of a conditional probability table
+type NetworkCPTRow struct {
+	ParentStates  []string  `json:"parent_states"`
+	Count         int       `json:"count"`
+	Probabilities []float64 `json:"probabilities"`
+}
+
+// NetworkNode represents a node of the learned Bayesian network
+type NetworkNode struct {
+	Name    string          `json:"name"`
+	States  []string        `json:"states"`
+	Parents []string        `json:"parents"`
+	CPT     []NetworkCPTRow `json:"cpt"`
+}
+
+// NetworkEdge represents a directed edge of the learned Bayesian network
+type NetworkEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// NetworkInference represents the answer to P(query | evidence)
+type NetworkInference struct {
+	Node                string             `json:"node"`
+	Evidence            map[string]int     `json:"evidence"`
+	Distribution        map[string]float64 `json:"distribution"`
+	EvidenceProbability float64            `json:"evidence_probability"`
+}
+
+// BayesNetworkResponse represents a learned Bayesian network with an optional query
+type BayesNetworkResponse struct {
+	Score      string            `json:"score"`
+	ESS        float64           `json:"ess"`
+	MaxParents int               `json:"max_parents"`
+	N          int               `json:"n"`
+	TotalScore float64           `json:"total_score"`
+	Iterations int               `json:"iterations"`
+	Nodes      []NetworkNode     `json:"nodes"`
+	Edges      []NetworkEdge     `json:"edges"`
+	Inference  *NetworkInference `json:"inference,omitempty"`
+}
+
+// Handler: Get Bayesian network
+// Learns a DAG over the items (and optionally binned Total) by hill climbing with BIC or BDeu,
+// returns nodes, edges and CPTs, and answers P(query | evidence) by exact enumeration
+func getBayesNetwork(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	score := r.URL.Query().Get("score")
+	if score == "" {
+		score = "bic"
+	}
+	if score != "bic" && score != "bdeu" {
+		http.Error(w, "Invalid 'score' parameter (must be bic or bdeu)", http.StatusBadRequest)
+		return
+	}
+	ess, err := floatParam(r, "ess", 1)
+	if err != nil || ess <= 0 {
+		http.Error(w, "Invalid 'ess' parameter (must be positive)", http.StatusBadRequest)
+		return
+	}
+	maxParents, err := intParam(r, "max_parents", 3)
+	if err != nil || maxParents < 0 || maxParents > 5 {
+		http.Error(w, "Invalid 'max_parents' parameter (must be between 0 and 5)", http.StatusBadRequest)
+		return
+	}
+	includeTotal := r.URL.Query().Get("include_total") != "false"
+	bins, err := intParam(r, "total_bins", 3)
+	if err != nil || bins < 2 || bins > 5 {
+		http.Error(w, "Invalid 'total_bins' parameter (must be between 2 and 5)", http.StatusBadRequest)
+		return
+	}
+
+	if len(grades) == 0 {
+		http.Error(w, "No data available", http.StatusInternalServerError)
+		return
+	}
+
+	d, err := newNetworkData(includeTotal, bins)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	query := -1
+	var evidence map[int]int
+	if s := r.URL.Query().Get("query"); s != "" {
+		var ok bool
+		query, ok = parseNetworkNode(d, s)
+		if !ok {
+			http.Error(w, "Invalid 'query' parameter (must be q1-q10 or total)", http.StatusBadRequest)
+			return
+		}
+		evidence, err = parseEvidence(d, r.URL.Query().Get("evidence"))
+		if err != nil {
+			http.Error(w, "Invalid 'evidence' parameter: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if _, ok := evidence[query]; ok {
+			http.Error(w, "Invalid 'evidence' parameter: the query node cannot be observed", http.StatusBadRequest)
+			return
+		}
+	}
+
+	parents, total, iterations := learnStructure(d, score, ess, maxParents)
+	tables := conditionalTables(d, parents, ess)
+
+	response := BayesNetworkResponse{
+		Score:      score,
+		ESS:        ess,
+		MaxParents: maxParents,
+		N:          len(grades),
+		TotalScore: total,
+		Iterations: iterations,
+		Edges:      []NetworkEdge{},
+	}
+	for v, name := range d.names {
+		node := NetworkNode{Name: name, States: d.states[v], Parents: []string{}}
+		for _, p := range parents[v] {
+			node.Parents = append(node.Parents, d.names[p])
+			response.Edges = append(response.Edges, NetworkEdge{From: d.names[p], To: name})
+		}
+		counts := d.counts(v, parents[v])
+		for j, probabilities := range tables[v] {
+			// Decode the mixed-radix configuration index into parent states
+			parentStates := make([]string, len(parents[v]))
+			rest := j
+			for i := len(parents[v]) - 1; i >= 0; i-- {
+				p := parents[v][i]
+				parentStates[i] = d.states[p][rest%d.cardinality[p]]
+				rest /= d.cardinality[p]
+			}
+			var count float64
+			for _, c := range counts[j] {
+				count += c
+			}
+			node.CPT = append(node.CPT, NetworkCPTRow{ParentStates: parentStates, Count: int(count), Probabilities: probabilities})
+		}
+		response.Nodes = append(response.Nodes, node)
+	}
+
+	if query >= 0 {
+		distribution, pEvidence := networkInference(d, parents, tables, query, evidence)
+		inference := &NetworkInference{
+			Node:                d.names[query],
+			Evidence:            make(map[string]int),
+			Distribution:        make(map[string]float64),
+			EvidenceProbability: pEvidence,
+		}
+		for v, s := range evidence {
+			inference.Evidence[d.names[v]] = s
+		}
+		for k, p := range distribution {
+			inference.Distribution[d.states[query][k]] = p
+		}
+		response.Inference = inference
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
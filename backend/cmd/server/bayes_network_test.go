@@ -0,0 +1,152 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// setupChainTestData - Q1 → Q2 → Q3 の連鎖があり、他の問題は独立なテストデータ
+func setupChainTestData(n int) {
+	rng := rand.New(rand.NewSource(9))
+	bernoulli := func(p float64) int {
+		if rng.Float64() < p {
+			return 1
+		}
+		return 0
+	}
+	grades = []Grade{}
+	for i := 0; i < n; i++ {
+		g := Grade{StudentID: i + 1}
+		g.Q1 = bernoulli(0.5)
+		g.Q2 = bernoulli(0.15 + 0.7*float64(g.Q1))
+		g.Q3 = bernoulli(0.15 + 0.7*float64(g.Q2))
+		g.Q4, g.Q5, g.Q6, g.Q7 = bernoulli(0.5), bernoulli(0.6), bernoulli(0.4), bernoulli(0.7)
+		g.Q8, g.Q9, g.Q10 = bernoulli(0.3), bernoulli(0.5), bernoulli(0.5)
+		g.Total = g.Q1 + g.Q2 + g.Q3 + g.Q4 + g.Q5 + g.Q6 + g.Q7 + g.Q8 + g.Q9 + g.Q10
+		grades = append(grades, g)
+	}
+}
+
+// TestBayesNetworkLearnsChain - 連鎖構造の骨格が学習されることのテスト（BICとBDeu）
+func TestBayesNetworkLearnsChain(t *testing.T) {
+	setupChainTestData(2000)
+
+	for _, score := range []string{"bic", "bdeu"} {
+		var result BayesNetworkResponse
+		serveJSON(t, getBayesNetwork, "GET", "/api/bayes-network?include_total=false&score="+score, "", &result)
+
+		adjacent := make(map[[2]string]bool)
+		for _, e := range result.Edges {
+			adjacent[[2]string{e.From, e.To}] = true
+			adjacent[[2]string{e.To, e.From}] = true
+		}
+		if !adjacent[[2]string{"Q1", "Q2"}] || !adjacent[[2]string{"Q2", "Q3"}] {
+			t.Errorf("%s: expected edges Q1-Q2 and Q2-Q3, got %+v", score, result.Edges)
+		}
+		if adjacent[[2]string{"Q1", "Q3"}] {
+			t.Errorf("%s: Q1 and Q3 are conditionally independent given Q2 but got an edge", score)
+		}
+		if len(result.Edges) > 3 {
+			t.Errorf("%s: expected a sparse network, got %+v", score, result.Edges)
+		}
+		if len(result.Nodes) != numQuestions {
+			t.Errorf("%s: expected %d nodes without Total, got %d", score, numQuestions, len(result.Nodes))
+		}
+		for _, node := range result.Nodes {
+			if len(node.CPT) != 1<<len(node.Parents) {
+				t.Errorf("%s: node %s has %d CPT rows for %d parents", score, node.Name, len(node.CPT), len(node.Parents))
+			}
+			for _, row := range node.CPT {
+				if math.Abs(row.Probabilities[0]+row.Probabilities[1]-1) > 1e-9 {
+					t.Errorf("%s: CPT row of %s does not sum to 1", score, node.Name)
+				}
+			}
+		}
+	}
+}
+
+// TestBayesNetworkInference - 厳密推論が経験的な条件付き確率に一致することのテスト
+func TestBayesNetworkInference(t *testing.T) {
+	setupChainTestData(2000)
+
+	var result BayesNetworkResponse
+	serveJSON(t, getBayesNetwork, "GET", "/api/bayes-network?include_total=false&query=q3&evidence=q1=1", "", &result)
+
+	var both, condition float64
+	for _, g := range grades {
+		if g.Q1 == 1 {
+			condition++
+			both += float64(g.Q3)
+		}
+	}
+	if result.Inference == nil || result.Inference.Node != "Q3" || result.Inference.Evidence["Q1"] != 1 {
+		t.Fatalf("unexpected inference %+v", result.Inference)
+	}
+	if got := result.Inference.Distribution["1"]; math.Abs(got-both/condition) > 0.02 {
+		t.Errorf("expected P(Q3=1 | Q1=1) near %.3f, got %.3f", both/condition, got)
+	}
+	if math.Abs(result.Inference.EvidenceProbability-condition/2000) > 0.01 {
+		t.Errorf("expected P(Q1=1) near %.3f, got %.3f", condition/2000, result.Inference.EvidenceProbability)
+	}
+}
+
+// TestBayesNetworkTotalNode - 合計点ノードの離散化と合計点の推論のテスト
+func TestBayesNetworkTotalNode(t *testing.T) {
+	setupChainTestData(500)
+
+	var result BayesNetworkResponse
+	serveJSON(t, getBayesNetwork, "GET", "/api/bayes-network?query=total&evidence=q2=0,q5=1&total_bins=3", "", &result)
+
+	total := result.Nodes[numQuestions]
+	if total.Name != "Total" || len(total.States) != 3 || total.States[0] != "0-3" || total.States[2] != "8-10" {
+		t.Fatalf("unexpected Total node %+v", total)
+	}
+	var sum float64
+	for _, p := range result.Inference.Distribution {
+		sum += p
+	}
+	if len(result.Inference.Distribution) != 3 || math.Abs(sum-1) > 1e-9 {
+		t.Errorf("expected a distribution over 3 Total bins, got %v", result.Inference.Distribution)
+	}
+}
+
+// TestBayesNetworkInvalidParameters - 不正なパラメータのテスト
+func TestBayesNetworkInvalidParameters(t *testing.T) {
+	setupTestData()
+
+	for _, query := range []string{
+		"score=aic",
+		"ess=0",
+		"max_parents=9",
+		"total_bins=1",
+		"query=q11",
+		"query=q1&evidence=q2",
+		"query=q1&evidence=q2=2",
+		"query=q1&evidence=q1=1",
+		"query=q1&evidence=q2=1,q2=0",
+		"query=total&include_total=false",
+	} {
+		req, _ := http.NewRequest("GET", "/api/bayes-network?"+query, nil)
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(getBayesNetwork).ServeHTTP(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("query %q: expected 400, got %d", query, rr.Code)
+		}
+	}
+}
+
+// TestBayesNetworkNonBinary - 0/1以外の得点がエラーになるかのテスト
+func TestBayesNetworkNonBinary(t *testing.T) {
+	setupTestData()
+	grades[1].Q1 = 2
+
+	req, _ := http.NewRequest("GET", "/api/bayes-network", nil)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(getBayesNetwork).ServeHTTP(rr, req)
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500 for a non-binary score, got %d", rr.Code)
+	}
+}
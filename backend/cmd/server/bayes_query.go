@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// BayesQueryResponse represents P(hypothesis | condition) for arbitrary filter expressions
+type BayesQueryResponse struct {
+	Condition              string  `json:"condition"`
+	Hypothesis             string  `json:"hypothesis"`
+	ParsedCondition        string  `json:"parsed_condition"`
+	ParsedHypothesis       string  `json:"parsed_hypothesis"`
+	N                      int     `json:"n"`
+	ConditionMetCount      int     `json:"condition_met_count"`
+	HypothesisMetCount     int     `json:"hypothesis_met_count"`
+	BothConditionsMetCount int     `json:"both_conditions_met_count"`
+	PosteriorProbability   float64 `json:"posterior_probability"`
+	PriorProbability       float64 `json:"prior_probability"`
+	LikelihoodProbability  float64 `json:"likelihood_probability"`
+	EvidenceProbability    float64 `json:"evidence_probability"`
+}
+
+// ErrorResponse represents an error returned as JSON instead of plain text
+type ErrorResponse struct {
+	Error      string `json:"error"`
+	Parameter  string `json:"parameter,omitempty"`
+	Expression string `json:"expression,omitempty"`
+	Position   *int   `json:"position,omitempty"`
+}
+
+// writeJSONError writes an ErrorResponse with the given status code
+func writeJSONError(w http.ResponseWriter, status int, response ErrorResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// expressionErrorResponse describes an invalid filter expression given in parameter, with the
+// position of the syntax error when the parser reports one
+func expressionErrorResponse(parameter, expression string, err error) ErrorResponse {
+	response := ErrorResponse{Error: err.Error(), Parameter: parameter, Expression: expression}
+	var exprErr *ExpressionError
+	if errors.As(err, &exprErr) {
+		response.Error = exprErr.Message
+		response.Position = &exprErr.Position
+	}
+	return response
+}
+
+// bayesCounts holds how many students satisfy the condition, the hypothesis and both
+type bayesCounts struct {
+	n          int
+	condition  int
+	hypothesis int
+	both       int
+}
+
+// countBayes evaluates a condition and a hypothesis against every grade
+func countBayes(condition, hypothesis filterExpr) bayesCounts {
+	counts := bayesCounts{n: len(grades)}
+	for _, grade := range grades {
+		c := condition.eval(grade)
+		h := hypothesis.eval(grade)
+		if c {
+			counts.condition++
+		}
+		if h {
+			counts.hypothesis++
+		}
+		if c && h {
+			counts.both++
+		}
+	}
+	return counts
+}
+
+// ratio returns numerator/denominator, or 0 when the denominator is 0 (as getBayesTheorem does)
+func ratio(numerator, denominator int) float64 {
+	if denominator == 0 {
+		return 0.0
+	}
+	return float64(numerator) / float64(denominator)
+}
+
+// Handler: Get Bayes query result
+// Calculates P(hypothesis | condition) where both are filter expressions, e.g.
+// condition="q1==1 && (q3==0 || q5==1)" and hypothesis="total between 6 and 8"
+func getBayesQuery(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	conditionStr := r.URL.Query().Get("condition")
+	hypothesisStr := r.URL.Query().Get("hypothesis")
+
+	if conditionStr == "" {
+		writeJSONError(w, http.StatusBadRequest, ErrorResponse{Error: "Missing 'condition' parameter", Parameter: "condition"})
+		return
+	}
+	if hypothesisStr == "" {
+		writeJSONError(w, http.StatusBadRequest, ErrorResponse{Error: "Missing 'hypothesis' parameter", Parameter: "hypothesis"})
+		return
+	}
+
+	parsed := make(map[string]filterExpr)
+	for _, param := range []struct{ name, expression string }{
+		{"condition", conditionStr},
+		{"hypothesis", hypothesisStr},
+	} {
+		expr, err := parseFilterExpression(param.expression)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, expressionErrorResponse(param.name, param.expression, err))
+			return
+		}
+		parsed[param.name] = expr
+	}
+
+	if len(grades) == 0 {
+		writeJSONError(w, http.StatusInternalServerError, ErrorResponse{Error: "No data available"})
+		return
+	}
+
+	condition := parsed["condition"]
+	hypothesis := parsed["hypothesis"]
+	counts := countBayes(condition, hypothesis)
+
+	response := BayesQueryResponse{
+		Condition:              conditionStr,
+		Hypothesis:             hypothesisStr,
+		ParsedCondition:        condition.String(),
+		ParsedHypothesis:       hypothesis.String(),
+		N:                      counts.n,
+		ConditionMetCount:      counts.condition,
+		HypothesisMetCount:     counts.hypothesis,
+		BothConditionsMetCount: counts.both,
+		PosteriorProbability:   ratio(counts.both, counts.condition),
+		PriorProbability:       ratio(counts.hypothesis, counts.n),
+		LikelihoodProbability:  ratio(counts.both, counts.hypothesis),
+		EvidenceProbability:    ratio(counts.condition, counts.n),
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
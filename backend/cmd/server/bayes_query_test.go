@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// TestBayesQuery - 複合条件によるベイズ計算の正常系テスト
+func TestBayesQuery(t *testing.T) {
+	setupTestData()
+	// Student1: Total=10, Q8=1 / Student2: Total=7, Q8=0 / Student3: Total=0
+	// 条件 q1==1 && (q8==0 || q9==1) は Student1, Student2 が満たす
+	// 仮説 total between 6 and 8 は Student2 のみ
+	// P(H | C) = 1/2, P(H) = 1/3, P(C | H) = 1, P(C) = 2/3
+
+	query := url.Values{}
+	query.Set("condition", "q1==1 && (q8==0 || q9==1)")
+	query.Set("hypothesis", "total between 6 and 8")
+
+	var result BayesQueryResponse
+	serveJSON(t, getBayesQuery, "GET", "/api/bayes/query?"+query.Encode(), "", &result)
+
+	if result.ConditionMetCount != 2 || result.HypothesisMetCount != 1 || result.BothConditionsMetCount != 1 {
+		t.Errorf("unexpected counts: %+v", result)
+	}
+	if result.PosteriorProbability != 0.5 {
+		t.Errorf("expected posterior 0.5, got %.4f", result.PosteriorProbability)
+	}
+	if result.LikelihoodProbability != 1.0 {
+		t.Errorf("expected likelihood 1.0, got %.4f", result.LikelihoodProbability)
+	}
+	if result.PriorProbability < 0.33 || result.PriorProbability > 0.34 {
+		t.Errorf("expected prior 1/3, got %.4f", result.PriorProbability)
+	}
+}
+
+// TestBayesQueryMatchesBayesTheorem - 単一条件の場合に /api/bayes と一致するテスト
+func TestBayesQueryMatchesBayesTheorem(t *testing.T) {
+	setupTestData()
+
+	query := url.Values{}
+	query.Set("condition", "q1 == 1")
+	query.Set("hypothesis", "total >= 8")
+
+	var result BayesQueryResponse
+	serveJSON(t, getBayesQuery, "GET", "/api/bayes/query?"+query.Encode(), "", &result)
+
+	// TestBayesTheorem と同じく P(Total≥8 | Q1=1) = 0.5
+	if result.PosteriorProbability != 0.5 {
+		t.Errorf("expected posterior 0.5, got %.4f", result.PosteriorProbability)
+	}
+}
+
+// TestBayesQueryParseError - 構文エラーがJSONで返るテスト
+func TestBayesQueryParseError(t *testing.T) {
+	setupTestData()
+
+	query := url.Values{}
+	query.Set("condition", "q1 == 1")
+	query.Set("hypothesis", "total >= ")
+
+	req, err := http.NewRequest("GET", "/api/bayes/query?"+query.Encode(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(getBayesQuery)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusBadRequest)
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected JSON error, got content type %s", ct)
+	}
+
+	var result ErrorResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &result); err != nil {
+		t.Fatalf("failed to decode error body: %v", err)
+	}
+	if result.Parameter != "hypothesis" {
+		t.Errorf("expected error on hypothesis, got %s", result.Parameter)
+	}
+	if result.Position == nil || *result.Position != 9 {
+		t.Errorf("expected error position 9, got %v", result.Position)
+	}
+}
+
+// TestBayesQueryMissingParams - パラメータ欠落のテスト
+func TestBayesQueryMissingParams(t *testing.T) {
+	setupTestData()
+
+	for _, query := range []string{"hypothesis=true", "condition=true"} {
+		t.Run(query, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "/api/bayes/query?"+query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(getBayesQuery)
+			handler.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != http.StatusBadRequest {
+				t.Errorf("handler returned wrong status code: got %v want %v",
+					status, http.StatusBadRequest)
+			}
+		})
+	}
+}
+
+// TestBayesQueryEmptyData - データが空の場合のテスト
+func TestBayesQueryEmptyData(t *testing.T) {
+	grades = []Grade{}
+
+	req, err := http.NewRequest("GET", "/api/bayes/query?condition=true&hypothesis=true", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(getBayesQuery)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusInternalServerError {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusInternalServerError)
+	}
+}
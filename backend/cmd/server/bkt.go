@@ -0,0 +1,287 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"sort"
+)
+
+// Bayesian Knowledge Tracing (Corbett & Anderson 1995) is a two-state hidden Markov model per
+// skill: a student starts in the learned state with probability prior, moves there after each
+// opportunity with probability learn and never forgets. A learned student answers correctly
+// unless they slip; an unlearned one only by guessing.
+
+const (
+	// bktParameterFloor keeps the BKT probabilities away from 0 and 1
+	bktParameterFloor = 1e-4
+	// bktMaxGuessSlip caps guess and slip at ½ so that the learned state is the one that answers
+	// correctly more often
+	bktMaxGuessSlip = 0.5
+	// maxBKTIterations bounds the EM iterations per skill
+	maxBKTIterations = 500
+	// maxBKTWork caps attempts × EM iterations so a request stays fast
+	maxBKTWork = 50000000
+)
+
+// bktParameters are the four BKT probabilities of a skill
+type bktParameters struct {
+	Prior float64 `json:"prior"`
+	Learn float64 `json:"learn"`
+	Guess float64 `json:"guess"`
+	Slip  float64 `json:"slip"`
+}
+
+// correctProbability returns P(correct) given the probability of being in the learned state
+func (p bktParameters) correctProbability(learned float64) float64 {
+	return learned*(1-p.Slip) + (1-learned)*p.Guess
+}
+
+// observe returns P(learned | answer) from the probability of being learned before the answer
+func (p bktParameters) observe(learned float64, correct int) float64 {
+	if correct == 1 {
+		return learned * (1 - p.Slip) / p.correctProbability(learned)
+	}
+	return learned * p.Slip / (1 - p.correctProbability(learned))
+}
+
+// trace returns the probability of being learned before each attempt and after the last one,
+// including the learning opportunity of the last attempt
+func (p bktParameters) trace(sequence []int) []float64 {
+	learned := make([]float64, len(sequence)+1)
+	learned[0] = p.Prior
+	for t, correct := range sequence {
+		posterior := p.observe(learned[t], correct)
+		learned[t+1] = posterior + (1-posterior)*p.Learn
+	}
+	return learned
+}
+
+// bktFit is the result of fitting one skill
+type bktFit struct {
+	params     bktParameters
+	logLik     float64
+	iterations int
+	converged  bool
+}
+
+// fitBKT estimates the BKT parameters of one skill by EM (Baum-Welch) over the attempt
+// sequences of every student, using scaled forward-backward recursions
+func fitBKT(sequences [][]int) bktFit {
+	fit := bktFit{params: bktParameters{Prior: 0.5, Learn: 0.1, Guess: 0.2, Slip: 0.1}}
+	previous := math.Inf(-1)
+	for fit.iterations < maxBKTIterations {
+		fit.iterations++
+		p := fit.params
+
+		var priorSum, learnNum, learnDen, guessNum, guessDen, slipNum, slipDen float64
+		fit.logLik = 0
+		for _, seq := range sequences {
+			n := len(seq)
+			// alpha[t][s] and beta[t][s] with s = 0 unlearned, 1 learned
+			alpha := make([][2]float64, n)
+			beta := make([][2]float64, n)
+			scale := make([]float64, n)
+			emission := func(t, s int) float64 {
+				correct := 0.0
+				if s == 1 {
+					correct = 1 - p.Slip
+				} else {
+					correct = p.Guess
+				}
+				if seq[t] == 1 {
+					return correct
+				}
+				return 1 - correct
+			}
+
+			for t := 0; t < n; t++ {
+				if t == 0 {
+					alpha[0][0] = (1 - p.Prior) * emission(0, 0)
+					alpha[0][1] = p.Prior * emission(0, 1)
+				} else {
+					alpha[t][0] = alpha[t-1][0] * (1 - p.Learn) * emission(t, 0)
+					alpha[t][1] = (alpha[t-1][0]*p.Learn + alpha[t-1][1]) * emission(t, 1)
+				}
+				scale[t] = alpha[t][0] + alpha[t][1]
+				alpha[t][0] /= scale[t]
+				alpha[t][1] /= scale[t]
+				fit.logLik += math.Log(scale[t])
+			}
+
+			beta[n-1] = [2]float64{1, 1}
+			for t := n - 2; t >= 0; t-- {
+				next0 := emission(t+1, 0) * beta[t+1][0]
+				next1 := emission(t+1, 1) * beta[t+1][1]
+				beta[t][0] = ((1-p.Learn)*next0 + p.Learn*next1) / scale[t+1]
+				beta[t][1] = next1 / scale[t+1]
+			}
+
+			for t := 0; t < n; t++ {
+				learned := alpha[t][1] * beta[t][1]
+				unlearned := alpha[t][0] * beta[t][0]
+				total := learned + unlearned
+				learned /= total
+				unlearned /= total
+				if t == 0 {
+					priorSum += learned
+				}
+				guessDen += unlearned
+				slipDen += learned
+				if seq[t] == 1 {
+					guessNum += unlearned
+				} else {
+					slipNum += learned
+				}
+				if t < n-1 {
+					// Expected unlearned → learned transitions between t and t+1
+					transition := alpha[t][0] * p.Learn * emission(t+1, 1) * beta[t+1][1] / scale[t+1]
+					learnNum += transition
+					learnDen += unlearned
+				}
+			}
+		}
+
+		if fit.logLik-previous < 1e-8*(1+math.Abs(fit.logLik)) {
+			fit.converged = true
+			break
+		}
+		previous = fit.logLik
+
+		fit.params.Prior = clampProbability(priorSum/float64(len(sequences)), bktParameterFloor)
+		if learnDen > 0 {
+			fit.params.Learn = clampProbability(learnNum/learnDen, bktParameterFloor)
+		}
+		if guessDen > 0 {
+			fit.params.Guess = math.Min(clampProbability(guessNum/guessDen, bktParameterFloor), bktMaxGuessSlip)
+		}
+		if slipDen > 0 {
+			fit.params.Slip = math.Min(clampProbability(slipNum/slipDen, bktParameterFloor), bktMaxGuessSlip)
+		}
+	}
+	return fit
+}
+
+// BKTStudentState represents a student's knowledge state on one skill
+type BKTStudentState struct {
+	StudentID         int       `json:"student_id"`
+	Attempts          int       `json:"attempts"`
+	Correct           int       `json:"correct"`
+	Mastery           float64   `json:"mastery"`
+	NextCorrect       float64   `json:"next_correct_probability"`
+	MasteryTrajectory []float64 `json:"mastery_trajectory,omitempty"`
+}
+
+// BKTSkill represents the fitted BKT model of one skill
+type BKTSkill struct {
+	Skill         string            `json:"skill"`
+	Students      int               `json:"students"`
+	Attempts      int               `json:"attempts"`
+	Parameters    bktParameters     `json:"parameters"`
+	LogLikelihood float64           `json:"log_likelihood"`
+	Iterations    int               `json:"iterations"`
+	Converged     bool              `json:"converged"`
+	States        []BKTStudentState `json:"states"`
+}
+
+// BKTResponse represents knowledge tracing results for the uploaded attempt log
+type BKTResponse struct {
+	Skills []BKTSkill `json:"skills"`
+}
+
+// Handler: Get Bayesian Knowledge Tracing
+// Fits learn, guess, slip and prior per skill by EM over the uploaded attempt log and returns each
+// student's current probability of mastery and of answering the next item correctly
+func getBKT(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	skillFilter := r.URL.Query().Get("skill")
+	studentFilter, err := intParam(r, "student_id", 0)
+	if err != nil {
+		http.Error(w, "Invalid 'student_id' parameter", http.StatusBadRequest)
+		return
+	}
+	trajectoryStr := r.URL.Query().Get("trajectory")
+	if trajectoryStr != "" && trajectoryStr != "true" && trajectoryStr != "false" {
+		http.Error(w, "Invalid 'trajectory' parameter (must be true or false)", http.StatusBadRequest)
+		return
+	}
+	trajectory := trajectoryStr == "true"
+
+	attempts := currentAttempts()
+	if len(attempts) == 0 {
+		http.Error(w, "No attempt log has been uploaded (POST /api/attempts first)", http.StatusBadRequest)
+		return
+	}
+	if len(attempts) > maxBKTWork/maxBKTIterations {
+		http.Error(w, "Too much work: attempts × EM iterations must not exceed 50000000", http.StatusBadRequest)
+		return
+	}
+
+	// Group attempts by skill and student; attempts are already in chronological order
+	sequences := make(map[string]map[int][]int)
+	for _, a := range attempts {
+		if skillFilter != "" && a.Skill != skillFilter {
+			continue
+		}
+		if sequences[a.Skill] == nil {
+			sequences[a.Skill] = make(map[int][]int)
+		}
+		sequences[a.Skill][a.StudentID] = append(sequences[a.Skill][a.StudentID], a.Correct)
+	}
+	if len(sequences) == 0 {
+		http.Error(w, "Unknown 'skill' parameter", http.StatusBadRequest)
+		return
+	}
+
+	skills := make([]string, 0, len(sequences))
+	for skill := range sequences {
+		skills = append(skills, skill)
+	}
+	sort.Strings(skills)
+
+	response := BKTResponse{}
+	for _, skill := range skills {
+		students := make([]int, 0, len(sequences[skill]))
+		for id := range sequences[skill] {
+			students = append(students, id)
+		}
+		sort.Ints(students)
+		data := make([][]int, len(students))
+		result := BKTSkill{Skill: skill, Students: len(students), States: []BKTStudentState{}}
+		for i, id := range students {
+			data[i] = sequences[skill][id]
+			result.Attempts += len(data[i])
+		}
+
+		fit := fitBKT(data)
+		result.Parameters = fit.params
+		result.LogLikelihood = fit.logLik
+		result.Iterations = fit.iterations
+		result.Converged = fit.converged
+
+		for i, id := range students {
+			if studentFilter != 0 && id != studentFilter {
+				continue
+			}
+			learned := fit.params.trace(data[i])
+			mastery := learned[len(learned)-1]
+			state := BKTStudentState{
+				StudentID:   id,
+				Attempts:    len(data[i]),
+				Mastery:     mastery,
+				NextCorrect: fit.params.correctProbability(mastery),
+			}
+			for _, c := range data[i] {
+				state.Correct += c
+			}
+			if trajectory {
+				state.MasteryTrajectory = learned
+			}
+			result.States = append(result.States, state)
+		}
+		response.Skills = append(response.Skills, result)
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
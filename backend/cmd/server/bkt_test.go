@@ -0,0 +1,132 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// setupBKTTestData - 既知のパラメータのBKTモデルから解答ログを生成する
+func setupBKTTestData(params bktParameters, students, attemptsPerStudent int) {
+	rng := rand.New(rand.NewSource(11))
+	var attempts []Attempt
+	for s := 1; s <= students; s++ {
+		learned := rng.Float64() < params.Prior
+		for t := 0; t < attemptsPerStudent; t++ {
+			p := params.Guess
+			if learned {
+				p = 1 - params.Slip
+			}
+			correct := 0
+			if rng.Float64() < p {
+				correct = 1
+			}
+			attempts = append(attempts, Attempt{StudentID: s, Skill: "fractions", Correct: correct})
+			if !learned && rng.Float64() < params.Learn {
+				learned = true
+			}
+		}
+	}
+	attemptStore.attempts = attempts
+}
+
+// TestBKTRecoversParameters - 生成に使ったパラメータをEMで復元できることのテスト
+func TestBKTRecoversParameters(t *testing.T) {
+	truth := bktParameters{Prior: 0.3, Learn: 0.15, Guess: 0.2, Slip: 0.1}
+	setupBKTTestData(truth, 500, 15)
+
+	var result BKTResponse
+	serveJSON(t, getBKT, "GET", "/api/bkt", "", &result)
+
+	if len(result.Skills) != 1 {
+		t.Fatalf("expected 1 skill, got %d", len(result.Skills))
+	}
+	skill := result.Skills[0]
+	if !skill.Converged || skill.Students != 500 || skill.Attempts != 7500 {
+		t.Errorf("unexpected fit summary %+v", skill)
+	}
+	p := skill.Parameters
+	if math.Abs(p.Prior-truth.Prior) > 0.07 || math.Abs(p.Learn-truth.Learn) > 0.05 ||
+		math.Abs(p.Guess-truth.Guess) > 0.05 || math.Abs(p.Slip-truth.Slip) > 0.05 {
+		t.Errorf("parameters %+v far from %+v", p, truth)
+	}
+}
+
+// TestBKTStudentState - 習得確率と次の正答確率の計算のテスト
+func TestBKTStudentState(t *testing.T) {
+	p := bktParameters{Prior: 0.5, Learn: 0.2, Guess: 0.2, Slip: 0.1}
+	learned := p.trace([]int{1})
+	// 正答後の事後確率 0.5·0.9/(0.5·0.9+0.5·0.2) = 0.818… に学習の機会を加える
+	posterior := 0.45 / 0.55
+	if expected := posterior + (1-posterior)*0.2; math.Abs(learned[1]-expected) > 1e-12 {
+		t.Errorf("expected mastery %.6f, got %.6f", expected, learned[1])
+	}
+
+	setupBKTTestData(bktParameters{Prior: 0.3, Learn: 0.15, Guess: 0.2, Slip: 0.1}, 200, 10)
+	attemptStore.attempts = append(attemptStore.attempts,
+		Attempt{StudentID: 999, Skill: "fractions", Correct: 1},
+		Attempt{StudentID: 999, Skill: "fractions", Correct: 1},
+		Attempt{StudentID: 999, Skill: "fractions", Correct: 1},
+		Attempt{StudentID: 1000, Skill: "fractions", Correct: 0},
+		Attempt{StudentID: 1000, Skill: "fractions", Correct: 0},
+		Attempt{StudentID: 1000, Skill: "fractions", Correct: 0},
+	)
+
+	var result BKTResponse
+	serveJSON(t, getBKT, "GET", "/api/bkt?skill=fractions&trajectory=true", "", &result)
+	states := make(map[int]BKTStudentState)
+	for _, s := range result.Skills[0].States {
+		states[s.StudentID] = s
+	}
+	strong, weak := states[999], states[1000]
+	if strong.Mastery <= weak.Mastery || strong.NextCorrect <= weak.NextCorrect {
+		t.Errorf("expected three correct answers to give higher mastery: %+v vs %+v", strong, weak)
+	}
+	if len(strong.MasteryTrajectory) != 4 || strong.Correct != 3 {
+		t.Errorf("unexpected trajectory %+v", strong)
+	}
+
+	result = BKTResponse{}
+	serveJSON(t, getBKT, "GET", "/api/bkt?student_id=999", "", &result)
+	if len(result.Skills[0].States) != 1 || result.Skills[0].States[0].MasteryTrajectory != nil {
+		t.Errorf("expected only student 999 without trajectory, got %+v", result.Skills[0].States)
+	}
+}
+
+// TestBKTErrors - 解答ログがない場合と未知の技能のテスト
+func TestBKTErrors(t *testing.T) {
+	attemptStore.attempts = nil
+	for _, query := range []string{"", "skill=fractions"} {
+		req, _ := http.NewRequest("GET", "/api/bkt?"+query, nil)
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(getBKT).ServeHTTP(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("query %q without attempts: expected 400, got %d", query, rr.Code)
+		}
+	}
+
+	attemptStore.attempts = []Attempt{{StudentID: 1, Skill: "add", Correct: 1}}
+	for _, query := range []string{"skill=sub", "student_id=x", "trajectory=yes"} {
+		req, _ := http.NewRequest("GET", "/api/bkt?"+query, nil)
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(getBKT).ServeHTTP(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("query %q: expected 400, got %d", query, rr.Code)
+		}
+	}
+}
+
+// TestBKTTooMuchWork - 解答数 × EM反復数が上限を超える場合のテスト
+func TestBKTTooMuchWork(t *testing.T) {
+	attemptStore.attempts = make([]Attempt, maxBKTWork/maxBKTIterations+1)
+	defer func() { attemptStore.attempts = nil }()
+
+	req, _ := http.NewRequest("GET", "/api/bkt", nil)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(getBKT).ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 above the work cap, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,367 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+const (
+	// bootstrapBlockSize replicates share one random source, so results do not depend on how
+	// blocks are spread over goroutines
+	bootstrapBlockSize = 64
+	// bootstrapSeedStride separates the block seeds of neighbouring user seeds
+	bootstrapSeedStride = 1 << 20
+	// maxBootstrapWork caps replicates × students so a request stays fast
+	maxBootstrapWork = 50000000
+)
+
+// bootstrapStatistic computes one or more named statistics from a sample of grades
+type bootstrapStatistic struct {
+	names   []string
+	compute func(sample []Grade) []float64
+}
+
+// ConfidenceInterval represents a two-sided confidence interval
+type ConfidenceInterval struct {
+	Lower float64 `json:"lower"`
+	Upper float64 `json:"upper"`
+}
+
+// BootstrapEstimate represents the bootstrap distribution of one statistic
+type BootstrapEstimate struct {
+	Name           string              `json:"name"`
+	Estimate       *float64            `json:"estimate"`
+	BootstrapMean  float64             `json:"bootstrap_mean"`
+	Bias           float64             `json:"bias"`
+	SE             float64             `json:"se"`
+	Percentile     *ConfidenceInterval `json:"percentile"`
+	BCa            *ConfidenceInterval `json:"bca"`
+	BiasCorrection *float64            `json:"bias_correction"`
+	Acceleration   *float64            `json:"acceleration"`
+	Invalid        int                 `json:"invalid_replicates"`
+}
+
+// BootstrapResponse represents bootstrap confidence intervals for a statistic
+type BootstrapResponse struct {
+	Statistic  string              `json:"statistic"`
+	N          int                 `json:"n"`
+	Replicates int                 `json:"replicates"`
+	Level      float64             `json:"level"`
+	Seed       int64               `json:"seed"`
+	Estimates  []BootstrapEstimate `json:"estimates"`
+	Warnings   []string            `json:"warnings"`
+}
+
+// medianTotal returns the median Total, averaging the middle pair for even sizes
+func medianTotal(sample []Grade) float64 {
+	totals := make([]float64, len(sample))
+	for i, g := range sample {
+		totals[i] = float64(g.Total)
+	}
+	sort.Float64s(totals)
+	return quantile(totals, 0.5)
+}
+
+// cronbachAlpha returns Cronbach's α (KR-20 for binary items) of the ten items, or NaN when the
+// item sums have no variance
+func cronbachAlpha(sample []Grade) float64 {
+	k := float64(numQuestions)
+	sums := make([]float64, len(sample))
+	var itemVariances float64
+	column := make([]float64, len(sample))
+	for q := 1; q <= numQuestions; q++ {
+		for i, g := range sample {
+			column[i] = float64(getQuestionValue(g, q))
+			sums[i] += column[i]
+		}
+		_, sd := meanAndSD(column)
+		itemVariances += sd * sd
+	}
+	_, sd := meanAndSD(sums)
+	if sd == 0 {
+		return math.NaN()
+	}
+	return k / (k - 1) * (1 - itemVariances/(sd*sd))
+}
+
+// newBootstrapStatistic returns the named statistic; correlation uses variables a and b
+// (question numbers, 0 for Total)
+func newBootstrapStatistic(name string, a, b int) bootstrapStatistic {
+	switch name {
+	case "mean":
+		return bootstrapStatistic{names: []string{"mean_total"}, compute: func(sample []Grade) []float64 {
+			var sum float64
+			for _, g := range sample {
+				sum += float64(g.Total)
+			}
+			return []float64{sum / float64(len(sample))}
+		}}
+	case "median":
+		return bootstrapStatistic{names: []string{"median_total"}, compute: func(sample []Grade) []float64 {
+			return []float64{medianTotal(sample)}
+		}}
+	case "item-rates":
+		return bootstrapStatistic{names: questionLabels(), compute: func(sample []Grade) []float64 {
+			rates := make([]float64, numQuestions)
+			for _, g := range sample {
+				for j := range rates {
+					rates[j] += float64(getQuestionValue(g, j+1))
+				}
+			}
+			for j := range rates {
+				rates[j] /= float64(len(sample))
+			}
+			return rates
+		}}
+	case "correlation":
+		label := func(q int) string {
+			if q == 0 {
+				return "Total"
+			}
+			return fmt.Sprintf("Q%d", q)
+		}
+		return bootstrapStatistic{names: []string{label(a) + "-" + label(b)}, compute: func(sample []Grade) []float64 {
+			x := make([]float64, len(sample))
+			y := make([]float64, len(sample))
+			for i, g := range sample {
+				x[i] = float64(variableValue(g, a))
+				y[i] = float64(variableValue(g, b))
+			}
+			// pearson reports 0 without variance; here the resample is undefined and dropped
+			_, sdX := meanAndSD(x)
+			_, sdY := meanAndSD(y)
+			if sdX == 0 || sdY == 0 {
+				return []float64{math.NaN()}
+			}
+			return []float64{pearson(x, y)}
+		}}
+	case "reliability":
+		return bootstrapStatistic{names: []string{"cronbach_alpha"}, compute: func(sample []Grade) []float64 {
+			return []float64{cronbachAlpha(sample)}
+		}}
+	}
+	return bootstrapStatistic{}
+}
+
+// runBootstrap draws replicates resamples of data with replacement and returns the statistic
+// of each as replicates[b][k]. Blocks of replicates are spread over workers goroutines.
+func runBootstrap(data []Grade, stat bootstrapStatistic, replicates, workers int, seed int64) [][]float64 {
+	results := make([][]float64, replicates)
+	blocks := (replicates + bootstrapBlockSize - 1) / bootstrapBlockSize
+	next := make(chan int, blocks)
+	for block := 0; block < blocks; block++ {
+		next <- block
+	}
+	close(next)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sample := make([]Grade, len(data))
+			for block := range next {
+				rng := rand.New(rand.NewSource(seed*bootstrapSeedStride + int64(block)))
+				end := (block + 1) * bootstrapBlockSize
+				if end > replicates {
+					end = replicates
+				}
+				for b := block * bootstrapBlockSize; b < end; b++ {
+					for i := range sample {
+						sample[i] = data[rng.Intn(len(data))]
+					}
+					results[b] = stat.compute(sample)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// jackknife returns the leave-one-out statistics as values[i][k]
+func jackknife(data []Grade, stat bootstrapStatistic) [][]float64 {
+	values := make([][]float64, len(data))
+	sample := make([]Grade, 0, len(data)-1)
+	for i := range data {
+		sample = append(sample[:0], data[:i]...)
+		sample = append(sample, data[i+1:]...)
+		values[i] = stat.compute(sample)
+	}
+	return values
+}
+
+// summarizeBootstrap computes the percentile and BCa intervals (Efron & Tibshirani 1993,
+// §14.3) of one statistic from its replicates and jackknife values
+func summarizeBootstrap(name string, estimate float64, replicates, jackknifeValues []float64, level float64) BootstrapEstimate {
+	valid := make([]float64, 0, len(replicates))
+	for _, v := range replicates {
+		if !math.IsNaN(v) {
+			valid = append(valid, v)
+		}
+	}
+	// The point estimate is kept even when too few replicates are valid for the intervals
+	result := BootstrapEstimate{Name: name, Estimate: finiteOrNil(estimate), Invalid: len(replicates) - len(valid)}
+	if len(valid) < 2 || result.Estimate == nil {
+		return result
+	}
+	sort.Float64s(valid)
+	mean, sd := meanAndSD(valid)
+	result.BootstrapMean = mean
+	result.Bias = mean - estimate
+	result.SE = sd
+
+	tail := (1 - level) / 2
+	result.Percentile = &ConfidenceInterval{Lower: quantile(valid, tail), Upper: quantile(valid, 1-tail)}
+
+	// Bias correction z0 from the share of replicates below the estimate, counting ties as half
+	var below float64
+	for _, v := range valid {
+		if v < estimate {
+			below++
+		} else if v == estimate {
+			below += 0.5
+		}
+	}
+	z0 := normalQuantile(below / float64(len(valid)))
+	if math.IsInf(z0, 0) || math.IsNaN(z0) {
+		return result
+	}
+
+	// Acceleration from the skewness of the jackknife values
+	var jackMean float64
+	for _, v := range jackknifeValues {
+		if math.IsNaN(v) {
+			return result
+		}
+		jackMean += v / float64(len(jackknifeValues))
+	}
+	var num, den float64
+	for _, v := range jackknifeValues {
+		d := jackMean - v
+		num += d * d * d
+		den += d * d
+	}
+	a := 0.0
+	if den > 0 {
+		a = num / (6 * math.Pow(den, 1.5))
+	}
+
+	adjusted := func(p float64) float64 {
+		z := normalQuantile(p)
+		return normalCDF(z0 + (z0+z)/(1-a*(z0+z)))
+	}
+	result.BiasCorrection = &z0
+	result.Acceleration = &a
+	result.BCa = &ConfidenceInterval{
+		Lower: quantile(valid, adjusted(tail)),
+		Upper: quantile(valid, adjusted(1-tail)),
+	}
+	return result
+}
+
+// Handler: Get bootstrap confidence intervals
+// Resamples students with replacement and returns percentile and BCa intervals for the mean or
+// median Total, item correct rates, a correlation (a, b) or Cronbach's alpha
+func getBootstrap(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	statName := r.URL.Query().Get("statistic")
+	var a, b int
+	switch statName {
+	case "mean", "median", "item-rates", "reliability":
+	case "correlation":
+		var okA, okB bool
+		a, okA = parseTableVariable(r.URL.Query().Get("a"))
+		b, okB = parseTableVariable(r.URL.Query().Get("b"))
+		if !okA || !okB {
+			http.Error(w, "Invalid 'a' or 'b' parameter (must be q1-q10 or total)", http.StatusBadRequest)
+			return
+		}
+	case "":
+		http.Error(w, "Missing 'statistic' parameter", http.StatusBadRequest)
+		return
+	default:
+		http.Error(w, "Invalid 'statistic' parameter (must be mean, median, item-rates, correlation or reliability)", http.StatusBadRequest)
+		return
+	}
+
+	replicates, err := intParam(r, "replicates", 2000)
+	if err != nil || replicates < 100 || replicates > 100000 {
+		http.Error(w, "Invalid 'replicates' parameter (must be between 100 and 100000)", http.StatusBadRequest)
+		return
+	}
+	level, err := floatParam(r, "level", 0.95)
+	if err != nil || level <= 0 || level >= 1 {
+		http.Error(w, "Invalid 'level' parameter (must be between 0 and 1)", http.StatusBadRequest)
+		return
+	}
+	seed, err := intParam(r, "seed", 1)
+	if err != nil {
+		http.Error(w, "Invalid 'seed' parameter", http.StatusBadRequest)
+		return
+	}
+
+	if len(grades) == 0 {
+		http.Error(w, "No data available", http.StatusInternalServerError)
+		return
+	}
+	if replicates > maxBootstrapWork/len(grades) {
+		http.Error(w, "Too much work: replicates × students must not exceed 50000000", http.StatusBadRequest)
+		return
+	}
+
+	data := append([]Grade(nil), grades...)
+	stat := newBootstrapStatistic(statName, a, b)
+	workers := runtime.NumCPU()
+	if workers > 8 {
+		workers = 8
+	}
+	boot := runBootstrap(data, stat, replicates, workers, int64(seed))
+	estimates := stat.compute(data)
+	var jack [][]float64
+	if len(data) > 1 {
+		jack = jackknife(data, stat)
+	}
+
+	response := BootstrapResponse{
+		Statistic:  statName,
+		N:          len(data),
+		Replicates: replicates,
+		Level:      level,
+		Seed:       int64(seed),
+		Warnings:   []string{},
+	}
+	for k, name := range stat.names {
+		column := make([]float64, replicates)
+		for i := range boot {
+			column[i] = boot[i][k]
+		}
+		jackColumn := make([]float64, len(jack))
+		for i := range jack {
+			jackColumn[i] = jack[i][k]
+		}
+		estimate := summarizeBootstrap(name, estimates[k], column, jackColumn, level)
+		if estimate.Invalid > 0 {
+			response.Warnings = append(response.Warnings,
+				fmt.Sprintf("%s is undefined in %d replicates, which were dropped", name, estimate.Invalid))
+		}
+		switch {
+		case estimate.Estimate == nil:
+			response.Warnings = append(response.Warnings, name+" is undefined for this sample, so no intervals are given")
+		case estimate.Percentile == nil:
+			response.Warnings = append(response.Warnings, name+": fewer than 2 valid replicates, so no intervals are given")
+		case estimate.BCa == nil:
+			response.Warnings = append(response.Warnings, name+": BCa interval is undefined for this sample")
+		}
+		response.Estimates = append(response.Estimates, estimate)
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// TestGetBootstrapMean - 平均のブートストラップ区間のテスト
+func TestGetBootstrapMean(t *testing.T) {
+	setupTotalsTestData(2, 3, 4, 5, 5, 6, 6, 7, 8, 9)
+
+	var result BootstrapResponse
+	serveJSON(t, getBootstrap, "GET", "/api/bootstrap?statistic=mean&replicates=4000&seed=3", "", &result)
+
+	if len(result.Estimates) != 1 {
+		t.Fatalf("expected 1 estimate, got %d", len(result.Estimates))
+	}
+	e := result.Estimates[0]
+	if e.Name != "mean_total" || e.Estimate == nil || math.Abs(*e.Estimate-5.5) > 1e-9 {
+		t.Errorf("unexpected estimate: %+v", e)
+	}
+	// 平均のブートストラップ標準誤差は標本標準偏差 × sqrt((n-1)/n) / sqrt(n) に近い
+	_, sd := meanAndSD([]float64{2, 3, 4, 5, 5, 6, 6, 7, 8, 9})
+	expectedSE := sd * math.Sqrt(0.9) / math.Sqrt(10)
+	if math.Abs(e.SE-expectedSE) > 0.05 {
+		t.Errorf("expected SE near %v, got %v", expectedSE, e.SE)
+	}
+	if e.Percentile == nil || e.Percentile.Lower >= *e.Estimate || e.Percentile.Upper <= *e.Estimate {
+		t.Errorf("percentile interval %+v does not contain the estimate", e.Percentile)
+	}
+	if e.BCa == nil || e.BCa.Lower >= *e.Estimate || e.BCa.Upper <= *e.Estimate {
+		t.Errorf("BCa interval %+v does not contain the estimate", e.BCa)
+	}
+}
+
+// TestRunBootstrapIsReproducibleAcrossWorkers - ワーカー数によらず同じシードで同じ結果になることのテスト
+func TestRunBootstrapIsReproducibleAcrossWorkers(t *testing.T) {
+	setupTestData()
+	stat := newBootstrapStatistic("item-rates", 0, 0)
+
+	single := runBootstrap(grades, stat, 500, 1, 7)
+	parallel := runBootstrap(grades, stat, 500, 4, 7)
+	if !reflect.DeepEqual(single, parallel) {
+		t.Error("replicates depend on the number of workers")
+	}
+	other := runBootstrap(grades, stat, 500, 4, 8)
+	if reflect.DeepEqual(single, other) {
+		t.Error("different seeds produced identical replicates")
+	}
+}
+
+// TestGetBootstrapItemRatesAndCorrelation - 正答率と相関のブートストラップのテスト
+func TestGetBootstrapItemRatesAndCorrelation(t *testing.T) {
+	setupTestData()
+
+	var result BootstrapResponse
+	serveJSON(t, getBootstrap, "GET", "/api/bootstrap?statistic=item-rates&replicates=200", "", &result)
+	if len(result.Estimates) != numQuestions || result.Estimates[7].Name != "Q8" {
+		t.Fatalf("unexpected item estimates: %+v", result.Estimates)
+	}
+	if result.Estimates[7].Estimate == nil || math.Abs(*result.Estimates[7].Estimate-1.0/3) > 1e-9 {
+		t.Errorf("expected Q8 rate 1/3, got %v", result.Estimates[7].Estimate)
+	}
+
+	result = BootstrapResponse{}
+	serveJSON(t, getBootstrap, "GET", "/api/bootstrap?statistic=correlation&a=q8&b=total&replicates=200", "", &result)
+	if len(result.Estimates) != 1 || result.Estimates[0].Name != "Q8-Total" {
+		t.Fatalf("unexpected correlation estimate: %+v", result.Estimates)
+	}
+	if result.Estimates[0].Estimate == nil || *result.Estimates[0].Estimate <= 0 {
+		t.Errorf("expected a positive correlation, got %v", result.Estimates[0].Estimate)
+	}
+	// Resamples in which Q8 or Total is constant have no correlation and are dropped
+	if result.Estimates[0].Invalid == 0 || result.Estimates[0].Invalid >= 200 {
+		t.Errorf("expected some but not all replicates to be undefined, got %d invalid", result.Estimates[0].Invalid)
+	}
+}
+
+// TestCronbachAlpha - Cronbachのαの計算のテスト
+func TestCronbachAlpha(t *testing.T) {
+	// 全問正解と全問不正解の2人では α = 1
+	all := Grade{Q1: 1, Q2: 1, Q3: 1, Q4: 1, Q5: 1, Q6: 1, Q7: 1, Q8: 1, Q9: 1, Q10: 1, Total: 10}
+	if alpha := cronbachAlpha([]Grade{all, {}}); math.Abs(alpha-1) > 1e-9 {
+		t.Errorf("expected alpha 1, got %v", alpha)
+	}
+	// 合計点に分散がなければ未定義
+	if alpha := cronbachAlpha([]Grade{all, all}); !math.IsNaN(alpha) {
+		t.Errorf("expected NaN, got %v", alpha)
+	}
+}
+
+// TestGetBootstrapReliabilityDropsUndefinedReplicates - αが未定義になる再標本の除外のテスト
+func TestGetBootstrapReliabilityDropsUndefinedReplicates(t *testing.T) {
+	setupTestData()
+
+	var result BootstrapResponse
+	serveJSON(t, getBootstrap, "GET", "/api/bootstrap?statistic=reliability&replicates=500", "", &result)
+	e := result.Estimates[0]
+	if e.Invalid == 0 || len(result.Warnings) == 0 {
+		t.Errorf("expected undefined replicates to be reported, got %+v", result)
+	}
+	if e.Invalid >= 500 {
+		t.Errorf("expected some valid replicates, got %d invalid", e.Invalid)
+	}
+}
+
+// TestSummarizeBootstrapTooFewValid - 有効な再標本が少なくても点推定値が保たれるかのテスト
+func TestSummarizeBootstrapTooFewValid(t *testing.T) {
+	nan := math.NaN()
+	e := summarizeBootstrap("r", 0.7, []float64{nan, nan, 0.5, nan}, nil, 0.95)
+	if e.Estimate == nil || *e.Estimate != 0.7 || e.Invalid != 3 {
+		t.Errorf("expected estimate 0.7 with 3 invalid replicates, got %+v", e)
+	}
+	if e.Percentile != nil || e.BCa != nil {
+		t.Errorf("expected no intervals from a single valid replicate, got %+v and %+v", e.Percentile, e.BCa)
+	}
+	if e := summarizeBootstrap("r", nan, []float64{0.1, 0.2, 0.3}, nil, 0.95); e.Estimate != nil {
+		t.Errorf("expected a null estimate when it is undefined, got %v", *e.Estimate)
+	}
+}
+
+// TestGetBootstrapInvalidParameters - 不正なパラメータのテスト
+func TestGetBootstrapInvalidParameters(t *testing.T) {
+	setupTestData()
+
+	for _, query := range []string{
+		"",
+		"statistic=variance",
+		"statistic=correlation&a=q1",
+		"statistic=mean&replicates=10",
+		"statistic=mean&level=1",
+		"statistic=mean&seed=x",
+	} {
+		req, err := http.NewRequest("GET", "/api/bootstrap?"+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(getBootstrap).ServeHTTP(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("query %q: expected 400, got %d", query, rr.Code)
+		}
+	}
+}
@@ -0,0 +1,565 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Computerized adaptive testing. The items behind grades are calibrated with a Rasch model (JML)
+// and a session then administers them one at a time, tracking the posterior of the student's
+// ability θ on a grid, choosing each next item by maximum Fisher information at the posterior
+// mean or by minimum expected posterior variance, and stopping once the posterior SD falls
+// below a threshold or the item limit is reached.
+
+const (
+	// catGridPoints, catGridMin and catGridMax define the θ grid of the posterior
+	catGridPoints = 241
+	catGridMin    = -6.0
+	catGridMax    = 6.0
+	// maxCATSessions bounds the sessions kept in memory; the oldest is dropped beyond it
+	maxCATSessions = 1000
+)
+
+// CATItem represents one calibrated item of the bank
+type CATItem struct {
+	Question   string  `json:"question"`
+	Difficulty float64 `json:"difficulty"`
+}
+
+// CATCalibration represents the Rasch calibration of the item bank
+type CATCalibration struct {
+	N           int       `json:"n"`
+	Converged   bool      `json:"converged"`
+	AbilityMean float64   `json:"ability_mean"`
+	AbilitySD   float64   `json:"ability_sd"`
+	Items       []CATItem `json:"items"`
+	Excluded    []string  `json:"excluded"`
+}
+
+// calibrateCATBank fits the Rasch model to the response matrix. Items answered correctly or
+// incorrectly by every student with a non-extreme score cannot be calibrated and are excluded.
+func calibrateCATBank() (CATCalibration, error) {
+	responses, err := binaryResponseMatrix()
+	if err != nil {
+		return CATCalibration{}, err
+	}
+	fit := fitRaschJML(responses)
+	calibration := CATCalibration{
+		N:         len(responses),
+		Converged: fit.converged,
+		Items:     []CATItem{},
+		Excluded:  []string{},
+	}
+	for j, label := range questionLabels() {
+		if fit.itemEstimable[j] {
+			calibration.Items = append(calibration.Items, CATItem{Question: label, Difficulty: fit.difficulty[j]})
+		} else {
+			calibration.Excluded = append(calibration.Excluded, label)
+		}
+	}
+	var abilities []float64
+	for i, ok := range fit.personEstimable {
+		if ok {
+			abilities = append(abilities, fit.ability[i])
+		}
+	}
+	if len(calibration.Items) < 2 || len(abilities) < 2 {
+		return calibration, fmt.Errorf("too few items or students with non-extreme scores to calibrate the item bank")
+	}
+	calibration.AbilityMean, calibration.AbilitySD = meanAndSD(abilities)
+	return calibration, nil
+}
+
+// CATSessionConfig is the JSON body that starts a session; omitted fields take their defaults
+type CATSessionConfig struct {
+	Selection   string   `json:"selection"`
+	MaxItems    int      `json:"max_items"`
+	SEThreshold float64  `json:"se_threshold"`
+	PriorMean   *float64 `json:"prior_mean"`
+	PriorSD     *float64 `json:"prior_sd"`
+}
+
+// CATAnsweredItem represents one administered item and the ability estimate after it
+type CATAnsweredItem struct {
+	Question   string  `json:"question"`
+	Difficulty float64 `json:"difficulty"`
+	Correct    int     `json:"correct"`
+	Theta      float64 `json:"theta"`
+	SE         float64 `json:"se"`
+}
+
+// CATPosteriorPoint is the posterior density of θ at one grid point
+type CATPosteriorPoint struct {
+	Theta   float64 `json:"theta"`
+	Density float64 `json:"density"`
+}
+
+// CATSessionState represents the current state of a session
+type CATSessionState struct {
+	SessionID     string              `json:"session_id"`
+	Selection     string              `json:"selection"`
+	MaxItems      int                 `json:"max_items"`
+	SEThreshold   float64             `json:"se_threshold"`
+	PriorMean     float64             `json:"prior_mean"`
+	PriorSD       float64             `json:"prior_sd"`
+	Theta         float64             `json:"theta"`
+	SE            float64             `json:"se"`
+	ThetaInterval ConfidenceInterval  `json:"theta_interval"`
+	Administered  int                 `json:"administered"`
+	Remaining     int                 `json:"remaining"`
+	Finished      bool                `json:"finished"`
+	StopReason    string              `json:"stop_reason,omitempty"`
+	History       []CATAnsweredItem   `json:"history"`
+	Posterior     []CATPosteriorPoint `json:"posterior,omitempty"`
+}
+
+// CATNextItem represents the item chosen for administration
+type CATNextItem struct {
+	SessionID                 string   `json:"session_id"`
+	Selection                 string   `json:"selection"`
+	Question                  string   `json:"question"`
+	Difficulty                float64  `json:"difficulty"`
+	Information               *float64 `json:"information,omitempty"`
+	ExpectedPosteriorVariance *float64 `json:"expected_posterior_variance,omitempty"`
+}
+
+// CATAnswer is the JSON body that submits a response
+type CATAnswer struct {
+	Question string `json:"question"`
+	Correct  *int   `json:"correct"`
+}
+
+// catSession is one adaptive test. The item bank is copied at the start so that later changes
+// to grades do not affect a running session.
+type catSession struct {
+	id          string
+	created     time.Time
+	bank        []CATItem
+	selection   string
+	maxItems    int
+	seThreshold float64
+	priorMean   float64
+	priorSD     float64
+	history     []CATAnsweredItem
+	// posterior holds the normalized posterior weights on the θ grid
+	posterior  []float64
+	stopReason string
+}
+
+// catStore holds the running sessions, written by every request of the session API
+var catStore struct {
+	sync.Mutex
+	sessions map[string]*catSession
+}
+
+// catGrid returns the θ value of grid point g
+func catGrid(g int) float64 {
+	return catGridMin + (catGridMax-catGridMin)*float64(g)/float64(catGridPoints-1)
+}
+
+// normalizeWeights turns log weights into weights summing to one, in place
+func normalizeWeights(logWeights []float64) []float64 {
+	total := logSumExp(logWeights)
+	for g := range logWeights {
+		logWeights[g] = math.Exp(logWeights[g] - total)
+	}
+	return logWeights
+}
+
+// posteriorMoments returns the mean and standard deviation of θ under grid weights
+func posteriorMoments(weights []float64) (float64, float64) {
+	var mean, second float64
+	for g, w := range weights {
+		theta := catGrid(g)
+		mean += w * theta
+		second += w * theta * theta
+	}
+	return mean, math.Sqrt(math.Max(second-mean*mean, 0))
+}
+
+// updatedPosterior returns the posterior after answering an item of the given difficulty
+func updatedPosterior(weights []float64, difficulty float64, correct int) []float64 {
+	logWeights := make([]float64, len(weights))
+	for g, w := range weights {
+		p := sigmoid(catGrid(g) - difficulty)
+		if correct == 0 {
+			p = 1 - p
+		}
+		logWeights[g] = math.Log(w) + math.Log(p)
+	}
+	return normalizeWeights(logWeights)
+}
+
+// remaining returns the bank items not yet administered
+func (s *catSession) remaining() []CATItem {
+	answered := make(map[string]bool)
+	for _, h := range s.history {
+		answered[h.Question] = true
+	}
+	var items []CATItem
+	for _, item := range s.bank {
+		if !answered[item.Question] {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// checkStop records why the session ends, if it does
+func (s *catSession) checkStop() {
+	_, se := posteriorMoments(s.posterior)
+	switch {
+	case se <= s.seThreshold:
+		s.stopReason = "se_threshold"
+	case len(s.history) >= s.maxItems:
+		s.stopReason = "max_items"
+	case len(s.remaining()) == 0:
+		s.stopReason = "bank_exhausted"
+	}
+}
+
+// nextItem selects the next item by maximum Fisher information p(1-p) at the posterior mean, or
+// by minimum expected posterior variance over the predictive distribution of the response
+func (s *catSession) nextItem() CATNextItem {
+	theta, _ := posteriorMoments(s.posterior)
+	next := CATNextItem{SessionID: s.id, Selection: s.selection}
+	best := math.Inf(1)
+	for _, item := range s.remaining() {
+		var criterion float64
+		switch s.selection {
+		case "mfi":
+			p := sigmoid(theta - item.Difficulty)
+			criterion = -p * (1 - p)
+		case "epv":
+			pCorrect := 0.0
+			for g, w := range s.posterior {
+				pCorrect += w * sigmoid(catGrid(g)-item.Difficulty)
+			}
+			_, sdCorrect := posteriorMoments(updatedPosterior(s.posterior, item.Difficulty, 1))
+			_, sdIncorrect := posteriorMoments(updatedPosterior(s.posterior, item.Difficulty, 0))
+			criterion = pCorrect*sdCorrect*sdCorrect + (1-pCorrect)*sdIncorrect*sdIncorrect
+		}
+		if criterion < best {
+			best = criterion
+			next.Question = item.Question
+			next.Difficulty = item.Difficulty
+		}
+	}
+	if s.selection == "mfi" {
+		information := -best
+		next.Information = &information
+	} else {
+		next.ExpectedPosteriorVariance = &best
+	}
+	return next
+}
+
+// state summarizes the session, including the posterior density when requested
+func (s *catSession) state(includePosterior bool) CATSessionState {
+	theta, se := posteriorMoments(s.posterior)
+	state := CATSessionState{
+		SessionID:    s.id,
+		Selection:    s.selection,
+		MaxItems:     s.maxItems,
+		SEThreshold:  s.seThreshold,
+		PriorMean:    s.priorMean,
+		PriorSD:      s.priorSD,
+		Theta:        theta,
+		SE:           se,
+		Administered: len(s.history),
+		Remaining:    len(s.remaining()),
+		Finished:     s.stopReason != "",
+		StopReason:   s.stopReason,
+		History:      append([]CATAnsweredItem{}, s.history...),
+	}
+
+	// 95% equal-tailed credible interval from the grid CDF
+	cumulative := 0.0
+	state.ThetaInterval = ConfidenceInterval{Lower: catGridMin, Upper: catGridMax}
+	lowerSet := false
+	for g, w := range s.posterior {
+		cumulative += w
+		if !lowerSet && cumulative >= 0.025 {
+			state.ThetaInterval.Lower = catGrid(g)
+			lowerSet = true
+		}
+		if cumulative >= 0.975 {
+			state.ThetaInterval.Upper = catGrid(g)
+			break
+		}
+	}
+
+	if includePosterior {
+		step := (catGridMax - catGridMin) / float64(catGridPoints-1)
+		for g, w := range s.posterior {
+			state.Posterior = append(state.Posterior, CATPosteriorPoint{Theta: catGrid(g), Density: w / step})
+		}
+	}
+	return state
+}
+
+// newSessionID returns a random hexadecimal session identifier
+func newSessionID() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// lookupCATSession returns the session named in the URL, writing a 404 when there is none.
+// The caller must hold catStore.
+func lookupCATSession(w http.ResponseWriter, r *http.Request) *catSession {
+	s := catStore.sessions[mux.Vars(r)["id"]]
+	if s == nil {
+		writeJSONError(w, http.StatusNotFound, ErrorResponse{Error: "Unknown CAT session"})
+	}
+	return s
+}
+
+// Handler: Get CAT item bank
+// Calibrates the item bank with a Rasch model fitted to the response matrix
+func getCATItems(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if len(grades) == 0 {
+		http.Error(w, "No data available", http.StatusInternalServerError)
+		return
+	}
+
+	calibration, err := calibrateCATBank()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(calibration)
+}
+
+// Handler: Start a CAT session
+// Calibrates the item bank and starts an adaptive test configured by the optional JSON body
+// {"selection": "mfi"|"epv", "max_items", "se_threshold", "prior_mean", "prior_sd"}; the prior
+// defaults to the ability distribution of the calibration sample
+func postCATSession(w http.ResponseWriter, r *http.Request) {
+	config := CATSessionConfig{}
+	if r.ContentLength != 0 {
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&config); err != nil {
+			writeJSONError(w, http.StatusBadRequest, ErrorResponse{Error: "Invalid session JSON: " + err.Error()})
+			return
+		}
+	}
+	if config.Selection == "" {
+		config.Selection = "mfi"
+	}
+	if config.Selection != "mfi" && config.Selection != "epv" {
+		writeJSONError(w, http.StatusBadRequest, ErrorResponse{Error: "selection must be mfi or epv", Parameter: "selection"})
+		return
+	}
+	if config.MaxItems == 0 {
+		config.MaxItems = numQuestions
+	}
+	if config.MaxItems < 1 || config.MaxItems > numQuestions {
+		writeJSONError(w, http.StatusBadRequest, ErrorResponse{Error: "max_items must be between 1 and 10", Parameter: "max_items"})
+		return
+	}
+	if config.SEThreshold == 0 {
+		config.SEThreshold = 0.4
+	}
+	if config.SEThreshold < 0 {
+		writeJSONError(w, http.StatusBadRequest, ErrorResponse{Error: "se_threshold must be positive", Parameter: "se_threshold"})
+		return
+	}
+	if config.PriorSD != nil && *config.PriorSD <= 0 {
+		writeJSONError(w, http.StatusBadRequest, ErrorResponse{Error: "prior_sd must be positive", Parameter: "prior_sd"})
+		return
+	}
+	if config.PriorMean != nil && (*config.PriorMean < catGridMin || *config.PriorMean > catGridMax) {
+		writeJSONError(w, http.StatusBadRequest, ErrorResponse{Error: "prior_mean must be between -6 and 6", Parameter: "prior_mean"})
+		return
+	}
+
+	if len(grades) == 0 {
+		writeJSONError(w, http.StatusInternalServerError, ErrorResponse{Error: "No data available"})
+		return
+	}
+	calibration, err := calibrateCATBank()
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+	id, err := newSessionID()
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, ErrorResponse{Error: "Could not create a session ID"})
+		return
+	}
+
+	s := &catSession{
+		id:          id,
+		created:     time.Now(),
+		bank:        calibration.Items,
+		selection:   config.Selection,
+		maxItems:    config.MaxItems,
+		seThreshold: config.SEThreshold,
+		priorMean:   calibration.AbilityMean,
+		priorSD:     calibration.AbilitySD,
+		history:     []CATAnsweredItem{},
+	}
+	if config.PriorMean != nil {
+		s.priorMean = *config.PriorMean
+	}
+	if config.PriorSD != nil {
+		s.priorSD = *config.PriorSD
+	}
+	if !(s.priorSD > 0) {
+		writeJSONError(w, http.StatusInternalServerError, ErrorResponse{Error: "The calibration sample has no ability spread; set prior_sd explicitly"})
+		return
+	}
+	logPrior := make([]float64, catGridPoints)
+	peak := math.Inf(-1)
+	for g := range logPrior {
+		z := (catGrid(g) - s.priorMean) / s.priorSD
+		logPrior[g] = -0.5 * z * z
+		peak = math.Max(peak, logPrior[g])
+	}
+	// A prior far narrower than the grid spacing puts no weight on any grid point
+	if math.IsInf(peak, -1) {
+		writeJSONError(w, http.StatusBadRequest, ErrorResponse{Error: "prior_sd is too small for the θ grid", Parameter: "prior_sd"})
+		return
+	}
+	s.posterior = normalizeWeights(logPrior)
+	s.checkStop()
+
+	catStore.Lock()
+	if catStore.sessions == nil {
+		catStore.sessions = make(map[string]*catSession)
+	}
+	if len(catStore.sessions) >= maxCATSessions {
+		var oldest *catSession
+		for _, other := range catStore.sessions {
+			if oldest == nil || other.created.Before(oldest.created) {
+				oldest = other
+			}
+		}
+		delete(catStore.sessions, oldest.id)
+	}
+	catStore.sessions[id] = s
+	state := s.state(false)
+	catStore.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(state)
+}
+
+// Handler: Get a CAT session
+// Returns the ability estimate and history, with the posterior density when posterior=true
+func getCATSession(w http.ResponseWriter, r *http.Request) {
+	catStore.Lock()
+	defer catStore.Unlock()
+	s := lookupCATSession(w, r)
+	if s == nil {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.state(r.URL.Query().Get("posterior") == "true"))
+}
+
+// Handler: Get the next CAT item
+// Selects the next item to administer with the session's selection rule
+func getCATNextItem(w http.ResponseWriter, r *http.Request) {
+	catStore.Lock()
+	defer catStore.Unlock()
+	s := lookupCATSession(w, r)
+	if s == nil {
+		return
+	}
+	if s.stopReason != "" {
+		writeJSONError(w, http.StatusConflict, ErrorResponse{Error: "CAT session has finished (" + s.stopReason + ")"})
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.nextItem())
+}
+
+// Handler: Submit a CAT response
+// Records the JSON body {"question": "Q3", "correct": 0|1} for an item not yet administered and
+// returns the updated θ posterior
+func postCATResponse(w http.ResponseWriter, r *http.Request) {
+	var answer CATAnswer
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&answer); err != nil {
+		writeJSONError(w, http.StatusBadRequest, ErrorResponse{Error: "Invalid response JSON: " + err.Error()})
+		return
+	}
+	if answer.Correct == nil || (*answer.Correct != 0 && *answer.Correct != 1) {
+		writeJSONError(w, http.StatusBadRequest, ErrorResponse{Error: "correct must be 0 or 1", Parameter: "correct"})
+		return
+	}
+	q, err := parseQuestion(strings.ToLower(answer.Question))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, ErrorResponse{Error: err.Error(), Parameter: "question"})
+		return
+	}
+
+	catStore.Lock()
+	defer catStore.Unlock()
+	s := lookupCATSession(w, r)
+	if s == nil {
+		return
+	}
+	if s.stopReason != "" {
+		writeJSONError(w, http.StatusConflict, ErrorResponse{Error: "CAT session has finished (" + s.stopReason + ")"})
+		return
+	}
+	label := fmt.Sprintf("Q%d", q)
+	var item *CATItem
+	remaining := s.remaining()
+	for k := range remaining {
+		if remaining[k].Question == label {
+			item = &remaining[k]
+			break
+		}
+	}
+	if item == nil {
+		writeJSONError(w, http.StatusBadRequest, ErrorResponse{Error: label + " is not in the item bank or was already administered", Parameter: "question"})
+		return
+	}
+
+	s.posterior = updatedPosterior(s.posterior, item.Difficulty, *answer.Correct)
+	theta, se := posteriorMoments(s.posterior)
+	s.history = append(s.history, CATAnsweredItem{
+		Question:   label,
+		Difficulty: item.Difficulty,
+		Correct:    *answer.Correct,
+		Theta:      theta,
+		SE:         se,
+	})
+	s.checkStop()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.state(false))
+}
+
+// Handler: Delete a CAT session
+func deleteCATSession(w http.ResponseWriter, r *http.Request) {
+	catStore.Lock()
+	defer catStore.Unlock()
+	s := lookupCATSession(w, r)
+	if s == nil {
+		return
+	}
+	delete(catStore.sessions, s.id)
+	w.WriteHeader(http.StatusNoContent)
+}
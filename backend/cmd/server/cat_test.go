@@ -0,0 +1,240 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+var catTestDifficulties = []float64{-2, -1.5, -1, -0.5, 0, 0, 0.5, 1, 1.5, 2}
+
+// serveCAT - CATのルートを登録したルーターでリクエストを処理する
+func serveCAT(t *testing.T, method, path, body string) *httptest.ResponseRecorder {
+	router := mux.NewRouter()
+	router.HandleFunc("/api/cat/items", getCATItems).Methods("GET")
+	router.HandleFunc("/api/cat/sessions", postCATSession).Methods("POST")
+	router.HandleFunc("/api/cat/sessions/{id}", getCATSession).Methods("GET")
+	router.HandleFunc("/api/cat/sessions/{id}", deleteCATSession).Methods("DELETE")
+	router.HandleFunc("/api/cat/sessions/{id}/next", getCATNextItem).Methods("GET")
+	router.HandleFunc("/api/cat/sessions/{id}/responses", postCATResponse).Methods("POST")
+
+	req, err := http.NewRequest(method, path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	return rr
+}
+
+// TestCATItemCalibration - 項目バンクの難易度推定のテスト
+func TestCATItemCalibration(t *testing.T) {
+	setupRaschTestData(500, catTestDifficulties, 7)
+
+	var calibration CATCalibration
+	decodeJSON(t, serveCAT(t, "GET", "/api/cat/items", ""), http.StatusOK, &calibration)
+
+	if !calibration.Converged || calibration.N != 500 {
+		t.Errorf("expected a converged calibration of 500 students, got %v, %d", calibration.Converged, calibration.N)
+	}
+	if len(calibration.Items) != numQuestions || len(calibration.Excluded) != 0 {
+		t.Fatalf("expected all %d items calibrated, got %d (excluded %v)", numQuestions, len(calibration.Items), calibration.Excluded)
+	}
+	for j, item := range calibration.Items {
+		if math.Abs(item.Difficulty-catTestDifficulties[j]) > 0.4 {
+			t.Errorf("%s: expected difficulty near %v, got %v", item.Question, catTestDifficulties[j], item.Difficulty)
+		}
+	}
+	if calibration.AbilitySD < 0.5 || calibration.AbilitySD > 1.5 {
+		t.Errorf("expected ability SD near 1, got %v", calibration.AbilitySD)
+	}
+}
+
+// TestCATSessionMaximumInformation - 最大情報量による出題と項目数による終了のテスト
+func TestCATSessionMaximumInformation(t *testing.T) {
+	setupRaschTestData(500, catTestDifficulties, 7)
+
+	var calibration CATCalibration
+	decodeJSON(t, serveCAT(t, "GET", "/api/cat/items", ""), http.StatusOK, &calibration)
+	var state CATSessionState
+	decodeJSON(t, serveCAT(t, "POST", "/api/cat/sessions", `{"max_items": 6, "se_threshold": 0.1}`), http.StatusCreated, &state)
+	if state.SessionID == "" || state.Selection != "mfi" || state.Finished {
+		t.Fatalf("unexpected initial state: %+v", state)
+	}
+	id := state.SessionID
+	previousSE := state.SE
+
+	seen := map[string]bool{}
+	for step := 0; step < 6; step++ {
+		var next CATNextItem
+		decodeJSON(t, serveCAT(t, "GET", "/api/cat/sessions/"+id+"/next", ""), http.StatusOK, &next)
+		if seen[next.Question] {
+			t.Fatalf("item %s selected twice", next.Question)
+		}
+		seen[next.Question] = true
+		if next.Information == nil {
+			t.Fatal("expected the information of the selected item")
+		}
+		// The first item is the one closest to the prior mean
+		if step == 0 {
+			for _, item := range calibration.Items {
+				if math.Abs(item.Difficulty-state.PriorMean) < math.Abs(next.Difficulty-state.PriorMean)-1e-9 {
+					t.Errorf("first item %s (difficulty %v) is not the most informative at θ = %v",
+						next.Question, next.Difficulty, state.PriorMean)
+				}
+			}
+		}
+
+		// A simulated examinee with θ = 1 answers items easier than their ability correctly
+		correct := 0
+		if next.Difficulty < 1 {
+			correct = 1
+		}
+		body := `{"question": "` + next.Question + `", "correct": ` + strconv.Itoa(correct) + `}`
+		decodeJSON(t, serveCAT(t, "POST", "/api/cat/sessions/"+id+"/responses", body), http.StatusOK, &state)
+		if state.Administered != step+1 || len(state.History) != step+1 {
+			t.Fatalf("expected %d administered items, got %d", step+1, state.Administered)
+		}
+		if state.SE > previousSE+1e-9 {
+			t.Errorf("posterior SD increased from %v to %v", previousSE, state.SE)
+		}
+		previousSE = state.SE
+	}
+
+	if !state.Finished || state.StopReason != "max_items" {
+		t.Errorf("expected the session to stop at max_items, got finished=%v reason %q", state.Finished, state.StopReason)
+	}
+	if state.Theta < 0 || state.ThetaInterval.Lower > state.Theta || state.ThetaInterval.Upper < state.Theta {
+		t.Errorf("expected a positive ability inside its interval, got %v %+v", state.Theta, state.ThetaInterval)
+	}
+	rr := serveCAT(t, "GET", "/api/cat/sessions/"+id+"/next", "")
+	if rr.Code != http.StatusConflict {
+		t.Errorf("expected status 409 for a finished session, got %d", rr.Code)
+	}
+}
+
+// TestCATSessionExpectedPosteriorVariance - 期待事後分散による出題と標準誤差による終了のテスト
+func TestCATSessionExpectedPosteriorVariance(t *testing.T) {
+	setupRaschTestData(500, catTestDifficulties, 7)
+
+	var state CATSessionState
+	decodeJSON(t, serveCAT(t, "POST", "/api/cat/sessions",
+		`{"selection": "epv", "se_threshold": 0.8, "prior_mean": 0, "prior_sd": 1}`), http.StatusCreated, &state)
+	id := state.SessionID
+
+	for !state.Finished {
+		var next CATNextItem
+		decodeJSON(t, serveCAT(t, "GET", "/api/cat/sessions/"+id+"/next", ""), http.StatusOK, &next)
+		if next.ExpectedPosteriorVariance == nil || *next.ExpectedPosteriorVariance >= state.SE*state.SE {
+			t.Fatalf("expected the selected item to reduce the posterior variance %v, got %v",
+				state.SE*state.SE, next.ExpectedPosteriorVariance)
+		}
+		decodeJSON(t, serveCAT(t, "POST", "/api/cat/sessions/"+id+"/responses",
+			`{"question": "`+next.Question+`", "correct": 1}`), http.StatusOK, &state)
+	}
+
+	if state.StopReason != "se_threshold" || state.SE > 0.8 || state.Administered >= numQuestions {
+		t.Errorf("expected an early stop by the SE threshold, got %q after %d items (SE %v)",
+			state.StopReason, state.Administered, state.SE)
+	}
+
+	var detailed CATSessionState
+	decodeJSON(t, serveCAT(t, "GET", "/api/cat/sessions/"+id+"?posterior=true", ""), http.StatusOK, &detailed)
+	if len(detailed.Posterior) != catGridPoints {
+		t.Fatalf("expected %d posterior points, got %d", catGridPoints, len(detailed.Posterior))
+	}
+	step := (catGridMax - catGridMin) / float64(catGridPoints-1)
+	integral := 0.0
+	for _, point := range detailed.Posterior {
+		integral += point.Density * step
+	}
+	if math.Abs(integral-1) > 1e-9 {
+		t.Errorf("expected the posterior density to integrate to 1, got %v", integral)
+	}
+}
+
+// TestCATSessionErrors - 不正なリクエストとセッション削除のテスト
+func TestCATSessionErrors(t *testing.T) {
+	setupRaschTestData(200, catTestDifficulties, 7)
+
+	if rr := serveCAT(t, "POST", "/api/cat/sessions", `{"selection": "random"}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for an unknown selection rule, got %d", rr.Code)
+	}
+	if rr := serveCAT(t, "POST", "/api/cat/sessions", `{"max_items": 11}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for max_items above the bank size, got %d", rr.Code)
+	}
+	for _, body := range []string{`{"prior_mean": 1e308}`, `{"prior_mean": -7}`, `{"prior_mean": 0.01, "prior_sd": 1e-300}`} {
+		if rr := serveCAT(t, "POST", "/api/cat/sessions", body); rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400 for an unusable prior, got %d", body, rr.Code)
+		}
+	}
+	if rr := serveCAT(t, "GET", "/api/cat/sessions/unknown/next", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for an unknown session, got %d", rr.Code)
+	}
+
+	var state CATSessionState
+	decodeJSON(t, serveCAT(t, "POST", "/api/cat/sessions", ""), http.StatusCreated, &state)
+	id := state.SessionID
+	responses := "/api/cat/sessions/" + id + "/responses"
+
+	if rr := serveCAT(t, "POST", responses, `{"question": "Q3", "correct": 2}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for correct = 2, got %d", rr.Code)
+	}
+	if rr := serveCAT(t, "POST", responses, `{"question": "Q11", "correct": 1}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for an unknown item, got %d", rr.Code)
+	}
+	if rr := serveCAT(t, "POST", responses, `{"question": "Q3", "correct": 1}`); rr.Code != http.StatusOK {
+		t.Errorf("expected status 200 for a valid response, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := serveCAT(t, "POST", responses, `{"question": "q3", "correct": 0}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for an item administered twice, got %d", rr.Code)
+	}
+
+	if rr := serveCAT(t, "DELETE", "/api/cat/sessions/"+id, ""); rr.Code != http.StatusNoContent {
+		t.Errorf("expected status 204 when deleting a session, got %d", rr.Code)
+	}
+	if rr := serveCAT(t, "GET", "/api/cat/sessions/"+id, ""); rr.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 after deleting the session, got %d", rr.Code)
+	}
+}
+
+// TestCATSessionNoAbilitySpread - 較正標本の能力が全員同じときに事前分布のSDを要求するかのテスト
+func TestCATSessionNoAbilitySpread(t *testing.T) {
+	// Every student answers five consecutive items correctly, so all abilities are equal
+	grades = []Grade{}
+	for i := 0; i < numQuestions; i++ {
+		row := make([]int, numQuestions)
+		for k := 0; k < 5; k++ {
+			row[(i+k)%numQuestions] = 1
+		}
+		grades = append(grades, Grade{StudentID: i + 1, Q1: row[0], Q2: row[1], Q3: row[2], Q4: row[3], Q5: row[4],
+			Q6: row[5], Q7: row[6], Q8: row[7], Q9: row[8], Q10: row[9], Total: 5})
+	}
+
+	if rr := serveCAT(t, "POST", "/api/cat/sessions", ""); rr.Code != http.StatusInternalServerError {
+		t.Errorf("expected status 500 without an ability spread, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var state CATSessionState
+	decodeJSON(t, serveCAT(t, "POST", "/api/cat/sessions", `{"prior_sd": 1}`), http.StatusCreated, &state)
+	if math.IsNaN(state.Theta) || state.PriorSD != 1 {
+		t.Errorf("expected a valid session with prior SD 1, got %+v", state)
+	}
+}
+
+// TestCATNonBinary - 0/1以外の得点で項目バンクの較正がエラーになるかのテスト
+func TestCATNonBinary(t *testing.T) {
+	setupRaschTestData(200, catTestDifficulties, 7)
+	grades[3].Q2 = 2
+
+	if rr := serveCAT(t, "GET", "/api/cat/items", ""); rr.Code != http.StatusInternalServerError {
+		t.Errorf("expected status 500 for a non-binary score, got %d", rr.Code)
+	}
+	if rr := serveCAT(t, "POST", "/api/cat/sessions", ""); rr.Code != http.StatusInternalServerError {
+		t.Errorf("expected status 500 when starting a session on non-binary data, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,497 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand"
+	"net/http"
+	"sort"
+)
+
+const (
+	// clusterProbabilityFloor keeps item probabilities away from 0 and 1 so log-likelihoods stay finite
+	clusterProbabilityFloor = 1e-6
+	// maxClusterIterations bounds both the k-means and the EM iterations of one start
+	maxClusterIterations = 500
+	// maxClusteringWork caps restarts × students × summed k so a request stays fast
+	maxClusteringWork = 2000000
+)
+
+// clusterFit is one fitted partition of the response matrix. Profiles hold the item correct
+// rates of each cluster and membership the (hard for k-means) class membership probabilities.
+type clusterFit struct {
+	k           int
+	profiles    [][]float64
+	weights     []float64
+	assignments []int
+	membership  [][]float64
+	logLik      float64
+	objective   float64
+	iterations  int
+	converged   bool
+}
+
+// ClusterModelFit represents the fit of one number of clusters
+type ClusterModelFit struct {
+	K              int      `json:"k"`
+	LogLikelihood  float64  `json:"log_likelihood"`
+	Parameters     int      `json:"parameters"`
+	BIC            float64  `json:"bic"`
+	WithinDistance *float64 `json:"within_distance,omitempty"`
+	Iterations     int      `json:"iterations"`
+	Converged      bool     `json:"converged"`
+}
+
+// ClusterProfile represents the item correct-rate profile of one cluster
+type ClusterProfile struct {
+	Cluster    int       `json:"cluster"`
+	Size       int       `json:"size"`
+	Proportion float64   `json:"proportion"`
+	MeanTotal  float64   `json:"mean_total"`
+	ItemRates  []float64 `json:"item_rates"`
+}
+
+// StudentCluster represents the cluster assignment of one student
+type StudentCluster struct {
+	StudentID     int       `json:"student_id"`
+	Total         int       `json:"total"`
+	Cluster       int       `json:"cluster"`
+	Probabilities []float64 `json:"probabilities,omitempty"`
+}
+
+// ClusteringResponse represents a clustering of students by response pattern
+type ClusteringResponse struct {
+	Method      string            `json:"method"`
+	Distance    string            `json:"distance,omitempty"`
+	N           int               `json:"n"`
+	Restarts    int               `json:"restarts"`
+	Seed        int64             `json:"seed"`
+	SelectedK   int               `json:"selected_k"`
+	Questions   []string          `json:"questions"`
+	Models      []ClusterModelFit `json:"models"`
+	Clusters    []ClusterProfile  `json:"clusters"`
+	Assignments []StudentCluster  `json:"assignments"`
+}
+
+// clusterDistance returns the Hamming (L1) or weighted Jaccard distance between a response
+// vector and a centroid of correct rates; both reduce to the usual binary distances when the
+// centroid is itself a response pattern
+func clusterDistance(distance string, x []int, c []float64) float64 {
+	if distance == "jaccard" {
+		var minSum, maxSum float64
+		for j, v := range x {
+			minSum += math.Min(float64(v), c[j])
+			maxSum += math.Max(float64(v), c[j])
+		}
+		if maxSum == 0 {
+			return 0
+		}
+		return 1 - minSum/maxSum
+	}
+	var d float64
+	for j, v := range x {
+		d += math.Abs(float64(v) - c[j])
+	}
+	return d
+}
+
+// bernoulliLogLikelihood returns log p(x | profile) for independent items
+func bernoulliLogLikelihood(x []int, profile []float64) float64 {
+	var ll float64
+	for j, v := range x {
+		p := math.Min(math.Max(profile[j], clusterProbabilityFloor), 1-clusterProbabilityFloor)
+		if v == 1 {
+			ll += math.Log(p)
+		} else {
+			ll += math.Log1p(-p)
+		}
+	}
+	return ll
+}
+
+// fitKMeans runs Lloyd's algorithm from a k-means++ start: students are assigned to the nearest
+// centroid under the chosen distance and centroids are the item correct rates of their members
+func fitKMeans(responses [][]int, k int, distance string, rng *rand.Rand) clusterFit {
+	n := len(responses)
+	centroids := make([][]float64, 0, k)
+	toCentroid := func(x []int) []float64 {
+		c := make([]float64, len(x))
+		for j, v := range x {
+			c[j] = float64(v)
+		}
+		return c
+	}
+	centroids = append(centroids, toCentroid(responses[rng.Intn(n)]))
+	nearest := make([]float64, n)
+	for len(centroids) < k {
+		var sum float64
+		for i, x := range responses {
+			nearest[i] = math.Inf(1)
+			for _, c := range centroids {
+				nearest[i] = math.Min(nearest[i], clusterDistance(distance, x, c))
+			}
+			nearest[i] *= nearest[i]
+			sum += nearest[i]
+		}
+		next := rng.Intn(n)
+		if sum > 0 {
+			u := rng.Float64() * sum
+			for i, d := range nearest {
+				u -= d
+				if u <= 0 {
+					next = i
+					break
+				}
+			}
+		}
+		centroids = append(centroids, toCentroid(responses[next]))
+	}
+
+	fit := clusterFit{k: k, assignments: make([]int, n)}
+	for i := range fit.assignments {
+		fit.assignments[i] = -1
+	}
+	for fit.iterations < maxClusterIterations {
+		fit.iterations++
+		changed := false
+		for i, x := range responses {
+			best, bestDistance := 0, math.Inf(1)
+			for c, centroid := range centroids {
+				if d := clusterDistance(distance, x, centroid); d < bestDistance {
+					best, bestDistance = c, d
+				}
+			}
+			if fit.assignments[i] != best {
+				fit.assignments[i] = best
+				changed = true
+			}
+		}
+		if !changed {
+			fit.converged = true
+			break
+		}
+
+		sizes := make([]int, k)
+		for c := range centroids {
+			centroids[c] = make([]float64, numQuestions)
+		}
+		for i, x := range responses {
+			c := fit.assignments[i]
+			sizes[c]++
+			for j, v := range x {
+				centroids[c][j] += float64(v)
+			}
+		}
+		for c := range centroids {
+			if sizes[c] == 0 {
+				continue
+			}
+			for j := range centroids[c] {
+				centroids[c][j] /= float64(sizes[c])
+			}
+		}
+		// Reseed each empty cluster with the student farthest from their centroid, once every
+		// centroid is a mean; a student reseeds at most one cluster
+		reseeded := make(map[int]bool)
+		for c := range centroids {
+			if sizes[c] > 0 {
+				continue
+			}
+			farthest, farthestDistance := -1, -1.0
+			for i, x := range responses {
+				if reseeded[i] {
+					continue
+				}
+				if d := clusterDistance(distance, x, centroids[fit.assignments[i]]); d > farthestDistance {
+					farthest, farthestDistance = i, d
+				}
+			}
+			if farthest >= 0 {
+				reseeded[farthest] = true
+				centroids[c] = toCentroid(responses[farthest])
+			}
+		}
+	}
+
+	fit.profiles = centroids
+	fit.weights = make([]float64, k)
+	fit.membership = make([][]float64, n)
+	for i, x := range responses {
+		c := fit.assignments[i]
+		fit.weights[c] += 1 / float64(n)
+		fit.objective += clusterDistance(distance, x, centroids[c])
+	}
+	// Classification likelihood of the partition under a Bernoulli mixture, so that BIC is
+	// comparable across k
+	for i, x := range responses {
+		c := fit.assignments[i]
+		fit.logLik += math.Log(fit.weights[c]) + bernoulliLogLikelihood(x, centroids[c])
+		fit.membership[i] = make([]float64, k)
+		fit.membership[i][c] = 1
+	}
+	return fit
+}
+
+// fitLatentClasses fits a latent class model (a mixture of independent Bernoulli items) by EM
+// from random starting profiles
+func fitLatentClasses(responses [][]int, k int, rng *rand.Rand) clusterFit {
+	n := len(responses)
+	fit := clusterFit{k: k, profiles: make([][]float64, k), weights: make([]float64, k), membership: make([][]float64, n)}
+	for c := range fit.profiles {
+		fit.weights[c] = 1 / float64(k)
+		fit.profiles[c] = make([]float64, numQuestions)
+		for j := range fit.profiles[c] {
+			fit.profiles[c][j] = 0.2 + 0.6*rng.Float64()
+		}
+	}
+	for i := range fit.membership {
+		fit.membership[i] = make([]float64, k)
+	}
+
+	logTerms := make([]float64, k)
+	previous := math.Inf(-1)
+	for fit.iterations < maxClusterIterations {
+		fit.iterations++
+
+		// E step: posterior class membership probabilities
+		fit.logLik = 0
+		for i, x := range responses {
+			for c := range logTerms {
+				logTerms[c] = math.Log(fit.weights[c]) + bernoulliLogLikelihood(x, fit.profiles[c])
+			}
+			normalizer := logSumExp(logTerms)
+			fit.logLik += normalizer
+			for c := range logTerms {
+				fit.membership[i][c] = math.Exp(logTerms[c] - normalizer)
+			}
+		}
+		if fit.logLik-previous < 1e-8*(1+math.Abs(fit.logLik)) {
+			fit.converged = true
+			break
+		}
+		previous = fit.logLik
+
+		// M step: class weights and item probabilities
+		for c := range fit.profiles {
+			var mass float64
+			rates := make([]float64, numQuestions)
+			for i, x := range responses {
+				r := fit.membership[i][c]
+				mass += r
+				for j, v := range x {
+					rates[j] += r * float64(v)
+				}
+			}
+			fit.weights[c] = math.Max(mass/float64(n), clusterProbabilityFloor)
+			if mass > 0 {
+				for j := range rates {
+					rates[j] /= mass
+				}
+				fit.profiles[c] = rates
+			}
+		}
+	}
+
+	fit.assignments = make([]int, n)
+	for i, probs := range fit.membership {
+		for c, p := range probs {
+			if p > probs[fit.assignments[i]] {
+				fit.assignments[i] = c
+			}
+		}
+	}
+	return fit
+}
+
+// fitClusters returns the best of several starts: the smallest within-cluster distance for
+// k-means and the largest log-likelihood for latent classes
+func fitClusters(responses [][]int, method, distance string, k, restarts int, rng *rand.Rand) clusterFit {
+	var best clusterFit
+	for start := 0; start < restarts; start++ {
+		var fit clusterFit
+		if method == "kmeans" {
+			fit = fitKMeans(responses, k, distance, rng)
+			if start == 0 || fit.objective < best.objective {
+				best = fit
+			}
+		} else {
+			fit = fitLatentClasses(responses, k, rng)
+			if start == 0 || fit.logLik > best.logLik {
+				best = fit
+			}
+		}
+	}
+	return best
+}
+
+// orderClusters relabels clusters from the highest to the lowest mean item correct rate, so
+// that cluster 1 is always the most proficient profile
+func orderClusters(fit clusterFit) clusterFit {
+	order := make([]int, fit.k)
+	means := make([]float64, fit.k)
+	for c := range order {
+		order[c] = c
+		for _, p := range fit.profiles[c] {
+			means[c] += p
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool { return means[order[a]] > means[order[b]] })
+	rank := make([]int, fit.k)
+	for newIndex, old := range order {
+		rank[old] = newIndex
+	}
+
+	ordered := fit
+	ordered.profiles = make([][]float64, fit.k)
+	ordered.weights = make([]float64, fit.k)
+	for old, newIndex := range rank {
+		ordered.profiles[newIndex] = fit.profiles[old]
+		ordered.weights[newIndex] = fit.weights[old]
+	}
+	ordered.assignments = make([]int, len(fit.assignments))
+	ordered.membership = make([][]float64, len(fit.membership))
+	for i := range fit.assignments {
+		ordered.assignments[i] = rank[fit.assignments[i]]
+		ordered.membership[i] = make([]float64, fit.k)
+		for old, p := range fit.membership[i] {
+			ordered.membership[i][rank[old]] = p
+		}
+	}
+	return ordered
+}
+
+// Handler: Get student clusters
+// Groups students by response pattern with k-means (Hamming or Jaccard distance) or latent
+// class analysis, choosing k by BIC when no k is given
+func getClusters(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	method := r.URL.Query().Get("method")
+	if method == "" {
+		method = "lca"
+	}
+	if method != "lca" && method != "kmeans" {
+		http.Error(w, "Invalid 'method' parameter (must be lca or kmeans)", http.StatusBadRequest)
+		return
+	}
+	distance := ""
+	if method == "kmeans" {
+		distance = r.URL.Query().Get("distance")
+		if distance == "" {
+			distance = "hamming"
+		}
+		if distance != "hamming" && distance != "jaccard" {
+			http.Error(w, "Invalid 'distance' parameter (must be hamming or jaccard)", http.StatusBadRequest)
+			return
+		}
+	}
+
+	kMin, err := intParam(r, "k_min", 1)
+	if err != nil || kMin < 1 || kMin > 10 {
+		http.Error(w, "Invalid 'k_min' parameter (must be between 1 and 10)", http.StatusBadRequest)
+		return
+	}
+	kMax, err := intParam(r, "k_max", 6)
+	if err != nil || kMax < kMin || kMax > 10 {
+		http.Error(w, "Invalid 'k_max' parameter (must be between k_min and 10)", http.StatusBadRequest)
+		return
+	}
+	if r.URL.Query().Get("k") != "" {
+		k, err := intParam(r, "k", 0)
+		if err != nil || k < 1 || k > 10 {
+			http.Error(w, "Invalid 'k' parameter (must be between 1 and 10)", http.StatusBadRequest)
+			return
+		}
+		kMin, kMax = k, k
+	}
+	restarts, err := intParam(r, "restarts", 10)
+	if err != nil || restarts < 1 || restarts > 100 {
+		http.Error(w, "Invalid 'restarts' parameter (must be between 1 and 100)", http.StatusBadRequest)
+		return
+	}
+	seed, err := intParam(r, "seed", 1)
+	if err != nil {
+		http.Error(w, "Invalid 'seed' parameter", http.StatusBadRequest)
+		return
+	}
+
+	if len(grades) == 0 {
+		http.Error(w, "No data available", http.StatusInternalServerError)
+		return
+	}
+	if kMax > len(grades) {
+		kMax = len(grades)
+		if kMin > kMax {
+			http.Error(w, "Not enough students for the requested number of clusters", http.StatusBadRequest)
+			return
+		}
+	}
+	if restarts*(kMin+kMax)*(kMax-kMin+1)/2 > maxClusteringWork/len(grades) {
+		http.Error(w, "Too much work: restarts × students × clusters must not exceed 2000000", http.StatusBadRequest)
+		return
+	}
+
+	responses := responseMatrix()
+	n := float64(len(grades))
+	rng := rand.New(rand.NewSource(int64(seed)))
+
+	response := ClusteringResponse{
+		Method:    method,
+		Distance:  distance,
+		N:         len(grades),
+		Restarts:  restarts,
+		Seed:      int64(seed),
+		Questions: questionLabels(),
+	}
+	var selected clusterFit
+	bestBIC := math.Inf(1)
+	for k := kMin; k <= kMax; k++ {
+		fit := fitClusters(responses, method, distance, k, restarts, rng)
+		parameters := k*numQuestions + k - 1
+		model := ClusterModelFit{
+			K:             k,
+			LogLikelihood: fit.logLik,
+			Parameters:    parameters,
+			BIC:           -2*fit.logLik + float64(parameters)*math.Log(n),
+			Iterations:    fit.iterations,
+			Converged:     fit.converged,
+		}
+		if method == "kmeans" {
+			model.WithinDistance = finiteOrNil(fit.objective)
+		}
+		response.Models = append(response.Models, model)
+		if model.BIC < bestBIC {
+			bestBIC = model.BIC
+			selected = fit
+		}
+	}
+
+	selected = orderClusters(selected)
+	response.SelectedK = selected.k
+	sizes := make([]int, selected.k)
+	totals := make([]float64, selected.k)
+	for i, g := range grades {
+		c := selected.assignments[i]
+		sizes[c]++
+		totals[c] += float64(g.Total)
+		student := StudentCluster{StudentID: g.StudentID, Total: g.Total, Cluster: c + 1}
+		if method == "lca" {
+			student.Probabilities = selected.membership[i]
+		}
+		response.Assignments = append(response.Assignments, student)
+	}
+	for c := 0; c < selected.k; c++ {
+		profile := ClusterProfile{
+			Cluster:    c + 1,
+			Size:       sizes[c],
+			Proportion: selected.weights[c],
+			ItemRates:  selected.profiles[c],
+		}
+		if sizes[c] > 0 {
+			profile.MeanTotal = totals[c] / float64(sizes[c])
+		}
+		response.Clusters = append(response.Clusters, profile)
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
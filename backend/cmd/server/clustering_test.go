@@ -0,0 +1,134 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// setupTwoProfileTestData - 前半の問題だけ得意な学生と後半の問題だけ得意な学生が10人ずつのテストデータ
+func setupTwoProfileTestData() {
+	grades = []Grade{}
+	for i := 0; i < 20; i++ {
+		values := make([]int, numQuestions)
+		for j := range values {
+			if (i < 10) == (j < 5) {
+				values[j] = 1
+			}
+		}
+		// 各学生で1問だけ反転させて完全には分離しないようにする
+		flip := i % numQuestions
+		values[flip] = 1 - values[flip]
+		g := Grade{StudentID: i + 1,
+			Q1: values[0], Q2: values[1], Q3: values[2], Q4: values[3], Q5: values[4],
+			Q6: values[5], Q7: values[6], Q8: values[7], Q9: values[8], Q10: values[9]}
+		for _, v := range values {
+			g.Total += v
+		}
+		grades = append(grades, g)
+	}
+}
+
+// checkTwoProfiles - 前半と後半の学生が別のクラスタに分かれていることを確認する
+func checkTwoProfiles(t *testing.T, result ClusteringResponse) {
+	if result.SelectedK != 2 || len(result.Clusters) != 2 {
+		t.Fatalf("expected 2 clusters, got k=%d", result.SelectedK)
+	}
+	first := result.Assignments[0].Cluster
+	for i, a := range result.Assignments {
+		if (i < 10) != (a.Cluster == first) {
+			t.Errorf("student %d assigned to cluster %d", a.StudentID, a.Cluster)
+		}
+	}
+	for _, c := range result.Clusters {
+		if c.Size != 10 || math.Abs(c.Proportion-0.5) > 0.01 {
+			t.Errorf("cluster %d: unexpected size %d proportion %.3f", c.Cluster, c.Size, c.Proportion)
+		}
+		if len(c.ItemRates) != numQuestions {
+			t.Fatalf("cluster %d: expected %d item rates", c.Cluster, numQuestions)
+		}
+		// 各クラスタの正答率は前半と後半で大きく異なる
+		if math.Abs(c.ItemRates[0]-c.ItemRates[9]) < 0.5 {
+			t.Errorf("cluster %d: profile %v does not separate the halves", c.Cluster, c.ItemRates)
+		}
+	}
+}
+
+// TestClustersLatentClassSelectsTwoClasses - 潜在クラス分析がBICで2クラスを選ぶことのテスト
+func TestClustersLatentClassSelectsTwoClasses(t *testing.T) {
+	setupTwoProfileTestData()
+
+	var result ClusteringResponse
+	serveJSON(t, getClusters, "GET", "/api/clusters?method=lca&k_max=4&seed=2", "", &result)
+
+	if len(result.Models) != 4 {
+		t.Fatalf("expected fits for k=1..4, got %d", len(result.Models))
+	}
+	checkTwoProfiles(t, result)
+	for _, a := range result.Assignments {
+		var sum float64
+		for _, p := range a.Probabilities {
+			sum += p
+		}
+		if len(a.Probabilities) != 2 || math.Abs(sum-1) > 1e-9 {
+			t.Errorf("student %d: invalid membership probabilities %v", a.StudentID, a.Probabilities)
+		}
+		if a.Probabilities[a.Cluster-1] < 0.9 {
+			t.Errorf("student %d: expected a confident assignment, got %v", a.StudentID, a.Probabilities)
+		}
+	}
+}
+
+// TestClustersKMeans - k-meansのテスト（ハミング距離とジャカード距離）
+func TestClustersKMeans(t *testing.T) {
+	setupTwoProfileTestData()
+
+	for _, distance := range []string{"hamming", "jaccard"} {
+		var result ClusteringResponse
+		serveJSON(t, getClusters, "GET", "/api/clusters?method=kmeans&distance="+distance+"&k=2", "", &result)
+		if result.Distance != distance || len(result.Models) != 1 || result.Models[0].WithinDistance == nil {
+			t.Fatalf("%s: unexpected response %+v", distance, result.Models)
+		}
+		checkTwoProfiles(t, result)
+		if result.Assignments[0].Probabilities != nil {
+			t.Errorf("%s: k-means should not report membership probabilities", distance)
+		}
+	}
+}
+
+// TestClustersOrderedByProficiency - クラスタ1が最も正答率の高いクラスタになることのテスト
+func TestClustersOrderedByProficiency(t *testing.T) {
+	setupTestData()
+
+	var result ClusteringResponse
+	serveJSON(t, getClusters, "GET", "/api/clusters?method=kmeans&k=2", "", &result)
+
+	if result.Clusters[0].MeanTotal < result.Clusters[1].MeanTotal {
+		t.Errorf("expected cluster 1 to be the most proficient, got %+v", result.Clusters)
+	}
+}
+
+// TestClustersInvalidParameters - 不正なパラメータのテスト
+func TestClustersInvalidParameters(t *testing.T) {
+	setupTestData()
+
+	for _, query := range []string{
+		"method=dbscan",
+		"method=kmeans&distance=cosine",
+		"k=0",
+		"k_min=3&k_max=2",
+		"restarts=0",
+		"k_min=5",
+	} {
+		req, err := http.NewRequest("GET", "/api/clusters?"+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(getClusters).ServeHTTP(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("query %q: expected 400, got %d", query, rr.Code)
+		}
+	}
+}
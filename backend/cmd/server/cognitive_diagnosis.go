@@ -0,0 +1,450 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+)
+
+// Cognitive diagnosis models (de la Torre 2009). Each student has a latent skill profile
+// α ∈ {0,1}^K; η_jc says whether profile c has what item j needs: all of its skills for DINA
+// ("and" gate) or any of them for DINO ("or" gate). Then P(X_j = 1 | c) = 1 − s_j when η_jc = 1
+// and g_j otherwise, with slip s_j and guess g_j.
+
+const (
+	// diagnosisParameterFloor keeps slip and guess inside (0, 1) during EM
+	diagnosisParameterFloor = 1e-4
+	// maxDiagnosisIterations bounds the EM iterations
+	maxDiagnosisIterations = 1000
+	// maxDiagnosisWork caps (draws + burn-in) × students × profiles for MCMC
+	maxDiagnosisWork = 50000000
+)
+
+// diagnosisModel holds the ideal responses η[c][j] of every skill profile
+type diagnosisModel struct {
+	skills   int
+	profiles [][]int
+	eta      [][]int
+}
+
+// newDiagnosisModel enumerates the 2^K profiles of the Q-matrix under the DINA or DINO gate
+func newDiagnosisModel(q QMatrix, model string) diagnosisModel {
+	m := diagnosisModel{skills: len(q.Skills)}
+	for c := 0; c < 1<<m.skills; c++ {
+		profile := make([]int, m.skills)
+		for k := range profile {
+			profile[k] = (c >> k) & 1
+		}
+		eta := make([]int, numQuestions)
+		for j, row := range q.Matrix {
+			required, mastered := 0, 0
+			for k, v := range row {
+				required += v
+				mastered += v * profile[k]
+			}
+			if (model == "dina" && mastered == required) || (model == "dino" && mastered > 0) {
+				eta[j] = 1
+			}
+		}
+		m.profiles = append(m.profiles, profile)
+		m.eta = append(m.eta, eta)
+	}
+	return m
+}
+
+// logLikelihoods returns log p(x | c) of one response vector for every profile
+func (m diagnosisModel) logLikelihoods(x []int, slip, guess []float64, out []float64) {
+	for c, eta := range m.eta {
+		var ll float64
+		for j, v := range x {
+			p := guess[j]
+			if eta[j] == 1 {
+				p = 1 - slip[j]
+			}
+			if v == 1 {
+				ll += math.Log(p)
+			} else {
+				ll += math.Log1p(-p)
+			}
+		}
+		out[c] = ll
+	}
+}
+
+// diagnosisFit holds slip, guess and profile weights with the posterior profile probabilities
+// of every student
+type diagnosisFit struct {
+	slip, guess            []float64
+	slipLower, slipUpper   []float64
+	guessLower, guessUpper []float64
+	weights                []float64
+	posterior              [][]float64
+	logLik                 float64
+	iterations             int
+	converged              bool
+}
+
+// fitDiagnosisEM estimates slip, guess and profile weights by marginal maximum likelihood
+func fitDiagnosisEM(m diagnosisModel, responses [][]int) diagnosisFit {
+	profiles := len(m.profiles)
+	fit := diagnosisFit{
+		slip:      make([]float64, numQuestions),
+		guess:     make([]float64, numQuestions),
+		weights:   make([]float64, profiles),
+		posterior: make([][]float64, len(responses)),
+	}
+	for j := range fit.slip {
+		fit.slip[j], fit.guess[j] = 0.2, 0.2
+	}
+	for c := range fit.weights {
+		fit.weights[c] = 1 / float64(profiles)
+	}
+	for i := range fit.posterior {
+		fit.posterior[i] = make([]float64, profiles)
+	}
+
+	logTerms := make([]float64, profiles)
+	previous := math.Inf(-1)
+	for fit.iterations < maxDiagnosisIterations {
+		fit.iterations++
+
+		fit.logLik = 0
+		for i, x := range responses {
+			m.logLikelihoods(x, fit.slip, fit.guess, logTerms)
+			for c := range logTerms {
+				logTerms[c] += math.Log(fit.weights[c])
+			}
+			normalizer := logSumExp(logTerms)
+			fit.logLik += normalizer
+			for c := range logTerms {
+				fit.posterior[i][c] = math.Exp(logTerms[c] - normalizer)
+			}
+		}
+		if fit.logLik-previous < 1e-8*(1+math.Abs(fit.logLik)) {
+			fit.converged = true
+			break
+		}
+		previous = fit.logLik
+
+		for c := range fit.weights {
+			var mass float64
+			for i := range responses {
+				mass += fit.posterior[i][c]
+			}
+			fit.weights[c] = math.Max(mass/float64(len(responses)), diagnosisParameterFloor)
+		}
+		// The floor adds mass, so the weights are renormalized to keep them a distribution
+		var total float64
+		for _, weight := range fit.weights {
+			total += weight
+		}
+		for c := range fit.weights {
+			fit.weights[c] /= total
+		}
+		for j := 0; j < numQuestions; j++ {
+			// Expected numbers of students (I) and of correct answers (R) with η = 0 and η = 1
+			var i0, r0, i1, r1 float64
+			for i, x := range responses {
+				for c, p := range fit.posterior[i] {
+					if m.eta[c][j] == 1 {
+						i1 += p
+						r1 += p * float64(x[j])
+					} else {
+						i0 += p
+						r0 += p * float64(x[j])
+					}
+				}
+			}
+			if i0 > 0 {
+				fit.guess[j] = clampProbability(r0/i0, diagnosisParameterFloor)
+			}
+			if i1 > 0 {
+				fit.slip[j] = clampProbability(1-r1/i1, diagnosisParameterFloor)
+			}
+		}
+	}
+	return fit
+}
+
+// clampProbability keeps p inside [floor, 1 − floor]
+func clampProbability(p, floor float64) float64 {
+	return math.Min(math.Max(p, floor), 1-floor)
+}
+
+// sampleTruncatedBeta draws from Beta(a, b) restricted to (lo, hi) by rejection, keeping current
+// when no draw lands in the interval
+func sampleTruncatedBeta(rng *rand.Rand, a, b, lo, hi, current float64) float64 {
+	for attempt := 0; attempt < 100; attempt++ {
+		if v := sampleBeta(rng, a, b); v > lo && v < hi {
+			return v
+		}
+	}
+	return current
+}
+
+// fitDiagnosisGibbs samples profiles, profile weights (Dirichlet(1) prior) and item parameters
+// (Beta(1, 1) priors with g_j < 1 − s_j, which also fixes the labels) by Gibbs sampling
+func fitDiagnosisGibbs(m diagnosisModel, responses [][]int, draws, burnIn int, level float64, seed int64) diagnosisFit {
+	rng := rand.New(rand.NewSource(seed))
+	profiles := len(m.profiles)
+	n := len(responses)
+
+	slip := make([]float64, numQuestions)
+	guess := make([]float64, numQuestions)
+	for j := range slip {
+		slip[j], guess[j] = 0.2, 0.2
+	}
+	weights := make([]float64, profiles)
+	for c := range weights {
+		weights[c] = 1 / float64(profiles)
+	}
+	assignment := make([]int, n)
+
+	fit := diagnosisFit{weights: make([]float64, profiles), posterior: make([][]float64, n)}
+	for i := range fit.posterior {
+		fit.posterior[i] = make([]float64, profiles)
+	}
+	slipDraws := make([][]float64, numQuestions)
+	guessDraws := make([][]float64, numQuestions)
+
+	logTerms := make([]float64, profiles)
+	for iter := 0; iter < burnIn+draws; iter++ {
+		counts := make([]float64, profiles)
+		for i, x := range responses {
+			m.logLikelihoods(x, slip, guess, logTerms)
+			for c := range logTerms {
+				logTerms[c] += math.Log(weights[c])
+			}
+			normalizer := logSumExp(logTerms)
+			u := rng.Float64()
+			assignment[i] = profiles - 1
+			for c := range logTerms {
+				u -= math.Exp(logTerms[c] - normalizer)
+				if u <= 0 {
+					assignment[i] = c
+					break
+				}
+			}
+			counts[assignment[i]]++
+		}
+
+		var sum float64
+		for c := range weights {
+			weights[c] = sampleGamma(rng, 1+counts[c])
+			sum += weights[c]
+		}
+		for c := range weights {
+			weights[c] = math.Max(weights[c]/sum, 1e-300)
+		}
+
+		for j := 0; j < numQuestions; j++ {
+			var i0, r0, i1, r1 float64
+			for i, x := range responses {
+				if m.eta[assignment[i]][j] == 1 {
+					i1++
+					r1 += float64(x[j])
+				} else {
+					i0++
+					r0 += float64(x[j])
+				}
+			}
+			guess[j] = sampleTruncatedBeta(rng, 1+r0, 1+i0-r0, 0, 1-slip[j], guess[j])
+			slip[j] = 1 - sampleTruncatedBeta(rng, 1+r1, 1+i1-r1, guess[j], 1, 1-slip[j])
+		}
+
+		if iter < burnIn {
+			continue
+		}
+		for i, c := range assignment {
+			fit.posterior[i][c] += 1 / float64(draws)
+		}
+		for c := range weights {
+			fit.weights[c] += weights[c] / float64(draws)
+		}
+		for j := range slip {
+			slipDraws[j] = append(slipDraws[j], slip[j])
+			guessDraws[j] = append(guessDraws[j], guess[j])
+		}
+	}
+
+	lowerP := (1 - level) / 2
+	fit.slip = make([]float64, numQuestions)
+	fit.guess = make([]float64, numQuestions)
+	fit.slipLower = make([]float64, numQuestions)
+	fit.slipUpper = make([]float64, numQuestions)
+	fit.guessLower = make([]float64, numQuestions)
+	fit.guessUpper = make([]float64, numQuestions)
+	for j := 0; j < numQuestions; j++ {
+		fit.slip[j], fit.slipLower[j], fit.slipUpper[j] = posteriorSummary(slipDraws[j], lowerP)
+		fit.guess[j], fit.guessLower[j], fit.guessUpper[j] = posteriorSummary(guessDraws[j], lowerP)
+	}
+	fit.iterations = burnIn + draws
+	return fit
+}
+
+// DiagnosisItem represents the slip and guess parameters of one item
+type DiagnosisItem struct {
+	Question   string   `json:"question"`
+	Skills     []string `json:"skills"`
+	Slip       float64  `json:"slip"`
+	SlipLower  *float64 `json:"slip_lower,omitempty"`
+	SlipUpper  *float64 `json:"slip_upper,omitempty"`
+	Guess      float64  `json:"guess"`
+	GuessLower *float64 `json:"guess_lower,omitempty"`
+	GuessUpper *float64 `json:"guess_upper,omitempty"`
+}
+
+// SkillPrevalence represents the share of students who have mastered a skill
+type SkillPrevalence struct {
+	Skill      string  `json:"skill"`
+	Prevalence float64 `json:"prevalence"`
+}
+
+// StudentMastery represents a student's posterior skill mastery probabilities
+type StudentMastery struct {
+	StudentID          int       `json:"student_id"`
+	Total              int       `json:"total"`
+	Mastery            []float64 `json:"mastery"`
+	Profile            string    `json:"profile"`
+	ProfileProbability float64   `json:"profile_probability"`
+}
+
+// CognitiveDiagnosisResponse represents a fitted DINA or DINO model
+type CognitiveDiagnosisResponse struct {
+	Model         string            `json:"model"`
+	Method        string            `json:"method"`
+	N             int               `json:"n"`
+	Skills        []string          `json:"skills"`
+	LogLikelihood *float64          `json:"log_likelihood,omitempty"`
+	Iterations    int               `json:"iterations"`
+	Converged     *bool             `json:"converged,omitempty"`
+	Level         *float64          `json:"level,omitempty"`
+	Items         []DiagnosisItem   `json:"items"`
+	Prevalence    []SkillPrevalence `json:"prevalence"`
+	Students      []StudentMastery  `json:"students"`
+	Warnings      []string          `json:"warnings"`
+}
+
+// Handler: Get cognitive diagnosis
+// Fits a DINA or DINO model with the uploaded Q-matrix by EM (default) or Gibbs sampling and
+// returns item slip/guess parameters and each student's skill mastery probabilities
+func getCognitiveDiagnosis(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	model := r.URL.Query().Get("model")
+	if model == "" {
+		model = "dina"
+	}
+	if model != "dina" && model != "dino" {
+		http.Error(w, "Invalid 'model' parameter (must be dina or dino)", http.StatusBadRequest)
+		return
+	}
+	method := r.URL.Query().Get("method")
+	if method == "" {
+		method = "em"
+	}
+	if method != "em" && method != "mcmc" {
+		http.Error(w, "Invalid 'method' parameter (must be em or mcmc)", http.StatusBadRequest)
+		return
+	}
+	draws, err := intParam(r, "draws", 2000)
+	if err != nil || draws < 100 {
+		http.Error(w, "Invalid 'draws' parameter (must be at least 100)", http.StatusBadRequest)
+		return
+	}
+	burnIn, err := intParam(r, "burn_in", 500)
+	if err != nil || burnIn < 0 {
+		http.Error(w, "Invalid 'burn_in' parameter (must be non-negative)", http.StatusBadRequest)
+		return
+	}
+	level, err := floatParam(r, "level", 0.95)
+	if err != nil || level <= 0 || level >= 1 {
+		http.Error(w, "Invalid 'level' parameter (must be between 0 and 1)", http.StatusBadRequest)
+		return
+	}
+	seed, err := intParam(r, "seed", 1)
+	if err != nil {
+		http.Error(w, "Invalid 'seed' parameter", http.StatusBadRequest)
+		return
+	}
+
+	q := currentQMatrix()
+	if q == nil {
+		http.Error(w, "No Q-matrix has been uploaded (PUT /api/q-matrix first)", http.StatusBadRequest)
+		return
+	}
+	if len(grades) == 0 {
+		http.Error(w, "No data available", http.StatusInternalServerError)
+		return
+	}
+	dm := newDiagnosisModel(*q, model)
+	if method == "mcmc" && draws+burnIn > maxDiagnosisWork/(len(grades)*len(dm.profiles)) {
+		http.Error(w, "Too much work: (draws + burn_in) × students × skill profiles must not exceed 50000000", http.StatusBadRequest)
+		return
+	}
+
+	responses := responseMatrix()
+	var fit diagnosisFit
+	response := CognitiveDiagnosisResponse{
+		Model:    model,
+		Method:   method,
+		N:        len(grades),
+		Skills:   q.Skills,
+		Warnings: []string{},
+	}
+	if method == "em" {
+		fit = fitDiagnosisEM(dm, responses)
+		response.LogLikelihood = finiteOrNil(fit.logLik)
+		response.Converged = &fit.converged
+	} else {
+		fit = fitDiagnosisGibbs(dm, responses, draws, burnIn, level, int64(seed))
+		response.Level = &level
+	}
+	response.Iterations = fit.iterations
+
+	labels := questionLabels()
+	for j := 0; j < numQuestions; j++ {
+		item := DiagnosisItem{Question: labels[j], Skills: q.skillsOf(j), Slip: fit.slip[j], Guess: fit.guess[j]}
+		if method == "mcmc" {
+			item.SlipLower, item.SlipUpper = &fit.slipLower[j], &fit.slipUpper[j]
+			item.GuessLower, item.GuessUpper = &fit.guessLower[j], &fit.guessUpper[j]
+		}
+		if fit.guess[j] >= 1-fit.slip[j] {
+			response.Warnings = append(response.Warnings, fmt.Sprintf(
+				"%s: guess %.3f is not below 1 − slip %.3f, so masters are not more likely to answer correctly", labels[j], fit.guess[j], 1-fit.slip[j]))
+		}
+		response.Items = append(response.Items, item)
+	}
+
+	for k, skill := range q.Skills {
+		var prevalence float64
+		for c, weight := range fit.weights {
+			prevalence += weight * float64(dm.profiles[c][k])
+		}
+		response.Prevalence = append(response.Prevalence, SkillPrevalence{Skill: skill, Prevalence: prevalence})
+	}
+
+	for i, g := range grades {
+		student := StudentMastery{StudentID: g.StudentID, Total: g.Total, Mastery: make([]float64, dm.skills)}
+		best := 0
+		for c, p := range fit.posterior[i] {
+			for k := range student.Mastery {
+				student.Mastery[k] += p * float64(dm.profiles[c][k])
+			}
+			if p > fit.posterior[i][best] {
+				best = c
+			}
+		}
+		profile := make([]byte, dm.skills)
+		for k, v := range dm.profiles[best] {
+			profile[k] = byte('0' + v)
+		}
+		student.Profile = string(profile)
+		student.ProfileProbability = fit.posterior[i][best]
+		response.Students = append(response.Students, student)
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
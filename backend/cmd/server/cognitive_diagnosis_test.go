@@ -0,0 +1,165 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// twoSkillQMatrix - Q1-Q3は技能A、Q4-Q6は技能B、Q7-Q10は両方を必要とするQ行列
+func twoSkillQMatrix() QMatrix {
+	return QMatrix{
+		Skills: []string{"A", "B"},
+		Matrix: [][]int{{1, 0}, {1, 0}, {1, 0}, {0, 1}, {0, 1}, {0, 1}, {1, 1}, {1, 1}, {1, 1}, {1, 1}},
+	}
+}
+
+// setupDINATestData - 4つの習得パターンの学生をDINAモデル（slip 0.1, guess 0.2）から生成する
+func setupDINATestData() [][]int {
+	q := twoSkillQMatrix()
+	qMatrixStore.current = &q
+	model := newDiagnosisModel(q, "dina")
+
+	rng := rand.New(rand.NewSource(42))
+	grades = []Grade{}
+	var profiles [][]int
+	for i := 0; i < 400; i++ {
+		c := i % 4
+		values := make([]int, numQuestions)
+		g := Grade{StudentID: i + 1}
+		for j := range values {
+			p := 0.2
+			if model.eta[c][j] == 1 {
+				p = 0.9
+			}
+			if rng.Float64() < p {
+				values[j] = 1
+				g.Total++
+			}
+		}
+		g.Q1, g.Q2, g.Q3, g.Q4, g.Q5 = values[0], values[1], values[2], values[3], values[4]
+		g.Q6, g.Q7, g.Q8, g.Q9, g.Q10 = values[5], values[6], values[7], values[8], values[9]
+		grades = append(grades, g)
+		profiles = append(profiles, model.profiles[c])
+	}
+	return profiles
+}
+
+// checkDINARecovery - slip・guessと習得パターンが真の値に近いことを確認する
+func checkDINARecovery(t *testing.T, result CognitiveDiagnosisResponse, truth [][]int) {
+	for _, item := range result.Items {
+		if math.Abs(item.Slip-0.1) > 0.08 || math.Abs(item.Guess-0.2) > 0.08 {
+			t.Errorf("%s: slip %.3f guess %.3f far from 0.1 and 0.2", item.Question, item.Slip, item.Guess)
+		}
+	}
+	for _, p := range result.Prevalence {
+		if math.Abs(p.Prevalence-0.5) > 0.1 {
+			t.Errorf("skill %s: prevalence %.3f far from 0.5", p.Skill, p.Prevalence)
+		}
+	}
+	correct := 0
+	for i, s := range result.Students {
+		if len(s.Mastery) != 2 {
+			t.Fatalf("student %d: expected 2 mastery probabilities", s.StudentID)
+		}
+		if (s.Mastery[0] > 0.5) == (truth[i][0] == 1) && (s.Mastery[1] > 0.5) == (truth[i][1] == 1) {
+			correct++
+		}
+	}
+	if rate := float64(correct) / float64(len(truth)); rate < 0.8 {
+		t.Errorf("expected at least 80%% of profiles recovered, got %.3f", rate)
+	}
+}
+
+// TestCognitiveDiagnosisDINAEM - EMによるDINAモデルの推定のテスト
+func TestCognitiveDiagnosisDINAEM(t *testing.T) {
+	truth := setupDINATestData()
+
+	var result CognitiveDiagnosisResponse
+	serveJSON(t, getCognitiveDiagnosis, "GET", "/api/cognitive-diagnosis?model=dina&method=em", "", &result)
+
+	if result.Converged == nil || !*result.Converged || result.LogLikelihood == nil {
+		t.Errorf("expected a converged EM fit, got %+v", result)
+	}
+	if result.Items[6].Skills[1] != "B" {
+		t.Errorf("unexpected skills for Q7: %v", result.Items[6].Skills)
+	}
+	checkDINARecovery(t, result, truth)
+}
+
+// TestDiagnosisEMWeightsSumToOne - 下限で切り上げた習得パターンの重みが正規化されるかのテスト
+func TestDiagnosisEMWeightsSumToOne(t *testing.T) {
+	// Every student answers every item, so all but one profile fall to the floor
+	responses := make([][]int, 50)
+	for i := range responses {
+		responses[i] = []int{1, 1, 1, 1, 1, 1, 1, 1, 1, 1}
+	}
+	fit := fitDiagnosisEM(newDiagnosisModel(twoSkillQMatrix(), "dina"), responses)
+
+	total := 0.0
+	for _, weight := range fit.weights {
+		total += weight
+	}
+	if math.Abs(total-1) > 1e-12 {
+		t.Errorf("expected profile weights to sum to 1, got %v (%v)", total, fit.weights)
+	}
+}
+
+// TestCognitiveDiagnosisDINAMCMC - ギブスサンプリングによるDINAモデルの推定のテスト
+func TestCognitiveDiagnosisDINAMCMC(t *testing.T) {
+	truth := setupDINATestData()
+
+	var result CognitiveDiagnosisResponse
+	serveJSON(t, getCognitiveDiagnosis, "GET", "/api/cognitive-diagnosis?model=dina&method=mcmc&draws=300&burn_in=100&seed=3", "", &result)
+
+	for _, item := range result.Items {
+		if item.SlipLower == nil || *item.SlipLower > item.Slip || *item.GuessUpper < item.Guess {
+			t.Errorf("%s: missing or inconsistent credible intervals", item.Question)
+		}
+	}
+	checkDINARecovery(t, result, truth)
+}
+
+// TestCognitiveDiagnosisDINO - DINOモデルでは技能を1つ持つだけで理想反応が1になることのテスト
+func TestCognitiveDiagnosisDINO(t *testing.T) {
+	model := newDiagnosisModel(twoSkillQMatrix(), "dino")
+	// プロファイル1は技能Aのみ習得
+	if model.eta[1][6] != 1 || model.eta[1][3] != 0 || model.eta[0][6] != 0 {
+		t.Errorf("unexpected DINO ideal responses %v", model.eta)
+	}
+
+	setupDINATestData()
+	var result CognitiveDiagnosisResponse
+	serveJSON(t, getCognitiveDiagnosis, "GET", "/api/cognitive-diagnosis?model=dino", "", &result)
+	if result.Model != "dino" || len(result.Students) != len(grades) {
+		t.Errorf("unexpected response %+v", result.Model)
+	}
+}
+
+// TestCognitiveDiagnosisRequiresQMatrix - Q行列がない場合と不正なパラメータのテスト
+func TestCognitiveDiagnosisRequiresQMatrix(t *testing.T) {
+	setupTestData()
+	qMatrixStore.current = nil
+
+	for _, query := range []string{"", "model=dina"} {
+		req, _ := http.NewRequest("GET", "/api/cognitive-diagnosis?"+query, nil)
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(getCognitiveDiagnosis).ServeHTTP(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("query %q without Q-matrix: expected 400, got %d", query, rr.Code)
+		}
+	}
+
+	q := twoSkillQMatrix()
+	qMatrixStore.current = &q
+	for _, query := range []string{"model=gdina", "method=vb", "draws=10", "level=2"} {
+		req, _ := http.NewRequest("GET", "/api/cognitive-diagnosis?"+query, nil)
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(getCognitiveDiagnosis).ServeHTTP(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("query %q: expected 400, got %d", query, rr.Code)
+		}
+	}
+}
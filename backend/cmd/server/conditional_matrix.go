@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// BetaPrior represents the parameters of a Beta(α, β) prior on a correct rate
+type BetaPrior struct {
+	Alpha float64 `json:"alpha"`
+	Beta  float64 `json:"beta"`
+}
+
+// ConditionalProbabilityMatrixResponse represents P(Qj=1 | Qi=1) and P(Qj=1 | Qi=0) for all
+// ordered pairs. Row i is the given question and column j the target question.
+type ConditionalProbabilityMatrixResponse struct {
+	QuestionLabels          []string     `json:"question_labels"`
+	GivenCorrect            [][]*float64 `json:"given_correct"`
+	GivenIncorrect          [][]*float64 `json:"given_incorrect"`
+	GivenCorrectCounts      []int        `json:"given_correct_counts"`
+	GivenIncorrectCounts    []int        `json:"given_incorrect_counts"`
+	BothCorrectCounts       [][]int      `json:"both_correct_counts"`
+	OnlyTargetCorrectCounts [][]int      `json:"only_target_correct_counts"`
+	Smoothing               *BetaPrior   `json:"smoothing"`
+	SmoothedGivenCorrect    [][]float64  `json:"smoothed_given_correct,omitempty"`
+	SmoothedGivenIncorrect  [][]float64  `json:"smoothed_given_incorrect,omitempty"`
+}
+
+// Handler: Get conditional probability matrix
+// Calculates P(Qj=1 | Qi=1) and P(Qj=1 | Qi=0) for every ordered question pair, optionally
+// smoothed with a Beta(alpha, beta) prior (posterior mean)
+func getConditionalProbabilityMatrix(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// Smoothing is enabled by smoothing=true or, unless explicitly disabled, by giving alpha/beta
+	query := r.URL.Query()
+	smoothingStr := query.Get("smoothing")
+	if smoothingStr != "" && smoothingStr != "true" && smoothingStr != "false" {
+		http.Error(w, "Invalid 'smoothing' parameter (must be true or false)", http.StatusBadRequest)
+		return
+	}
+	smoothing := smoothingStr == "true" ||
+		(smoothingStr == "" && (query.Get("alpha") != "" || query.Get("beta") != ""))
+
+	alpha, err := floatParam(r, "alpha", 1.0)
+	if err != nil || alpha <= 0 {
+		http.Error(w, "Invalid 'alpha' parameter (must be positive)", http.StatusBadRequest)
+		return
+	}
+	beta, err := floatParam(r, "beta", 1.0)
+	if err != nil || beta <= 0 {
+		http.Error(w, "Invalid 'beta' parameter (must be positive)", http.StatusBadRequest)
+		return
+	}
+
+	if len(grades) == 0 {
+		http.Error(w, "No data available", http.StatusInternalServerError)
+		return
+	}
+
+	givenCorrectCounts := make([]int, numQuestions)
+	givenIncorrectCounts := make([]int, numQuestions)
+	bothCorrect := make([][]int, numQuestions)
+	onlyTargetCorrect := make([][]int, numQuestions)
+	for i := 0; i < numQuestions; i++ {
+		bothCorrect[i] = make([]int, numQuestions)
+		onlyTargetCorrect[i] = make([]int, numQuestions)
+	}
+
+	for _, grade := range grades {
+		for i := 1; i <= numQuestions; i++ {
+			givenValue := getQuestionValue(grade, i)
+			if givenValue == 1 {
+				givenCorrectCounts[i-1]++
+			} else {
+				givenIncorrectCounts[i-1]++
+			}
+			for j := 1; j <= numQuestions; j++ {
+				if getQuestionValue(grade, j) != 1 {
+					continue
+				}
+				if givenValue == 1 {
+					bothCorrect[i-1][j-1]++
+				} else {
+					onlyTargetCorrect[i-1][j-1]++
+				}
+			}
+		}
+	}
+
+	response := ConditionalProbabilityMatrixResponse{
+		QuestionLabels:          questionLabels(),
+		GivenCorrect:            make([][]*float64, numQuestions),
+		GivenIncorrect:          make([][]*float64, numQuestions),
+		GivenCorrectCounts:      givenCorrectCounts,
+		GivenIncorrectCounts:    givenIncorrectCounts,
+		BothCorrectCounts:       bothCorrect,
+		OnlyTargetCorrectCounts: onlyTargetCorrect,
+	}
+	if smoothing {
+		response.Smoothing = &BetaPrior{Alpha: alpha, Beta: beta}
+		response.SmoothedGivenCorrect = make([][]float64, numQuestions)
+		response.SmoothedGivenIncorrect = make([][]float64, numQuestions)
+	}
+
+	for i := 0; i < numQuestions; i++ {
+		response.GivenCorrect[i] = make([]*float64, numQuestions)
+		response.GivenIncorrect[i] = make([]*float64, numQuestions)
+		for j := 0; j < numQuestions; j++ {
+			// Raw estimates are undefined (null) when nobody is in the conditioning group
+			if givenCorrectCounts[i] > 0 {
+				response.GivenCorrect[i][j] = finiteOrNil(float64(bothCorrect[i][j]) / float64(givenCorrectCounts[i]))
+			}
+			if givenIncorrectCounts[i] > 0 {
+				response.GivenIncorrect[i][j] = finiteOrNil(float64(onlyTargetCorrect[i][j]) / float64(givenIncorrectCounts[i]))
+			}
+		}
+
+		if smoothing {
+			response.SmoothedGivenCorrect[i] = make([]float64, numQuestions)
+			response.SmoothedGivenIncorrect[i] = make([]float64, numQuestions)
+			for j := 0; j < numQuestions; j++ {
+				response.SmoothedGivenCorrect[i][j] = (float64(bothCorrect[i][j]) + alpha) /
+					(float64(givenCorrectCounts[i]) + alpha + beta)
+				response.SmoothedGivenIncorrect[i][j] = (float64(onlyTargetCorrect[i][j]) + alpha) /
+					(float64(givenIncorrectCounts[i]) + alpha + beta)
+			}
+		}
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestConditionalProbabilityMatrix - 条件付き確率マトリックスの正常系テスト
+func TestConditionalProbabilityMatrix(t *testing.T) {
+	setupTestData()
+	// Student1: 全問正解, Student2: Q1-Q7正解, Student3: 全問不正解
+	// P(Q8=1 | Q1=1) = 1/2, P(Q8=1 | Q1=0) = 0/1
+
+	var result ConditionalProbabilityMatrixResponse
+	serveJSON(t, getConditionalProbabilityMatrix, "GET", "/api/conditional-probability-matrix", "", &result)
+
+	if len(result.GivenCorrect) != 10 || len(result.GivenIncorrect) != 10 {
+		t.Fatalf("expected 10x10 matrices")
+	}
+	if p := result.GivenCorrect[0][7]; p == nil || *p != 0.5 {
+		t.Errorf("expected P(Q8=1 | Q1=1) = 0.5, got %v", p)
+	}
+	if p := result.GivenIncorrect[0][7]; p == nil || *p != 0.0 {
+		t.Errorf("expected P(Q8=1 | Q1=0) = 0.0, got %v", p)
+	}
+	if result.GivenCorrectCounts[0] != 2 || result.BothCorrectCounts[0][7] != 1 {
+		t.Errorf("unexpected counts: given=%d both=%d", result.GivenCorrectCounts[0], result.BothCorrectCounts[0][7])
+	}
+
+	// 単一ペアのエンドポイントと一致することを確認
+	if p := result.GivenCorrect[0][1]; p == nil || *p != 1.0 {
+		t.Errorf("expected P(Q2=1 | Q1=1) = 1.0 as in /api/conditional-probability, got %v", p)
+	}
+
+	// スムージングを指定しない場合は返さない
+	if result.Smoothing != nil || result.SmoothedGivenCorrect != nil {
+		t.Error("expected no smoothed estimates without smoothing")
+	}
+}
+
+// TestConditionalProbabilityMatrixSmoothing - ベータ事前分布によるスムージングのテスト
+func TestConditionalProbabilityMatrixSmoothing(t *testing.T) {
+	setupTestData()
+	// Q8を条件とすると Q8=1 は Student1 のみ
+	// 平滑化: P(Q9=1 | Q8=1) = (1+1)/(1+1+1) = 2/3
+	// P(Q1=1 | Q8=0) = (1+1)/(2+1+1) = 0.5
+
+	var result ConditionalProbabilityMatrixResponse
+	serveJSON(t, getConditionalProbabilityMatrix, "GET", "/api/conditional-probability-matrix?smoothing=true", "", &result)
+
+	if result.Smoothing == nil || result.Smoothing.Alpha != 1 || result.Smoothing.Beta != 1 {
+		t.Fatalf("expected Beta(1,1) smoothing, got %+v", result.Smoothing)
+	}
+	if v := result.SmoothedGivenCorrect[7][8]; math.Abs(v-2.0/3.0) > 1e-9 {
+		t.Errorf("expected smoothed P(Q9=1 | Q8=1) = 0.667, got %.4f", v)
+	}
+	if v := result.SmoothedGivenIncorrect[7][0]; math.Abs(v-0.5) > 1e-9 {
+		t.Errorf("expected smoothed P(Q1=1 | Q8=0) = 0.5, got %.4f", v)
+	}
+}
+
+// TestConditionalProbabilityMatrixUndefined - 条件側の該当者が0人の場合はnullになるテスト
+func TestConditionalProbabilityMatrixUndefined(t *testing.T) {
+	// 全員Q1=1なので P(・| Q1=0) は定義できない
+	grades = []Grade{
+		{StudentID: 1, Q1: 1, Q2: 1, Total: 2},
+		{StudentID: 2, Q1: 1, Q2: 0, Total: 1},
+	}
+
+	var result ConditionalProbabilityMatrixResponse
+	serveJSON(t, getConditionalProbabilityMatrix, "GET", "/api/conditional-probability-matrix", "", &result)
+
+	if result.GivenIncorrect[0][1] != nil {
+		t.Errorf("expected null for P(Q2=1 | Q1=0), got %v", *result.GivenIncorrect[0][1])
+	}
+}
+
+// TestConditionalProbabilityMatrixInvalidParams - 無効なパラメータのテスト
+func TestConditionalProbabilityMatrixInvalidParams(t *testing.T) {
+	setupTestData()
+
+	testCases := []struct {
+		name  string
+		query string
+	}{
+		{"invalid_smoothing", "smoothing=yes"},
+		{"zero_alpha", "alpha=0"},
+		{"negative_beta", "beta=-1"},
+		{"non_numeric_alpha", "alpha=abc"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "/api/conditional-probability-matrix?"+tc.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(getConditionalProbabilityMatrix)
+			handler.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != http.StatusBadRequest {
+				t.Errorf("handler returned wrong status code: got %v want %v",
+					status, http.StatusBadRequest)
+			}
+		})
+	}
+}
+
+// TestConditionalProbabilityMatrixEmptyData - データが空の場合のテスト
+func TestConditionalProbabilityMatrixEmptyData(t *testing.T) {
+	grades = []Grade{}
+
+	req, err := http.NewRequest("GET", "/api/conditional-probability-matrix", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(getConditionalProbabilityMatrix)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusInternalServerError {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusInternalServerError)
+	}
+}
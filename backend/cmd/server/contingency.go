@@ -0,0 +1,331 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// ChiSquareTest represents Pearson's chi-square test of independence
+type ChiSquareTest struct {
+	Statistic      float64  `json:"statistic"`
+	DF             int      `json:"df"`
+	PValue         float64  `json:"p_value"`
+	YatesStatistic *float64 `json:"yates_statistic"`
+	YatesPValue    *float64 `json:"yates_p_value"`
+}
+
+// EffectSizeEstimate represents a ratio estimate with its confidence interval
+type EffectSizeEstimate struct {
+	Estimate         float64 `json:"estimate"`
+	Lower            float64 `json:"lower"`
+	Upper            float64 `json:"upper"`
+	HaldaneCorrected bool    `json:"haldane_corrected"`
+}
+
+// ContingencyTableResponse represents the cross tabulation of two variables
+type ContingencyTableResponse struct {
+	RowVariable        string              `json:"row_variable"`
+	ColumnVariable     string              `json:"column_variable"`
+	RowLevels          []int               `json:"row_levels"`
+	ColumnLevels       []int               `json:"column_levels"`
+	Counts             [][]int             `json:"counts"`
+	RowTotals          []int               `json:"row_totals"`
+	ColumnTotals       []int               `json:"column_totals"`
+	N                  int                 `json:"n"`
+	JointProbabilities [][]float64         `json:"joint_probabilities"`
+	RowMarginals       []float64           `json:"row_marginals"`
+	ColumnMarginals    []float64           `json:"column_marginals"`
+	ColumnGivenRow     [][]*float64        `json:"column_given_row"`
+	RowGivenColumn     [][]*float64        `json:"row_given_column"`
+	ExpectedCounts     [][]float64         `json:"expected_counts"`
+	ChiSquare          *ChiSquareTest      `json:"chi_square"`
+	FisherExactPValue  *float64            `json:"fisher_exact_p_value"`
+	ConfidenceLevel    float64             `json:"confidence_level"`
+	OddsRatio          *EffectSizeEstimate `json:"odds_ratio"`
+	RelativeRisk       *EffectSizeEstimate `json:"relative_risk"`
+}
+
+// parseTableVariable parses "q1".."q10" or "total" and returns the question number (0 for Total)
+func parseTableVariable(s string) (int, bool) {
+	if s == "total" {
+		return 0, true
+	}
+	q, err := parseQuestion(s)
+	if err != nil {
+		return 0, false
+	}
+	return q, true
+}
+
+// variableValue returns the value of question q for a grade, or its Total when q is 0
+func variableValue(g Grade, q int) int {
+	if q == 0 {
+		return g.Total
+	}
+	return getQuestionValue(g, q)
+}
+
+// variableLevels returns the sorted distinct observed values of a variable.
+// Items always include 0 and 1 so that a 2×2 table is produced even when nobody missed an item.
+func variableLevels(q int) []int {
+	seen := make(map[int]bool)
+	if q != 0 {
+		seen[0] = true
+		seen[1] = true
+	}
+	for _, g := range grades {
+		seen[variableValue(g, q)] = true
+	}
+	levels := make([]int, 0, len(seen))
+	for level := range seen {
+		levels = append(levels, level)
+	}
+	sort.Ints(levels)
+	return levels
+}
+
+// calculateChiSquare runs Pearson's chi-square test on the non-empty rows and columns of a table.
+// Yates' continuity correction is added when the reduced table is 2×2.
+func calculateChiSquare(counts [][]int, rowTotals, colTotals []int, n int) *ChiSquareTest {
+	var rows, cols []int
+	for i, total := range rowTotals {
+		if total > 0 {
+			rows = append(rows, i)
+		}
+	}
+	for j, total := range colTotals {
+		if total > 0 {
+			cols = append(cols, j)
+		}
+	}
+
+	df := (len(rows) - 1) * (len(cols) - 1)
+	if df <= 0 {
+		return nil
+	}
+
+	var statistic, yates float64
+	for _, i := range rows {
+		for _, j := range cols {
+			expected := float64(rowTotals[i]*colTotals[j]) / float64(n)
+			diff := float64(counts[i][j]) - expected
+			statistic += diff * diff / expected
+			corrected := math.Max(math.Abs(diff)-0.5, 0)
+			yates += corrected * corrected / expected
+		}
+	}
+
+	test := &ChiSquareTest{
+		Statistic: statistic,
+		DF:        df,
+		PValue:    chiSquarePValue(statistic, df),
+	}
+	if df == 1 {
+		yatesPValue := chiSquarePValue(yates, 1)
+		test.YatesStatistic = &yates
+		test.YatesPValue = &yatesPValue
+	}
+	return test
+}
+
+// calculateFisherExact returns the two-sided Fisher exact p-value of a 2×2 table
+// [[a, b], [c, d]], summing all tables with the same margins that are no more likely.
+func calculateFisherExact(a, b, c, d int) float64 {
+	row1 := a + b
+	col1 := a + c
+	n := a + b + c + d
+
+	logProb := func(x int) float64 {
+		return logChoose(col1, x) + logChoose(n-col1, row1-x) - logChoose(n, row1)
+	}
+
+	observed := logProb(a)
+	minX := row1 - (n - col1)
+	if minX < 0 {
+		minX = 0
+	}
+	maxX := row1
+	if col1 < maxX {
+		maxX = col1
+	}
+
+	pValue := 0.0
+	for x := minX; x <= maxX; x++ {
+		lp := logProb(x)
+		// Small log-scale tolerance so that tables tied with the observed one are included
+		if lp <= observed+1e-7 {
+			pValue += math.Exp(lp)
+		}
+	}
+	return math.Min(pValue, 1.0)
+}
+
+// calculateRatioEstimates returns the odds ratio and relative risk of B=1 for A=1 versus A=0
+// with Woolf and Katz log-scale confidence intervals. The Haldane–Anscombe correction (+0.5 to
+// every cell) is applied when any cell is empty.
+func calculateRatioEstimates(n11, n10, n01, n00 int, confidence float64) (*EffectSizeEstimate, *EffectSizeEstimate) {
+	a, b, c, d := float64(n11), float64(n10), float64(n01), float64(n00)
+	corrected := n11 == 0 || n10 == 0 || n01 == 0 || n00 == 0
+	if corrected {
+		a, b, c, d = a+0.5, b+0.5, c+0.5, d+0.5
+	}
+
+	z := normalQuantile(1 - (1-confidence)/2)
+
+	oddsRatio := (a * d) / (b * c)
+	seLogOR := math.Sqrt(1/a + 1/b + 1/c + 1/d)
+
+	relativeRisk := (a / (a + b)) / (c / (c + d))
+	seLogRR := math.Sqrt(1/a - 1/(a+b) + 1/c - 1/(c+d))
+
+	return &EffectSizeEstimate{
+		Estimate:         oddsRatio,
+		Lower:            math.Exp(math.Log(oddsRatio) - z*seLogOR),
+		Upper:            math.Exp(math.Log(oddsRatio) + z*seLogOR),
+		HaldaneCorrected: corrected,
+	}, &EffectSizeEstimate{
+		Estimate:         relativeRisk,
+		Lower:            math.Exp(math.Log(relativeRisk) - z*seLogRR),
+		Upper:            math.Exp(math.Log(relativeRisk) + z*seLogRR),
+		HaldaneCorrected: corrected,
+	}
+}
+
+// Handler: Get contingency table
+// Cross-tabulates two variables (q1-q10 or total) with marginals, conditional probabilities
+// and tests of association
+func getContingencyTable(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	aStr := r.URL.Query().Get("a")
+	bStr := r.URL.Query().Get("b")
+
+	if aStr == "" {
+		http.Error(w, "Missing 'a' parameter", http.StatusBadRequest)
+		return
+	}
+	if bStr == "" {
+		http.Error(w, "Missing 'b' parameter", http.StatusBadRequest)
+		return
+	}
+
+	a, ok := parseTableVariable(aStr)
+	if !ok {
+		http.Error(w, "Invalid 'a' parameter (must be q1-q10 or total)", http.StatusBadRequest)
+		return
+	}
+	b, ok := parseTableVariable(bStr)
+	if !ok {
+		http.Error(w, "Invalid 'b' parameter (must be q1-q10 or total)", http.StatusBadRequest)
+		return
+	}
+
+	confidence := 0.95
+	if confidenceStr := r.URL.Query().Get("confidence"); confidenceStr != "" {
+		var err error
+		confidence, err = strconv.ParseFloat(confidenceStr, 64)
+		if err != nil || confidence <= 0 || confidence >= 1 {
+			http.Error(w, "Invalid 'confidence' parameter (must be between 0 and 1)", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if len(grades) == 0 {
+		http.Error(w, "No data available", http.StatusInternalServerError)
+		return
+	}
+
+	rowLevels := variableLevels(a)
+	colLevels := variableLevels(b)
+	rowIndex := make(map[int]int)
+	for i, level := range rowLevels {
+		rowIndex[level] = i
+	}
+	colIndex := make(map[int]int)
+	for j, level := range colLevels {
+		colIndex[level] = j
+	}
+
+	// Count observations
+	counts := make([][]int, len(rowLevels))
+	for i := range counts {
+		counts[i] = make([]int, len(colLevels))
+	}
+	for _, g := range grades {
+		counts[rowIndex[variableValue(g, a)]][colIndex[variableValue(g, b)]]++
+	}
+
+	// Marginals
+	n := len(grades)
+	rowTotals := make([]int, len(rowLevels))
+	colTotals := make([]int, len(colLevels))
+	for i := range rowLevels {
+		for j := range colLevels {
+			rowTotals[i] += counts[i][j]
+			colTotals[j] += counts[i][j]
+		}
+	}
+
+	rowMarginals := make([]float64, len(rowLevels))
+	for i, total := range rowTotals {
+		rowMarginals[i] = float64(total) / float64(n)
+	}
+	colMarginals := make([]float64, len(colLevels))
+	for j, total := range colTotals {
+		colMarginals[j] = float64(total) / float64(n)
+	}
+
+	// Joint, conditional and expected tables
+	joint := make([][]float64, len(rowLevels))
+	columnGivenRow := make([][]*float64, len(rowLevels))
+	rowGivenColumn := make([][]*float64, len(rowLevels))
+	expected := make([][]float64, len(rowLevels))
+	for i := range rowLevels {
+		joint[i] = make([]float64, len(colLevels))
+		columnGivenRow[i] = make([]*float64, len(colLevels))
+		rowGivenColumn[i] = make([]*float64, len(colLevels))
+		expected[i] = make([]float64, len(colLevels))
+		for j := range colLevels {
+			joint[i][j] = float64(counts[i][j]) / float64(n)
+			if rowTotals[i] > 0 {
+				columnGivenRow[i][j] = finiteOrNil(float64(counts[i][j]) / float64(rowTotals[i]))
+			}
+			if colTotals[j] > 0 {
+				rowGivenColumn[i][j] = finiteOrNil(float64(counts[i][j]) / float64(colTotals[j]))
+			}
+			expected[i][j] = float64(rowTotals[i]*colTotals[j]) / float64(n)
+		}
+	}
+
+	response := ContingencyTableResponse{
+		RowVariable:        aStr,
+		ColumnVariable:     bStr,
+		RowLevels:          rowLevels,
+		ColumnLevels:       colLevels,
+		Counts:             counts,
+		RowTotals:          rowTotals,
+		ColumnTotals:       colTotals,
+		N:                  n,
+		JointProbabilities: joint,
+		RowMarginals:       rowMarginals,
+		ColumnMarginals:    colMarginals,
+		ColumnGivenRow:     columnGivenRow,
+		RowGivenColumn:     rowGivenColumn,
+		ExpectedCounts:     expected,
+		ChiSquare:          calculateChiSquare(counts, rowTotals, colTotals, n),
+		ConfidenceLevel:    confidence,
+	}
+
+	// Exact test and ratio measures are only defined for 2×2 tables (levels 0 and 1)
+	if len(rowLevels) == 2 && len(colLevels) == 2 {
+		fisher := calculateFisherExact(counts[1][1], counts[1][0], counts[0][1], counts[0][0])
+		response.FisherExactPValue = &fisher
+		response.OddsRatio, response.RelativeRisk = calculateRatioEstimates(
+			counts[1][1], counts[1][0], counts[0][1], counts[0][0], confidence)
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// setupContingencyTestData - 2×2分割表 [[3,1],[1,3]] を作るテストデータ
+func setupContingencyTestData() {
+	grades = []Grade{
+		{StudentID: 1, Q1: 1, Q2: 1, Total: 2},
+		{StudentID: 2, Q1: 1, Q2: 1, Total: 2},
+		{StudentID: 3, Q1: 1, Q2: 1, Total: 2},
+		{StudentID: 4, Q1: 1, Q2: 0, Total: 1},
+		{StudentID: 5, Q1: 0, Q2: 1, Total: 1},
+		{StudentID: 6, Q1: 0, Q2: 0, Total: 0},
+		{StudentID: 7, Q1: 0, Q2: 0, Total: 0},
+		{StudentID: 8, Q1: 0, Q2: 0, Total: 0},
+	}
+}
+
+// TestContingencyTable - 2×2分割表の正常系テスト
+func TestContingencyTable(t *testing.T) {
+	setupContingencyTestData()
+
+	var result ContingencyTableResponse
+	serveJSON(t, getContingencyTable, "GET", "/api/contingency?a=q1&b=q2", "", &result)
+
+	// 度数の確認（行=Q1, 列=Q2, 水準は0,1の順）
+	if result.Counts[1][1] != 3 || result.Counts[0][0] != 3 || result.Counts[1][0] != 1 || result.Counts[0][1] != 1 {
+		t.Errorf("unexpected counts: %v", result.Counts)
+	}
+	if result.N != 8 {
+		t.Errorf("expected n=8, got %d", result.N)
+	}
+
+	// P(Q2=1 | Q1=1) = 3/4
+	if p := result.ColumnGivenRow[1][1]; p == nil || *p != 0.75 {
+		t.Errorf("expected P(Q2=1 | Q1=1) = 0.75, got %v", p)
+	}
+
+	// カイ二乗: 期待度数は全て2 → χ² = 4 × 1/2 = 2.0, Yates補正後 = 4 × 0.25/2 = 0.5
+	if result.ChiSquare == nil {
+		t.Fatal("chi_square is missing")
+	}
+	if math.Abs(result.ChiSquare.Statistic-2.0) > 1e-9 {
+		t.Errorf("expected chi-square 2.0, got %.4f", result.ChiSquare.Statistic)
+	}
+	if result.ChiSquare.YatesStatistic == nil || math.Abs(*result.ChiSquare.YatesStatistic-0.5) > 1e-9 {
+		t.Errorf("expected Yates chi-square 0.5, got %v", result.ChiSquare.YatesStatistic)
+	}
+
+	// Fisherの正確確率検定: (1+16+16+1)/70
+	if result.FisherExactPValue == nil || math.Abs(*result.FisherExactPValue-34.0/70.0) > 1e-9 {
+		t.Errorf("expected Fisher p-value %.4f, got %v", 34.0/70.0, result.FisherExactPValue)
+	}
+
+	// オッズ比 = 3*3/(1*1) = 9, 相対リスク = (3/4)/(1/4) = 3
+	if result.OddsRatio == nil || math.Abs(result.OddsRatio.Estimate-9.0) > 1e-9 {
+		t.Errorf("expected odds ratio 9.0, got %v", result.OddsRatio)
+	}
+	if result.OddsRatio.Lower >= 9.0 || result.OddsRatio.Upper <= 9.0 {
+		t.Errorf("odds ratio CI [%.4f, %.4f] does not contain the estimate", result.OddsRatio.Lower, result.OddsRatio.Upper)
+	}
+	if result.RelativeRisk == nil || math.Abs(result.RelativeRisk.Estimate-3.0) > 1e-9 {
+		t.Errorf("expected relative risk 3.0, got %v", result.RelativeRisk)
+	}
+}
+
+// TestContingencyTablePolytomous - r×c分割表（Totalとのクロス集計）のテスト
+func TestContingencyTablePolytomous(t *testing.T) {
+	setupTestData()
+
+	var result ContingencyTableResponse
+	serveJSON(t, getContingencyTable, "GET", "/api/contingency?a=q1&b=total", "", &result)
+
+	if len(result.ColumnLevels) != 3 {
+		t.Errorf("expected 3 total levels, got %v", result.ColumnLevels)
+	}
+	if result.ChiSquare == nil || result.ChiSquare.DF != 2 {
+		t.Errorf("expected chi-square with 2 df, got %+v", result.ChiSquare)
+	}
+	// 2×2以外ではFisher検定とオッズ比は返さない
+	if result.FisherExactPValue != nil || result.OddsRatio != nil || result.RelativeRisk != nil {
+		t.Error("expected Fisher test and ratio estimates to be null for r×c tables")
+	}
+}
+
+// TestContingencyTableZeroCell - 度数0のセルがある場合のHaldane補正テスト
+func TestContingencyTableZeroCell(t *testing.T) {
+	setupTestData()
+	// Q1とQ8: (1,1)×1, (1,0)×1, (0,0)×1, (0,1)×0
+
+	var result ContingencyTableResponse
+	serveJSON(t, getContingencyTable, "GET", "/api/contingency?a=q1&b=q8", "", &result)
+
+	if result.OddsRatio == nil || !result.OddsRatio.HaldaneCorrected {
+		t.Errorf("expected Haldane-corrected odds ratio, got %+v", result.OddsRatio)
+	}
+	// (1.5*1.5)/(1.5*0.5) = 3
+	if math.Abs(result.OddsRatio.Estimate-3.0) > 1e-9 {
+		t.Errorf("expected corrected odds ratio 3.0, got %.4f", result.OddsRatio.Estimate)
+	}
+}
+
+// TestContingencyTableInvalidParams - 無効なパラメータのテスト
+func TestContingencyTableInvalidParams(t *testing.T) {
+	setupTestData()
+
+	testCases := []struct {
+		name  string
+		query string
+	}{
+		{"missing_a", "b=q2"},
+		{"missing_b", "a=q1"},
+		{"invalid_a", "a=q11&b=q2"},
+		{"invalid_b", "a=q1&b=foo"},
+		{"invalid_confidence", "a=q1&b=q2&confidence=95"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "/api/contingency?"+tc.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(getContingencyTable)
+			handler.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != http.StatusBadRequest {
+				t.Errorf("handler returned wrong status code: got %v want %v",
+					status, http.StatusBadRequest)
+			}
+		})
+	}
+}
+
+// TestContingencyTableEmptyData - データが空の場合のテスト
+func TestContingencyTableEmptyData(t *testing.T) {
+	grades = []Grade{}
+
+	req, err := http.NewRequest("GET", "/api/contingency?a=q1&b=q2", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(getContingencyTable)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusInternalServerError {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusInternalServerError)
+	}
+}
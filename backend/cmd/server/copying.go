@@ -0,0 +1,310 @@
+package main
+
+import (
+	"container/heap"
+	"encoding/json"
+	"math"
+	"math/bits"
+	"net/http"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// Answer-copying detection. With binary scoring the only trace copying leaves is a pair of
+// students sharing incorrect answers, and a plain count of them says little: two weak students
+// miss the same hard items anyway. Each shared incorrect answer is therefore weighted by its
+// surprisal -log P(both wrong on k), with P(both wrong on k) = (1 - P_ik)(1 - P_jk) under
+// independence given each student's Rasch ability, and the weighted count is referred to its
+// exact null distribution.
+
+// sharedIncorrectTail returns P(T ≥ t) where T = Σ_k s_k·w_k, s_k ~ Bernoulli(both[k]) independent
+// and w_k = -log both[k], and t is the value of T for the observed shared mask. The distribution
+// is enumerated over all 2^J subsets; prob and score are scratch buffers of that length.
+func sharedIncorrectTail(both []float64, shared uint16, prob, score []float64) float64 {
+	observed := 0.0
+	for k, p := range both {
+		if shared&(1<<uint(k)) != 0 {
+			observed -= math.Log(p)
+		}
+	}
+
+	prob[0], score[0] = 1, 0
+	for k, p := range both {
+		size := 1 << uint(k)
+		weight := -math.Log(p)
+		for m := 0; m < size; m++ {
+			prob[m|size] = prob[m] * p
+			score[m|size] = score[m] + weight
+			prob[m] *= 1 - p
+		}
+	}
+	tail := 0.0
+	for m := 0; m < 1<<uint(len(both)); m++ {
+		if score[m] >= observed-1e-9 {
+			tail += prob[m]
+		}
+	}
+	return math.Min(tail, 1)
+}
+
+// correctProbabilities returns P(correct) for every student and item under the Rasch JML fit.
+// Responses of students or items with extreme scores are treated as certain.
+func correctProbabilities(responses [][]int, fit raschJML) [][]float64 {
+	probabilities := make([][]float64, len(responses))
+	for i, row := range responses {
+		probabilities[i] = make([]float64, numQuestions)
+		for k, x := range row {
+			if fit.personEstimable[i] && fit.itemEstimable[k] {
+				probabilities[i][k] = sigmoid(fit.ability[i] - fit.difficulty[k])
+			} else {
+				probabilities[i][k] = float64(x)
+			}
+		}
+	}
+	return probabilities
+}
+
+// copyingCandidate is a flagged pair before its report entry is built
+type copyingCandidate struct {
+	i, j    int
+	shared  uint16
+	matches int
+	pValue  float64
+}
+
+// before orders flagged pairs by p-value, then by more shared incorrect answers, then by
+// student IDs
+func (c copyingCandidate) before(other copyingCandidate) bool {
+	if c.pValue != other.pValue {
+		return c.pValue < other.pValue
+	}
+	if c.matches != other.matches {
+		return c.matches > other.matches
+	}
+	if grades[c.i].StudentID != grades[other.i].StudentID {
+		return grades[c.i].StudentID < grades[other.i].StudentID
+	}
+	return grades[c.j].StudentID < grades[other.j].StudentID
+}
+
+// copyingHeap keeps the most suspicious pairs seen so far with the least suspicious on top,
+// so a worker holds at most 'limit' of them however many pairs are flagged
+type copyingHeap []copyingCandidate
+
+func (h copyingHeap) Len() int            { return len(h) }
+func (h copyingHeap) Less(a, b int) bool  { return h[b].before(h[a]) }
+func (h copyingHeap) Swap(a, b int)       { h[a], h[b] = h[b], h[a] }
+func (h *copyingHeap) Push(x interface{}) { *h = append(*h, x.(copyingCandidate)) }
+func (h *copyingHeap) Pop() interface{} {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
+
+// offer adds a flagged pair, dropping the least suspicious one when more than limit are held
+func (h *copyingHeap) offer(c copyingCandidate, limit int) {
+	if h.Len() < limit {
+		heap.Push(h, c)
+	} else if c.before((*h)[0]) {
+		(*h)[0] = c
+		heap.Fix(h, 0)
+	}
+}
+
+// CopyingPair represents a pair of students whose shared incorrect answers are improbable
+type CopyingPair struct {
+	StudentA           int      `json:"student_a"`
+	StudentB           int      `json:"student_b"`
+	IdenticalIncorrect int      `json:"identical_incorrect"`
+	ExpectedIncorrect  float64  `json:"expected_identical_incorrect"`
+	SharedIncorrect    []string `json:"shared_incorrect"`
+	Identical          int      `json:"identical_responses"`
+	Omega              *float64 `json:"omega"`
+	PValue             float64  `json:"p_value"`
+}
+
+// AnswerCopyingResponse represents the pairs flagged by the answer-copying analysis
+type AnswerCopyingResponse struct {
+	N          int           `json:"n"`
+	Pairs      int           `json:"pairs"`
+	Examined   int           `json:"examined"`
+	MinMatches int           `json:"min_matches"`
+	Alpha      float64       `json:"alpha"`
+	Correction string        `json:"correction"`
+	Threshold  float64       `json:"threshold"`
+	Flagged    int           `json:"flagged"`
+	Flags      []CopyingPair `json:"flagged_pairs"`
+}
+
+// Handler: Get answer-copying analysis
+// Compares the identical incorrect answers of every pair of students with what their Rasch
+// abilities predict and returns the pairs whose tail probability is significant
+func getAnswerCopying(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	alpha, err := floatParam(r, "alpha", 0.05)
+	if err != nil || alpha <= 0 || alpha >= 1 {
+		http.Error(w, "Invalid 'alpha' parameter (must be between 0 and 1)", http.StatusBadRequest)
+		return
+	}
+	correction := r.URL.Query().Get("correction")
+	if correction == "" {
+		correction = "bonferroni"
+	}
+	if correction != "bonferroni" && correction != "none" {
+		http.Error(w, "Invalid 'correction' parameter (must be bonferroni or none)", http.StatusBadRequest)
+		return
+	}
+	minMatches, err := intParam(r, "min_matches", 3)
+	if err != nil || minMatches < 1 || minMatches > numQuestions {
+		http.Error(w, "Invalid 'min_matches' parameter (must be between 1 and 10)", http.StatusBadRequest)
+		return
+	}
+	limit, err := intParam(r, "limit", 100)
+	if err != nil || limit < 1 {
+		http.Error(w, "Invalid 'limit' parameter (must be positive)", http.StatusBadRequest)
+		return
+	}
+
+	if len(grades) == 0 {
+		http.Error(w, "No data available", http.StatusInternalServerError)
+		return
+	}
+
+	responses, err := binaryResponseMatrix()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	n := len(responses)
+	probabilities := correctProbabilities(responses, fitRaschJML(responses))
+	// Bit k of incorrect[i] / correct[i] is set when student i missed / answered item k
+	incorrect := make([]uint16, n)
+	correct := make([]uint16, n)
+	for i, row := range responses {
+		for k, x := range row {
+			if x == 1 {
+				correct[i] |= 1 << uint(k)
+			} else {
+				incorrect[i] |= 1 << uint(k)
+			}
+		}
+	}
+
+	pairs := n * (n - 1) / 2
+	threshold := alpha
+	if correction == "bonferroni" && pairs > 0 {
+		threshold = alpha / float64(pairs)
+	}
+	labels := questionLabels()
+
+	// Rows are handed out to workers; each keeps its own top-limit pairs and counts
+	workers := runtime.NumCPU()
+	if workers > 8 {
+		workers = 8
+	}
+	next := make(chan int, n)
+	for i := 0; i < n; i++ {
+		next <- i
+	}
+	close(next)
+	kept := make([]copyingHeap, workers)
+	flagged := make([]int, workers)
+	examined := make([]int, workers)
+	var wg sync.WaitGroup
+	for worker := 0; worker < workers; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			both := make([]float64, numQuestions)
+			prob := make([]float64, 1<<numQuestions)
+			score := make([]float64, 1<<numQuestions)
+			for i := range next {
+				for j := i + 1; j < n; j++ {
+					shared := incorrect[i] & incorrect[j]
+					matches := bits.OnesCount16(shared)
+					if matches < minMatches {
+						continue
+					}
+					examined[worker]++
+					for k := range both {
+						both[k] = (1 - probabilities[i][k]) * (1 - probabilities[j][k])
+					}
+					// Observing exactly the shared set already implies T ≥ t, so P(T ≥ t) is at
+					// least its probability; most pairs are ruled out without the enumeration
+					bound := 1.0
+					for k := range both {
+						if shared&(1<<uint(k)) != 0 {
+							bound *= both[k]
+						}
+					}
+					if bound > threshold {
+						continue
+					}
+					pValue := sharedIncorrectTail(both, shared, prob, score)
+					if pValue > threshold {
+						continue
+					}
+					flagged[worker]++
+					kept[worker].offer(copyingCandidate{i: i, j: j, shared: shared, matches: matches, pValue: pValue}, limit)
+				}
+			}
+		}(worker)
+	}
+	wg.Wait()
+
+	response := AnswerCopyingResponse{
+		N:          n,
+		Pairs:      pairs,
+		MinMatches: minMatches,
+		Alpha:      alpha,
+		Correction: correction,
+		Threshold:  threshold,
+		Flags:      []CopyingPair{},
+	}
+	var top []copyingCandidate
+	for worker := range kept {
+		response.Examined += examined[worker]
+		response.Flagged += flagged[worker]
+		top = append(top, kept[worker]...)
+	}
+	sort.Slice(top, func(a, b int) bool { return top[a].before(top[b]) })
+	if len(top) > limit {
+		top = top[:limit]
+	}
+
+	// Report entries are built only for the returned pairs
+	for _, c := range top {
+		pi, pj := probabilities[c.i], probabilities[c.j]
+		// ω-style index: standardized count of identical responses of either kind
+		identical := bits.OnesCount16(c.shared | correct[c.i]&correct[c.j])
+		var expected, agreeMean, agreeVar float64
+		for k := 0; k < numQuestions; k++ {
+			both := (1 - pi[k]) * (1 - pj[k])
+			expected += both
+			p := pi[k]*pj[k] + both
+			agreeMean += p
+			agreeVar += p * (1 - p)
+		}
+		pair := CopyingPair{
+			StudentA:           grades[c.i].StudentID,
+			StudentB:           grades[c.j].StudentID,
+			IdenticalIncorrect: c.matches,
+			ExpectedIncorrect:  expected,
+			SharedIncorrect:    []string{},
+			Identical:          identical,
+			Omega:              finiteOrNil((float64(identical) - agreeMean) / math.Sqrt(agreeVar)),
+			PValue:             c.pValue,
+		}
+		for k := 0; k < numQuestions; k++ {
+			if c.shared&(1<<uint(k)) != 0 {
+				pair.SharedIncorrect = append(pair.SharedIncorrect, labels[k])
+			}
+		}
+		response.Flags = append(response.Flags, pair)
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// setupCopyingTestData - Raschモデルから生成した学生に、誤答パターンが同一の2人を加える
+// 学生1と学生2: Q1-Q5を誤答（易しい問題ばかりの同じ誤答）
+func setupCopyingTestData(n int) {
+	rng := rand.New(rand.NewSource(23))
+	grades = []Grade{}
+	for i := 1; i <= n; i++ {
+		row := make([]int, numQuestions)
+		if i <= 2 {
+			row[5], row[6], row[7], row[8], row[9] = 1, 1, 1, 1, 1
+		} else {
+			theta := rng.NormFloat64()
+			for k := range row {
+				b := -2 + 4*float64(k)/float64(numQuestions-1)
+				if rng.Float64() < sigmoid(theta-b) {
+					row[k] = 1
+				}
+			}
+		}
+		g := Grade{StudentID: i, Q1: row[0], Q2: row[1], Q3: row[2], Q4: row[3], Q5: row[4],
+			Q6: row[5], Q7: row[6], Q8: row[7], Q9: row[8], Q10: row[9]}
+		g.Total = g.Q1 + g.Q2 + g.Q3 + g.Q4 + g.Q5 + g.Q6 + g.Q7 + g.Q8 + g.Q9 + g.Q10
+		grades = append(grades, g)
+	}
+}
+
+// TestSharedIncorrectTail - 重み付き同一誤答数の上側確率のテスト
+func TestSharedIncorrectTail(t *testing.T) {
+	prob := make([]float64, 1<<numQuestions)
+	score := make([]float64, 1<<numQuestions)
+
+	// With equal probabilities the weighted count is a scaled binomial count
+	half := make([]float64, numQuestions)
+	for k := range half {
+		half[k] = 0.5
+	}
+	if got := sharedIncorrectTail(half, 1<<numQuestions-1, prob, score); math.Abs(got-1.0/1024) > 1e-12 {
+		t.Errorf("expected P(all 10 shared) = 1/1024, got %v", got)
+	}
+	if got := sharedIncorrectTail(half, 1<<(numQuestions-1)-1, prob, score); math.Abs(got-11.0/1024) > 1e-12 {
+		t.Errorf("expected P(at least 9 shared) = 11/1024, got %v", got)
+	}
+
+	// Sharing the rarer miss is as extreme as sharing both, but not sharing only the common one
+	pair := []float64{0.2, 0.7}
+	if got := sharedIncorrectTail(pair, 1, prob, score); math.Abs(got-0.2) > 1e-12 {
+		t.Errorf("expected tail 0.2, got %v", got)
+	}
+	if got := sharedIncorrectTail(pair, 2, prob, score); math.Abs(got-(1-0.8*0.3)) > 1e-12 {
+		t.Errorf("expected tail 0.76, got %v", got)
+	}
+	if got := sharedIncorrectTail(pair, 0, prob, score); got != 1 {
+		t.Errorf("expected tail 1 with nothing shared, got %v", got)
+	}
+}
+
+// TestAnswerCopyingFlagsPair - 同一の誤答パターンを持つペアが検出されるかのテスト
+func TestAnswerCopyingFlagsPair(t *testing.T) {
+	setupCopyingTestData(100)
+
+	var result AnswerCopyingResponse
+	serveJSON(t, getAnswerCopying, "GET", "/api/answer-copying", "", &result)
+
+	if result.N != 100 || result.Pairs != 100*99/2 {
+		t.Fatalf("expected 100 students and 4950 pairs, got %d and %d", result.N, result.Pairs)
+	}
+	if math.Abs(result.Threshold-0.05/4950) > 1e-15 {
+		t.Errorf("expected Bonferroni threshold 0.05/4950, got %v", result.Threshold)
+	}
+	if result.Flagged == 0 {
+		t.Fatal("expected the copying pair to be flagged")
+	}
+	top := result.Flags[0]
+	if top.StudentA != 1 || top.StudentB != 2 {
+		t.Fatalf("expected students 1 and 2 as the most suspicious pair, got %d and %d", top.StudentA, top.StudentB)
+	}
+	if top.IdenticalIncorrect != 5 || len(top.SharedIncorrect) != 5 || top.SharedIncorrect[0] != "Q1" {
+		t.Errorf("expected 5 shared incorrect answers Q1-Q5, got %d %v", top.IdenticalIncorrect, top.SharedIncorrect)
+	}
+	if top.Identical != 10 {
+		t.Errorf("expected 10 identical responses, got %d", top.Identical)
+	}
+	if top.ExpectedIncorrect >= 5 || top.PValue > result.Threshold {
+		t.Errorf("expected fewer than 5 expected matches and a significant p-value, got %v and %v",
+			top.ExpectedIncorrect, top.PValue)
+	}
+	if top.Omega == nil || *top.Omega < 2 {
+		t.Errorf("expected a large ω index, got %v", top.Omega)
+	}
+	// Independent students are almost never flagged after the Bonferroni correction
+	if result.Flagged > 3 {
+		t.Errorf("too many flagged pairs: %d", result.Flagged)
+	}
+	if result.Examined > result.Pairs {
+		t.Errorf("examined %d pairs out of %d", result.Examined, result.Pairs)
+	}
+}
+
+// TestAnswerCopyingNoCorrection - 補正なしでは検出ペアが増えるかのテスト
+func TestAnswerCopyingNoCorrection(t *testing.T) {
+	setupCopyingTestData(100)
+
+	var corrected AnswerCopyingResponse
+	serveJSON(t, getAnswerCopying, "GET", "/api/answer-copying?correction=bonferroni", "", &corrected)
+	var uncorrected AnswerCopyingResponse
+	serveJSON(t, getAnswerCopying, "GET", "/api/answer-copying?correction=none&limit=5", "", &uncorrected)
+
+	if uncorrected.Threshold != 0.05 {
+		t.Errorf("expected threshold 0.05 without correction, got %v", uncorrected.Threshold)
+	}
+	if uncorrected.Flagged < corrected.Flagged {
+		t.Errorf("uncorrected analysis flagged fewer pairs (%d) than corrected (%d)", uncorrected.Flagged, corrected.Flagged)
+	}
+	if len(uncorrected.Flags) > 5 {
+		t.Errorf("expected at most 5 pairs with limit=5, got %d", len(uncorrected.Flags))
+	}
+	// The limit truncates the list but not the count, and keeps the most suspicious pairs
+	var all AnswerCopyingResponse
+	serveJSON(t, getAnswerCopying, "GET", "/api/answer-copying?correction=none&limit=10000", "", &all)
+	if all.Flagged != uncorrected.Flagged || len(all.Flags) != all.Flagged {
+		t.Errorf("expected %d flagged pairs with and without the limit, got %d and %d listed",
+			uncorrected.Flagged, all.Flagged, len(all.Flags))
+	}
+	for i := range uncorrected.Flags {
+		if uncorrected.Flags[i].StudentA != all.Flags[i].StudentA || uncorrected.Flags[i].StudentB != all.Flags[i].StudentB {
+			t.Errorf("pair %d differs under the limit: %+v vs %+v", i, uncorrected.Flags[i], all.Flags[i])
+		}
+	}
+	for i := 1; i < len(uncorrected.Flags); i++ {
+		if uncorrected.Flags[i-1].PValue > uncorrected.Flags[i].PValue {
+			t.Fatalf("pairs not sorted by p-value at %d", i)
+		}
+	}
+}
+
+// TestAnswerCopyingInvalidParams - 不正なパラメータのテスト
+func TestAnswerCopyingInvalidParams(t *testing.T) {
+	setupTestData()
+
+	for _, query := range []string{"alpha=0", "alpha=1", "correction=holm", "min_matches=0", "min_matches=11", "limit=0"} {
+		req, err := http.NewRequest("GET", "/api/answer-copying?"+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(getAnswerCopying).ServeHTTP(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", query, rr.Code)
+		}
+	}
+}
+
+// TestAnswerCopyingNonBinary - 0/1以外の得点がエラーになるかのテスト
+func TestAnswerCopyingNonBinary(t *testing.T) {
+	setupCopyingTestData(20)
+	grades[5].Q3 = 2
+
+	req, err := http.NewRequest("GET", "/api/answer-copying", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(getAnswerCopying).ServeHTTP(rr, req)
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("expected status 500 for a non-binary score, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,442 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Filter expressions select students by their responses, e.g.
+//
+//	q1 == 1 && (q3 == 0 || q5 == 1)
+//	total between 6 and 8
+//	not q2 == 1 or total >= 9
+//
+// Grammar (keywords and variable names are case-insensitive):
+//
+//	expr       := orExpr
+//	orExpr     := andExpr { ("||" | "or") andExpr }
+//	andExpr    := unary { ("&&" | "and") unary }
+//	unary      := ("!" | "not") unary | primary
+//	primary    := "(" expr ")" | "true" | "false" | comparison
+//	comparison := operand ("==" | "!=" | "<" | "<=" | ">" | ">=") operand
+//	            | operand "between" operand "and" operand
+//	operand    := "q1".."q10" | "total" | integer
+//
+// Expressions are parsed into a small AST and evaluated against each Grade; nothing is executed.
+
+const (
+	maxExpressionLength = 1000
+	maxExpressionDepth  = 50
+)
+
+// ExpressionError represents a parse error at a byte offset of the expression
+type ExpressionError struct {
+	Message  string
+	Position int
+}
+
+func (e *ExpressionError) Error() string {
+	return fmt.Sprintf("%s at position %d", e.Message, e.Position)
+}
+
+// filterExpr is a parsed filter expression
+type filterExpr interface {
+	eval(g Grade) bool
+	String() string
+}
+
+// operand is either a variable (question number, 0 for Total) or an integer constant
+type operand struct {
+	isConstant bool
+	variable   int
+	constant   int
+}
+
+func variableOperand(q int) operand {
+	return operand{variable: q}
+}
+
+func constantOperand(v int) operand {
+	return operand{isConstant: true, constant: v}
+}
+
+func (o operand) value(g Grade) int {
+	if o.isConstant {
+		return o.constant
+	}
+	return variableValue(g, o.variable)
+}
+
+func (o operand) String() string {
+	if o.isConstant {
+		return strconv.Itoa(o.constant)
+	}
+	if o.variable == 0 {
+		return "total"
+	}
+	return fmt.Sprintf("q%d", o.variable)
+}
+
+type comparisonExpr struct {
+	left  operand
+	op    string
+	right operand
+}
+
+func (e comparisonExpr) eval(g Grade) bool {
+	l, r := e.left.value(g), e.right.value(g)
+	switch e.op {
+	case "==":
+		return l == r
+	case "!=":
+		return l != r
+	case "<":
+		return l < r
+	case "<=":
+		return l <= r
+	case ">":
+		return l > r
+	case ">=":
+		return l >= r
+	}
+	return false
+}
+
+func (e comparisonExpr) String() string {
+	return fmt.Sprintf("%s %s %s", e.left, e.op, e.right)
+}
+
+type betweenExpr struct {
+	value operand
+	low   operand
+	high  operand
+}
+
+func (e betweenExpr) eval(g Grade) bool {
+	v := e.value.value(g)
+	return v >= e.low.value(g) && v <= e.high.value(g)
+}
+
+func (e betweenExpr) String() string {
+	return fmt.Sprintf("%s between %s and %s", e.value, e.low, e.high)
+}
+
+type andExpr struct {
+	left, right filterExpr
+}
+
+func (e andExpr) eval(g Grade) bool {
+	return e.left.eval(g) && e.right.eval(g)
+}
+
+func (e andExpr) String() string {
+	return fmt.Sprintf("(%s && %s)", e.left, e.right)
+}
+
+type orExpr struct {
+	left, right filterExpr
+}
+
+func (e orExpr) eval(g Grade) bool {
+	return e.left.eval(g) || e.right.eval(g)
+}
+
+func (e orExpr) String() string {
+	return fmt.Sprintf("(%s || %s)", e.left, e.right)
+}
+
+type notExpr struct {
+	inner filterExpr
+}
+
+func (e notExpr) eval(g Grade) bool {
+	return !e.inner.eval(g)
+}
+
+func (e notExpr) String() string {
+	return fmt.Sprintf("!%s", e.inner)
+}
+
+type boolLiteral bool
+
+func (e boolLiteral) eval(g Grade) bool {
+	return bool(e)
+}
+
+func (e boolLiteral) String() string {
+	return strconv.FormatBool(bool(e))
+}
+
+// token kinds produced by the lexer
+const (
+	tokenEOF = iota
+	tokenIdent
+	tokenNumber
+	tokenOperator
+	tokenLParen
+	tokenRParen
+)
+
+type token struct {
+	kind int
+	text string
+	pos  int
+}
+
+// tokenize splits an expression into tokens, lower-casing identifiers
+func tokenize(input string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(input) {
+		c := input[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", pos: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", pos: i})
+			i++
+		case isDigit(c) || (c == '-' && i+1 < len(input) && isDigit(input[i+1])):
+			start := i
+			i++
+			for i < len(input) && isDigit(input[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: input[start:i], pos: start})
+		case isLetter(c):
+			start := i
+			for i < len(input) && (isLetter(input[i]) || isDigit(input[i]) || input[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: strings.ToLower(input[start:i]), pos: start})
+		default:
+			two := ""
+			if i+1 < len(input) {
+				two = input[i : i+2]
+			}
+			switch {
+			case two == "==" || two == "!=" || two == "<=" || two == ">=" || two == "&&" || two == "||":
+				tokens = append(tokens, token{kind: tokenOperator, text: two, pos: i})
+				i += 2
+			case c == '<' || c == '>' || c == '!':
+				tokens = append(tokens, token{kind: tokenOperator, text: string(c), pos: i})
+				i++
+			case c == '=':
+				return nil, &ExpressionError{Message: "unexpected '=' (use '==' for equality)", Position: i}
+			default:
+				return nil, &ExpressionError{Message: fmt.Sprintf("unexpected character %q", c), Position: i}
+			}
+		}
+	}
+	tokens = append(tokens, token{kind: tokenEOF, pos: len(input)})
+	return tokens, nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// expressionParser is a recursive descent parser over the token stream
+type expressionParser struct {
+	tokens []token
+	pos    int
+	depth  int
+}
+
+// parseFilterExpression parses a filter expression into an evaluable AST
+func parseFilterExpression(input string) (filterExpr, error) {
+	if strings.TrimSpace(input) == "" {
+		return nil, &ExpressionError{Message: "empty expression", Position: 0}
+	}
+	if len(input) > maxExpressionLength {
+		return nil, &ExpressionError{
+			Message:  fmt.Sprintf("expression longer than %d characters", maxExpressionLength),
+			Position: maxExpressionLength,
+		}
+	}
+
+	tokens, err := tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &expressionParser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, p.errorAt(tok, fmt.Sprintf("unexpected %s", describeToken(tok)))
+	}
+	return expr, nil
+}
+
+func (p *expressionParser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *expressionParser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *expressionParser) errorAt(tok token, message string) error {
+	return &ExpressionError{Message: message, Position: tok.pos}
+}
+
+// isKeyword reports whether tok is one of the given operators or keywords
+func isKeyword(tok token, words ...string) bool {
+	if tok.kind != tokenOperator && tok.kind != tokenIdent {
+		return false
+	}
+	for _, w := range words {
+		if tok.text == w {
+			return true
+		}
+	}
+	return false
+}
+
+func describeToken(tok token) string {
+	if tok.kind == tokenEOF {
+		return "end of expression"
+	}
+	return fmt.Sprintf("'%s'", tok.text)
+}
+
+func (p *expressionParser) parseOr() (filterExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for isKeyword(p.peek(), "||", "or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orExpr{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *expressionParser) parseAnd() (filterExpr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for isKeyword(p.peek(), "&&", "and") {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andExpr{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *expressionParser) parseUnary() (filterExpr, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > maxExpressionDepth {
+		return nil, p.errorAt(p.peek(), "expression nested too deeply")
+	}
+
+	if isKeyword(p.peek(), "!", "not") {
+		p.next()
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{inner: inner}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *expressionParser) parsePrimary() (filterExpr, error) {
+	tok := p.peek()
+
+	if tok.kind == tokenLParen {
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, p.errorAt(closing, fmt.Sprintf("expected ')' but found %s", describeToken(closing)))
+		}
+		return inner, nil
+	}
+
+	if isKeyword(tok, "true", "false") {
+		p.next()
+		return boolLiteral(tok.text == "true"), nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *expressionParser) parseComparison() (filterExpr, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	opTok := p.next()
+	if isKeyword(opTok, "between") {
+		low, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		if andTok := p.next(); !isKeyword(andTok, "and", "&&") {
+			return nil, p.errorAt(andTok, fmt.Sprintf("expected 'and' in between but found %s", describeToken(andTok)))
+		}
+		high, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return betweenExpr{value: left, low: low, high: high}, nil
+	}
+
+	switch opTok.text {
+	case "==", "!=", "<", "<=", ">", ">=":
+		if opTok.kind != tokenOperator {
+			break
+		}
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return comparisonExpr{left: left, op: opTok.text, right: right}, nil
+	}
+	return nil, p.errorAt(opTok, fmt.Sprintf("expected comparison operator or 'between' but found %s", describeToken(opTok)))
+}
+
+func (p *expressionParser) parseOperand() (operand, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenNumber:
+		v, err := strconv.Atoi(tok.text)
+		if err != nil {
+			return operand{}, p.errorAt(tok, fmt.Sprintf("invalid number '%s'", tok.text))
+		}
+		return constantOperand(v), nil
+	case tokenIdent:
+		if q, ok := parseTableVariable(tok.text); ok {
+			return variableOperand(q), nil
+		}
+		return operand{}, p.errorAt(tok, fmt.Sprintf("unknown variable '%s' (must be q1-q10 or total)", tok.text))
+	}
+	return operand{}, p.errorAt(tok, fmt.Sprintf("expected variable or number but found %s", describeToken(tok)))
+}
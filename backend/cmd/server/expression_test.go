@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestParseFilterExpression - フィルタ式の構文解析と評価のテスト
+func TestParseFilterExpression(t *testing.T) {
+	grade := Grade{StudentID: 1, Q1: 1, Q2: 0, Q3: 0, Q4: 1, Q5: 1, Q6: 0, Q7: 1, Q8: 0, Q9: 0, Q10: 1, Total: 5}
+
+	testCases := []struct {
+		expression string
+		expected   bool
+	}{
+		{"q1 == 1", true},
+		{"q2==1", false},
+		{"Q1 == 1 && (q3 == 0 || q5 == 1)", true},
+		{"q1 == 1 and q2 == 1", false},
+		{"q2 == 1 or q4 == 1", true},
+		{"!q2 == 1", true},
+		{"not (q1 == 1 && q4 == 1)", false},
+		{"total between 4 and 6", true},
+		{"total between 6 and 8", false},
+		{"total between 4 and 6 and q2 == 0", true},
+		{"total >= 5 && total < 6", true},
+		{"total != 5", false},
+		{"q1 > q2", true},
+		{"5 <= total", true},
+		{"true", true},
+		{"false || q10 == 1", true},
+		{"q1 == 1 || q2 == 1 && q3 == 1", true}, // && は || より優先
+		{"q6 == -1", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expression, func(t *testing.T) {
+			expr, err := parseFilterExpression(tc.expression)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %v", err)
+			}
+			if got := expr.eval(grade); got != tc.expected {
+				t.Errorf("expected %v, got %v (parsed as %s)", tc.expected, got, expr)
+			}
+		})
+	}
+}
+
+// TestParseFilterExpressionErrors - 構文エラーと位置情報のテスト
+func TestParseFilterExpressionErrors(t *testing.T) {
+	testCases := []struct {
+		expression string
+		position   int
+	}{
+		{"", 0},
+		{"q1 = 1", 3},
+		{"q11 == 1", 0},
+		{"q1 == ", 6},
+		{"(q1 == 1", 8},
+		{"q1 == 1)", 7},
+		{"q1 1", 3},
+		{"total between 1 or 3", 16},
+		{"q1 == 1 && $", 11},
+		{"foo > 3", 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expression, func(t *testing.T) {
+			_, err := parseFilterExpression(tc.expression)
+			if err == nil {
+				t.Fatal("expected a parse error")
+			}
+			var exprErr *ExpressionError
+			if !errors.As(err, &exprErr) {
+				t.Fatalf("expected *ExpressionError, got %T", err)
+			}
+			if exprErr.Position != tc.position {
+				t.Errorf("expected error at position %d, got %d (%s)", tc.position, exprErr.Position, exprErr.Message)
+			}
+		})
+	}
+}
+
+// TestParseFilterExpressionDepthLimit - 過度なネストを拒否するテスト
+func TestParseFilterExpressionDepthLimit(t *testing.T) {
+	expression := ""
+	for i := 0; i < 100; i++ {
+		expression += "!"
+	}
+	expression += "q1 == 1"
+
+	if _, err := parseFilterExpression(expression); err == nil {
+		t.Error("expected deeply nested expression to be rejected")
+	}
+}
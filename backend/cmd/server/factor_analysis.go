@@ -0,0 +1,537 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"sort"
+)
+
+const (
+	// tetrachoricLimit bounds tetrachoric correlations away from ±1
+	tetrachoricLimit = 0.999
+	// minEigenvalue is the floor used when smoothing a correlation matrix that is not positive
+	// definite
+	minEigenvalue = 1e-4
+	// maxFactorIterations bounds principal axis factoring and the rotations
+	maxFactorIterations = 1000
+)
+
+// bivariateNormalCDF returns P(X ≤ h, Y ≤ k) for standard normals with correlation rho, using
+// Φ₂(h, k, ρ) = Φ(h)Φ(k) + ∫₀^ρ φ₂(h, k, r) dr with 20-point Gauss-Legendre quadrature
+func bivariateNormalCDF(h, k, rho float64) float64 {
+	nodes := [10]float64{0.0765265211334973, 0.2277858511416451, 0.3737060887154195, 0.5108670019508271,
+		0.6360536807265150, 0.7463319064601508, 0.8391169718222188, 0.9122344282513259,
+		0.9639719272779138, 0.9931285991850949}
+	weights := [10]float64{0.1527533871307258, 0.1491729864726037, 0.1420961093183820, 0.1316886384491766,
+		0.1181945319615184, 0.1019301198172404, 0.0832767415767048, 0.0626720483341091,
+		0.0406014298003869, 0.0176140071391521}
+	density := func(r float64) float64 {
+		s := 1 - r*r
+		return math.Exp(-(h*h-2*r*h*k+k*k)/(2*s)) / (2 * math.Pi * math.Sqrt(s))
+	}
+	var integral float64
+	for i, x := range nodes {
+		integral += weights[i] * (density(rho/2*(1+x)) + density(rho/2*(1-x)))
+	}
+	return normalCDF(h)*normalCDF(k) + rho/2*integral
+}
+
+// tetrachoric estimates the correlation of the latent normals behind two binary items by
+// maximum likelihood with the thresholds fixed at their marginal values. Half is added to every
+// cell when one is empty.
+func tetrachoric(x, y []int) float64 {
+	var table [2][2]float64
+	for i := range x {
+		table[x[i]][y[i]]++
+	}
+	if table[0][0] == 0 || table[0][1] == 0 || table[1][0] == 0 || table[1][1] == 0 {
+		for a := range table {
+			for b := range table[a] {
+				table[a][b] += 0.5
+			}
+		}
+	}
+	n := table[0][0] + table[0][1] + table[1][0] + table[1][1]
+	// Thresholds such that P(latent ≤ τ) = P(item = 0)
+	tauX := normalQuantile((table[0][0] + table[0][1]) / n)
+	tauY := normalQuantile((table[0][0] + table[1][0]) / n)
+	if math.IsInf(tauX, 0) || math.IsInf(tauY, 0) {
+		return 0
+	}
+	px, py := normalCDF(tauX), normalCDF(tauY)
+
+	logLik := func(rho float64) float64 {
+		p00 := bivariateNormalCDF(tauX, tauY, rho)
+		p01 := px - p00
+		p10 := py - p00
+		p11 := 1 - px - py + p00
+		ll := 0.0
+		for _, term := range [][2]float64{{table[0][0], p00}, {table[0][1], p01}, {table[1][0], p10}, {table[1][1], p11}} {
+			ll += term[0] * math.Log(math.Max(term[1], 1e-300))
+		}
+		return ll
+	}
+
+	// Golden section search on (−limit, limit); the likelihood is unimodal in ρ
+	ratio := (math.Sqrt(5) - 1) / 2
+	lo, hi := -tetrachoricLimit, tetrachoricLimit
+	a := hi - ratio*(hi-lo)
+	b := lo + ratio*(hi-lo)
+	fa, fb := logLik(a), logLik(b)
+	for hi-lo > 1e-7 {
+		if fa > fb {
+			hi, b, fb = b, a, fa
+			a = hi - ratio*(hi-lo)
+			fa = logLik(a)
+		} else {
+			lo, a, fa = a, b, fb
+			b = lo + ratio*(hi-lo)
+			fb = logLik(b)
+		}
+	}
+	return (lo + hi) / 2
+}
+
+// correlationMatrix returns the Pearson or tetrachoric correlation matrix of response columns.
+// Items without variance get zero correlations, like pearson.
+func correlationMatrix(responses [][]int, kind string) [][]float64 {
+	columns := make([][]int, numQuestions)
+	floatColumns := make([][]float64, numQuestions)
+	for j := range columns {
+		columns[j] = make([]int, len(responses))
+		floatColumns[j] = make([]float64, len(responses))
+		for i, row := range responses {
+			columns[j][i] = row[j]
+			floatColumns[j][i] = float64(row[j])
+		}
+	}
+	matrix := identityMatrix(numQuestions)
+	for a := 0; a < numQuestions; a++ {
+		for b := a + 1; b < numQuestions; b++ {
+			var r float64
+			if kind == "tetrachoric" {
+				r = tetrachoric(columns[a], columns[b])
+			} else {
+				r = pearson(floatColumns[a], floatColumns[b])
+			}
+			matrix[a][b], matrix[b][a] = r, r
+		}
+	}
+	return matrix
+}
+
+// smoothCorrelationMatrix raises eigenvalues below minEigenvalue and rescales to a unit
+// diagonal, so that pairwise tetrachoric matrices become positive definite. It reports whether
+// any change was needed.
+func smoothCorrelationMatrix(matrix [][]float64) ([][]float64, bool) {
+	values, vectors := symmetricEigen(matrix)
+	if values[len(values)-1] >= minEigenvalue {
+		return matrix, false
+	}
+	n := len(matrix)
+	smoothed := newMatrix(n, n)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			for k, v := range values {
+				smoothed[i][j] += vectors[i][k] * math.Max(v, minEigenvalue) * vectors[j][k]
+			}
+		}
+	}
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			if i != j {
+				smoothed[i][j] /= math.Sqrt(smoothed[i][i] * smoothed[j][j])
+			}
+		}
+	}
+	for i := 0; i < n; i++ {
+		smoothed[i][i] = 1
+	}
+	return smoothed, true
+}
+
+// parallelAnalysis compares the observed eigenvalues with those of data sets whose item columns
+// are permuted independently, which keeps every item's correct rate but removes correlations
+// (Horn 1965). It returns the mean and 95th percentile eigenvalues.
+func parallelAnalysis(responses [][]int, kind string, iterations int, rng *rand.Rand) ([]float64, []float64) {
+	n := len(responses)
+	permuted := make([][]int, n)
+	for i := range permuted {
+		permuted[i] = make([]int, numQuestions)
+	}
+	samples := make([][]float64, numQuestions)
+	for it := 0; it < iterations; it++ {
+		for j := 0; j < numQuestions; j++ {
+			order := rng.Perm(n)
+			for i, src := range order {
+				permuted[i][j] = responses[src][j]
+			}
+		}
+		matrix, _ := smoothCorrelationMatrix(correlationMatrix(permuted, kind))
+		values, _ := symmetricEigen(matrix)
+		for k, v := range values {
+			samples[k] = append(samples[k], v)
+		}
+	}
+	means := make([]float64, numQuestions)
+	percentiles := make([]float64, numQuestions)
+	for k, s := range samples {
+		means[k], _ = meanAndSD(s)
+		sort.Float64s(s)
+		percentiles[k] = quantile(s, 0.95)
+	}
+	return means, percentiles
+}
+
+// extractFactors returns unrotated loadings of k factors: principal component loadings
+// (eigenvectors scaled by √λ) or iterated principal axis factoring, which starts from squared
+// multiple correlations as communalities. It also reports whether PAF converged.
+func extractFactors(matrix [][]float64, k int, method string) ([][]float64, int, bool) {
+	n := len(matrix)
+	loadingsFrom := func(m [][]float64) [][]float64 {
+		values, vectors := symmetricEigen(m)
+		loadings := newMatrix(n, k)
+		for f := 0; f < k; f++ {
+			scale := math.Sqrt(math.Max(values[f], 0))
+			// Orient every factor so that its loadings sum to a positive value
+			var sum float64
+			for i := 0; i < n; i++ {
+				sum += vectors[i][f]
+			}
+			if sum < 0 {
+				scale = -scale
+			}
+			for i := 0; i < n; i++ {
+				loadings[i][f] = vectors[i][f] * scale
+			}
+		}
+		return loadings
+	}
+	if method == "pca" {
+		return loadingsFrom(matrix), 0, true
+	}
+
+	communalities := make([]float64, n)
+	if inverse, err := invertSPD(matrix); err == nil {
+		for i := range communalities {
+			communalities[i] = 1 - 1/inverse[i][i]
+		}
+	} else {
+		for i := range communalities {
+			communalities[i] = 0.5
+		}
+	}
+	reduced := newMatrix(n, n)
+	var loadings [][]float64
+	for iter := 1; iter <= maxFactorIterations; iter++ {
+		for i := range matrix {
+			copy(reduced[i], matrix[i])
+			reduced[i][i] = communalities[i]
+		}
+		loadings = loadingsFrom(reduced)
+		var change float64
+		for i := range communalities {
+			var h2 float64
+			for _, l := range loadings[i] {
+				h2 += l * l
+			}
+			change = math.Max(change, math.Abs(h2-communalities[i]))
+			communalities[i] = h2
+		}
+		if change < 1e-6 {
+			return loadings, iter, true
+		}
+	}
+	return loadings, maxFactorIterations, false
+}
+
+// varimax rotates loadings orthogonally to maximise the variance of squared loadings within
+// each factor, using Kaiser normalisation and pairwise planar rotations
+func varimax(loadings [][]float64) [][]float64 {
+	n, k := len(loadings), len(loadings[0])
+	if k < 2 {
+		return loadings
+	}
+	rotated := newMatrix(n, k)
+	norms := make([]float64, n)
+	for i, row := range loadings {
+		norms[i] = math.Sqrt(dot(row, row))
+		for f, v := range row {
+			if norms[i] > 0 {
+				rotated[i][f] = v / norms[i]
+			}
+		}
+	}
+	for iter := 0; iter < maxFactorIterations; iter++ {
+		var maxAngle float64
+		for p := 0; p < k; p++ {
+			for q := p + 1; q < k; q++ {
+				var a, b, c, d float64
+				for i := 0; i < n; i++ {
+					u := rotated[i][p]*rotated[i][p] - rotated[i][q]*rotated[i][q]
+					v := 2 * rotated[i][p] * rotated[i][q]
+					a += u
+					b += v
+					c += u*u - v*v
+					d += 2 * u * v
+				}
+				angle := math.Atan2(d-2*a*b/float64(n), c-(a*a-b*b)/float64(n)) / 4
+				maxAngle = math.Max(maxAngle, math.Abs(angle))
+				cos, sin := math.Cos(angle), math.Sin(angle)
+				for i := 0; i < n; i++ {
+					x, y := rotated[i][p], rotated[i][q]
+					rotated[i][p] = x*cos + y*sin
+					rotated[i][q] = -x*sin + y*cos
+				}
+			}
+		}
+		if maxAngle < 1e-8 {
+			break
+		}
+	}
+	for i := range rotated {
+		for f := range rotated[i] {
+			rotated[i][f] *= norms[i]
+		}
+	}
+	return rotated
+}
+
+// promax raises varimax loadings to the fourth power (keeping signs) as an oblique target,
+// fits it by least squares and returns the pattern loadings and factor correlations
+// (Hendrickson & White 1964)
+func promax(loadings [][]float64) ([][]float64, [][]float64, error) {
+	k := len(loadings[0])
+	rotated := varimax(loadings)
+	if k < 2 {
+		return rotated, identityMatrix(1), nil
+	}
+	target := newMatrix(len(rotated), k)
+	for i, row := range rotated {
+		for f, v := range row {
+			target[i][f] = v * v * v * math.Abs(v)
+		}
+	}
+	lt := transpose(rotated)
+	inverse, err := invertSPD(matMul(lt, rotated))
+	if err != nil {
+		return nil, nil, err
+	}
+	t := matMul(inverse, matMul(lt, target))
+	// Scale the columns so that the factors have unit variance
+	tInverse, err := invertSPD(matMul(transpose(t), t))
+	if err != nil {
+		return nil, nil, err
+	}
+	for f := 0; f < k; f++ {
+		scale := math.Sqrt(tInverse[f][f])
+		for g := 0; g < k; g++ {
+			t[g][f] *= scale
+		}
+	}
+	phi, err := invertSPD(matMul(transpose(t), t))
+	if err != nil {
+		return nil, nil, err
+	}
+	return matMul(rotated, t), phi, nil
+}
+
+// FactorEigenvalue represents one eigenvalue of the correlation matrix with its scree and
+// parallel analysis data
+type FactorEigenvalue struct {
+	Index              int     `json:"index"`
+	Eigenvalue         float64 `json:"eigenvalue"`
+	ProportionVariance float64 `json:"proportion_variance"`
+	CumulativeVariance float64 `json:"cumulative_variance"`
+	ParallelMean       float64 `json:"parallel_mean"`
+	Parallel95         float64 `json:"parallel_95"`
+	Retain             bool    `json:"retain"`
+}
+
+// ItemLoadings represents the loadings of one item on every factor
+type ItemLoadings struct {
+	Question    string    `json:"question"`
+	Loadings    []float64 `json:"loadings"`
+	Communality float64   `json:"communality"`
+	Uniqueness  float64   `json:"uniqueness"`
+}
+
+// FactorAnalysisResponse represents an exploratory factor analysis of the items
+type FactorAnalysisResponse struct {
+	Correlation        string             `json:"correlation"`
+	Extraction         string             `json:"extraction"`
+	Rotation           string             `json:"rotation"`
+	N                  int                `json:"n"`
+	Factors            int                `json:"factors"`
+	SuggestedFactors   int                `json:"suggested_factors"`
+	Smoothed           bool               `json:"smoothed"`
+	Converged          bool               `json:"converged"`
+	Iterations         int                `json:"iterations"`
+	Matrix             [][]float64        `json:"matrix"`
+	Eigenvalues        []FactorEigenvalue `json:"eigenvalues"`
+	Loadings           []ItemLoadings     `json:"loadings"`
+	VarianceExplained  []float64          `json:"variance_explained"`
+	FactorCorrelations [][]float64        `json:"factor_correlations,omitempty"`
+	ParallelIterations int                `json:"parallel_iterations"`
+	Warnings           []string           `json:"warnings"`
+}
+
+// Handler: Get exploratory factor analysis
+// Returns eigenvalues with scree and parallel analysis data and the loadings of k factors
+// (principal axis or principal components, varimax/promax rotation) on the Pearson or
+// tetrachoric item correlation matrix
+func getFactorAnalysis(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	kind := r.URL.Query().Get("correlation")
+	if kind == "" {
+		kind = "pearson"
+	}
+	if kind != "pearson" && kind != "tetrachoric" {
+		http.Error(w, "Invalid 'correlation' parameter (must be pearson or tetrachoric)", http.StatusBadRequest)
+		return
+	}
+	extraction := r.URL.Query().Get("extraction")
+	if extraction == "" {
+		extraction = "paf"
+	}
+	if extraction != "paf" && extraction != "pca" {
+		http.Error(w, "Invalid 'extraction' parameter (must be paf or pca)", http.StatusBadRequest)
+		return
+	}
+	rotation := r.URL.Query().Get("rotation")
+	if rotation == "" {
+		rotation = "varimax"
+	}
+	if rotation != "none" && rotation != "varimax" && rotation != "promax" {
+		http.Error(w, "Invalid 'rotation' parameter (must be none, varimax or promax)", http.StatusBadRequest)
+		return
+	}
+	factors, err := intParam(r, "factors", 0)
+	if err != nil || factors < 0 || factors >= numQuestions {
+		http.Error(w, fmt.Sprintf("Invalid 'factors' parameter (must be between 1 and %d)", numQuestions-1), http.StatusBadRequest)
+		return
+	}
+	iterations, err := intParam(r, "iterations", 100)
+	if err != nil || iterations < 10 || iterations > 1000 {
+		http.Error(w, "Invalid 'iterations' parameter (must be between 10 and 1000)", http.StatusBadRequest)
+		return
+	}
+	seed, err := intParam(r, "seed", 1)
+	if err != nil {
+		http.Error(w, "Invalid 'seed' parameter", http.StatusBadRequest)
+		return
+	}
+
+	if len(grades) < 2 {
+		http.Error(w, "No data available", http.StatusInternalServerError)
+		return
+	}
+
+	responses := responseMatrix()
+	if kind == "tetrachoric" {
+		for i, row := range responses {
+			for j, x := range row {
+				if x != 0 && x != 1 {
+					http.Error(w, fmt.Sprintf("student %d has non-binary Q%d", grades[i].StudentID, j+1), http.StatusInternalServerError)
+					return
+				}
+			}
+		}
+	}
+	// Unlike getCorrelationMatrix, items without variance get zero correlations (see pearson)
+	matrix, smoothed := smoothCorrelationMatrix(correlationMatrix(responses, kind))
+	values, _ := symmetricEigen(matrix)
+	parallelMean, parallel95 := parallelAnalysis(responses, kind, iterations, rand.New(rand.NewSource(int64(seed))))
+
+	response := FactorAnalysisResponse{
+		Correlation:        kind,
+		Extraction:         extraction,
+		Rotation:           rotation,
+		N:                  len(grades),
+		Smoothed:           smoothed,
+		Matrix:             matrix,
+		ParallelIterations: iterations,
+		Warnings:           []string{},
+	}
+	if smoothed {
+		response.Warnings = append(response.Warnings, "The correlation matrix was not positive definite and has been smoothed")
+	}
+
+	var cumulative float64
+	retaining := true
+	for k, v := range values {
+		cumulative += v
+		retain := retaining && v > parallel95[k]
+		if retain {
+			response.SuggestedFactors++
+		} else {
+			retaining = false
+		}
+		response.Eigenvalues = append(response.Eigenvalues, FactorEigenvalue{
+			Index:              k + 1,
+			Eigenvalue:         v,
+			ProportionVariance: v / float64(numQuestions),
+			CumulativeVariance: cumulative / float64(numQuestions),
+			ParallelMean:       parallelMean[k],
+			Parallel95:         parallel95[k],
+			Retain:             retain,
+		})
+	}
+
+	response.Factors = factors
+	if factors == 0 {
+		response.Factors = response.SuggestedFactors
+		if response.Factors == 0 {
+			response.Factors = 1
+			response.Warnings = append(response.Warnings, "No eigenvalue exceeds parallel analysis; showing one factor")
+		}
+	}
+
+	loadings, iters, converged := extractFactors(matrix, response.Factors, extraction)
+	response.Iterations = iters
+	response.Converged = converged
+	if !converged {
+		response.Warnings = append(response.Warnings, "Principal axis factoring did not converge")
+	}
+	switch rotation {
+	case "varimax":
+		loadings = varimax(loadings)
+	case "promax":
+		pattern, phi, err := promax(loadings)
+		if err != nil {
+			response.Warnings = append(response.Warnings, "Promax rotation failed; showing varimax loadings")
+			loadings = varimax(loadings)
+		} else {
+			loadings = pattern
+			response.FactorCorrelations = phi
+		}
+	}
+
+	labels := questionLabels()
+	response.VarianceExplained = make([]float64, response.Factors)
+	for i, row := range loadings {
+		// Communalities come from the structure implied by the factors: h² = λᵀΦλ
+		communality := dot(row, row)
+		if response.FactorCorrelations != nil {
+			communality = dot(row, matVec(response.FactorCorrelations, row))
+		}
+		for f, l := range row {
+			response.VarianceExplained[f] += l * l
+		}
+		if communality > 1 {
+			response.Warnings = append(response.Warnings, fmt.Sprintf("%s: communality %.3f exceeds 1 (Heywood case)", labels[i], communality))
+		}
+		response.Loadings = append(response.Loadings, ItemLoadings{
+			Question:    labels[i],
+			Loadings:    row,
+			Communality: communality,
+			Uniqueness:  1 - communality,
+		})
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// setupTwoFactorTestData - Q1-Q5は因子1、Q6-Q10は因子2を測る（因子間相関 rho）潜在正規モデルからデータを生成する
+func setupTwoFactorTestData(n int, rho float64) {
+	rng := rand.New(rand.NewSource(5))
+	grades = []Grade{}
+	thresholds := []float64{-0.8, -0.4, 0, 0.4, 0.8}
+	for i := 0; i < n; i++ {
+		f1 := rng.NormFloat64()
+		f2 := rho*f1 + math.Sqrt(1-rho*rho)*rng.NormFloat64()
+		values := make([]int, numQuestions)
+		g := Grade{StudentID: i + 1}
+		for j := range values {
+			factor := f1
+			if j >= 5 {
+				factor = f2
+			}
+			latent := 0.8*factor + 0.6*rng.NormFloat64()
+			if latent > thresholds[j%5] {
+				values[j] = 1
+				g.Total++
+			}
+		}
+		g.Q1, g.Q2, g.Q3, g.Q4, g.Q5 = values[0], values[1], values[2], values[3], values[4]
+		g.Q6, g.Q7, g.Q8, g.Q9, g.Q10 = values[5], values[6], values[7], values[8], values[9]
+		grades = append(grades, g)
+	}
+}
+
+// checkSimpleStructure - 各問題が自分の因子に大きく、もう一方の因子に小さく負荷することを確認する
+func checkSimpleStructure(t *testing.T, result FactorAnalysisResponse, minLoading, maxCrossLoading float64) {
+	// 因子の順序は回転で入れ替わりうるので、Q1が最も大きく負荷する因子を因子Aとする
+	first := 0
+	if math.Abs(result.Loadings[0].Loadings[1]) > math.Abs(result.Loadings[0].Loadings[0]) {
+		first = 1
+	}
+	for j, item := range result.Loadings {
+		own, other := first, 1-first
+		if j >= 5 {
+			own, other = other, own
+		}
+		if item.Loadings[own] < minLoading || math.Abs(item.Loadings[other]) > maxCrossLoading {
+			t.Errorf("%s: loadings %v do not show simple structure", item.Question, item.Loadings)
+		}
+	}
+}
+
+// TestSymmetricEigen - ヤコビ法の固有値分解のテスト
+func TestSymmetricEigen(t *testing.T) {
+	a := [][]float64{{4, 1, 2}, {1, 3, 0}, {2, 0, 5}}
+	values, vectors := symmetricEigen(a)
+
+	if values[0] < values[1] || values[1] < values[2] {
+		t.Errorf("eigenvalues not in descending order: %v", values)
+	}
+	if math.Abs(values[0]+values[1]+values[2]-12) > 1e-9 {
+		t.Errorf("eigenvalues %v do not sum to the trace 12", values)
+	}
+	for k, lambda := range values {
+		v := []float64{vectors[0][k], vectors[1][k], vectors[2][k]}
+		av := matVec(a, v)
+		for i := range v {
+			if math.Abs(av[i]-lambda*v[i]) > 1e-9 {
+				t.Errorf("A·v ≠ λ·v for eigenvalue %v", lambda)
+			}
+		}
+	}
+}
+
+// TestTetrachoric - 二変量正規分布の累積確率と四分相関係数のテスト
+func TestTetrachoric(t *testing.T) {
+	for _, rho := range []float64{-0.7, 0, 0.3, 0.9} {
+		expected := 0.25 + math.Asin(rho)/(2*math.Pi)
+		if got := bivariateNormalCDF(0, 0, rho); math.Abs(got-expected) > 1e-9 {
+			t.Errorf("Φ2(0, 0, %v): expected %v, got %v", rho, expected, got)
+		}
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	var x, y []int
+	for i := 0; i < 20000; i++ {
+		a := rng.NormFloat64()
+		b := 0.6*a + 0.8*rng.NormFloat64()
+		xi, yi := 0, 0
+		if a > 0.3 {
+			xi = 1
+		}
+		if b > -0.5 {
+			yi = 1
+		}
+		x = append(x, xi)
+		y = append(y, yi)
+	}
+	if r := tetrachoric(x, y); math.Abs(r-0.6) > 0.03 {
+		t.Errorf("expected tetrachoric correlation near 0.6, got %v", r)
+	}
+}
+
+// TestFactorAnalysisTwoFactors - 平行分析が2因子を示し、バリマックス回転で単純構造が得られることのテスト
+func TestFactorAnalysisTwoFactors(t *testing.T) {
+	setupTwoFactorTestData(800, 0)
+
+	var result FactorAnalysisResponse
+	serveJSON(t, getFactorAnalysis, "GET", "/api/factor-analysis?iterations=20", "", &result)
+
+	if result.SuggestedFactors != 2 || result.Factors != 2 {
+		t.Fatalf("expected 2 suggested factors, got %d (eigenvalues %+v)", result.SuggestedFactors, result.Eigenvalues)
+	}
+	if len(result.Eigenvalues) != numQuestions || math.Abs(result.Eigenvalues[numQuestions-1].CumulativeVariance-1) > 1e-9 {
+		t.Errorf("unexpected scree data %+v", result.Eigenvalues)
+	}
+	if !result.Converged || result.FactorCorrelations != nil {
+		t.Errorf("expected a converged orthogonal solution")
+	}
+	checkSimpleStructure(t, result, 0.3, 0.15)
+	for _, item := range result.Loadings {
+		if math.Abs(item.Communality+item.Uniqueness-1) > 1e-9 {
+			t.Errorf("%s: communality and uniqueness do not sum to 1", item.Question)
+		}
+	}
+}
+
+// TestFactorAnalysisPromaxTetrachoric - 四分相関とプロマックス回転で因子間相関が推定されることのテスト
+func TestFactorAnalysisPromaxTetrachoric(t *testing.T) {
+	setupTwoFactorTestData(800, 0.5)
+
+	var result FactorAnalysisResponse
+	serveJSON(t, getFactorAnalysis, "GET", "/api/factor-analysis?correlation=tetrachoric&rotation=promax&factors=2&iterations=10", "", &result)
+
+	if len(result.FactorCorrelations) != 2 {
+		t.Fatalf("expected a 2×2 factor correlation matrix, got %v", result.FactorCorrelations)
+	}
+	if phi := result.FactorCorrelations[0][1]; phi < 0.3 || phi > 0.7 {
+		t.Errorf("expected a factor correlation near 0.5, got %v", phi)
+	}
+	checkSimpleStructure(t, result, 0.5, 0.2)
+}
+
+// TestFactorAnalysisPCAOneFactor - 主成分の第1負荷量が固有値の平方根とベクトルから得られることのテスト
+func TestFactorAnalysisPCAOneFactor(t *testing.T) {
+	setupTwoFactorTestData(300, 0.9)
+
+	var result FactorAnalysisResponse
+	serveJSON(t, getFactorAnalysis, "GET", "/api/factor-analysis?extraction=pca&rotation=none&factors=1&iterations=10", "", &result)
+
+	var ss float64
+	for _, item := range result.Loadings {
+		ss += item.Loadings[0] * item.Loadings[0]
+		if item.Loadings[0] <= 0 {
+			t.Errorf("%s: expected a positive loading on the general factor", item.Question)
+		}
+	}
+	if math.Abs(ss-result.Eigenvalues[0].Eigenvalue) > 1e-9 || math.Abs(result.VarianceExplained[0]-ss) > 1e-9 {
+		t.Errorf("sum of squared loadings %v does not match the first eigenvalue %v", ss, result.Eigenvalues[0].Eigenvalue)
+	}
+}
+
+// TestFactorAnalysisInvalidParameters - 不正なパラメータのテスト
+func TestFactorAnalysisInvalidParameters(t *testing.T) {
+	setupTestData()
+
+	for _, query := range []string{
+		"correlation=spearman",
+		"extraction=ml",
+		"rotation=oblimin",
+		"factors=10",
+		"iterations=5",
+	} {
+		req, _ := http.NewRequest("GET", "/api/factor-analysis?"+query, nil)
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(getFactorAnalysis).ServeHTTP(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("query %q: expected 400, got %d", query, rr.Code)
+		}
+	}
+}
+
+// TestFactorAnalysisNonBinary - 0/1以外の得点ではテトラコリック相関がエラーになるかのテスト
+func TestFactorAnalysisNonBinary(t *testing.T) {
+	setupTestData()
+	grades[1].Q1 = 2
+
+	req, _ := http.NewRequest("GET", "/api/factor-analysis?correlation=tetrachoric", nil)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(getFactorAnalysis).ServeHTTP(rr, req)
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500 for a non-binary score, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"math/rand"
+	"net/http"
+)
+
+// Normal model for Totals with the conjugate Normal–Inverse-Gamma prior
+//
+//	y_i ~ N(μ, σ²),  μ | σ² ~ N(μ0, σ²/κ0),  σ² ~ InvGamma(a0, b0)
+//
+// The Gibbs sampler alternates the full conditionals
+//
+//	μ | σ², y ~ N((κ0μ0 + nȳ)/(κ0+n), σ²/(κ0+n))
+//	σ² | μ, y ~ InvGamma(a0 + (n+1)/2, b0 + ½[Σ(y_i-μ)² + κ0(μ-μ0)²])
+//
+// and the closed-form posterior is NIG(μn, κn, an, bn), whose marginals are
+// μ ~ t_{2an}(μn, bn/(an κn)) and σ² ~ InvGamma(an, bn).
+
+// NIGParameters represents the parameters of a Normal–Inverse-Gamma distribution
+type NIGParameters struct {
+	Mu    float64 `json:"mu"`
+	Kappa float64 `json:"kappa"`
+	Alpha float64 `json:"alpha"`
+	Beta  float64 `json:"beta"`
+}
+
+// AnalyticSummary represents the closed-form marginal posterior of a parameter. Mean and SD
+// are null when the moment does not exist.
+type AnalyticSummary struct {
+	Mean  *float64 `json:"mean"`
+	SD    *float64 `json:"sd"`
+	Lower float64  `json:"lower"`
+	Upper float64  `json:"upper"`
+}
+
+// GibbsParameterComparison compares the sampled and analytic marginal posterior of a parameter
+type GibbsParameterComparison struct {
+	Name     string           `json:"name"`
+	Sampled  ParameterSummary `json:"sampled"`
+	Analytic AnalyticSummary  `json:"analytic"`
+}
+
+// GibbsDraw represents one joint posterior draw
+type GibbsDraw struct {
+	Mu     float64 `json:"mu"`
+	Sigma2 float64 `json:"sigma2"`
+}
+
+// GibbsNormalResponse represents the Gibbs sampler output for the Normal model on Totals
+type GibbsNormalResponse struct {
+	N              int                        `json:"n"`
+	SampleMean     float64                    `json:"sample_mean"`
+	SampleVariance float64                    `json:"sample_variance"`
+	Level          float64                    `json:"level"`
+	Draws          int                        `json:"draws"`
+	BurnIn         int                        `json:"burn_in"`
+	Thin           int                        `json:"thin"`
+	Seed           int64                      `json:"seed"`
+	Prior          NIGParameters              `json:"prior"`
+	Posterior      NIGParameters              `json:"posterior"`
+	Parameters     []GibbsParameterComparison `json:"parameters"`
+	Samples        []GibbsDraw                `json:"samples"`
+}
+
+// updateNIG returns the conjugate posterior of a NIG prior given data y
+func updateNIG(prior NIGParameters, y []float64) NIGParameters {
+	n := float64(len(y))
+	mean, sd := meanAndSD(y)
+	return updateNIGSufficient(prior, n, mean, sd*sd*(n-1))
+}
+
+// updateNIGSufficient returns the conjugate posterior of a NIG prior given the sample size, mean
+// and sum of squared deviations of the data
+func updateNIGSufficient(prior NIGParameters, n, mean, sumSquares float64) NIGParameters {
+	kappa := prior.Kappa + n
+	return NIGParameters{
+		Mu:    (prior.Kappa*prior.Mu + n*mean) / kappa,
+		Kappa: kappa,
+		Alpha: prior.Alpha + n/2,
+		Beta:  prior.Beta + 0.5*sumSquares + prior.Kappa*n*(mean-prior.Mu)*(mean-prior.Mu)/(2*kappa),
+	}
+}
+
+// studentTQuantile returns the p-quantile of Student's t with nu degrees of freedom, using
+// P(|T| > t) = I_{ν/(ν+t²)}(ν/2, ½)
+func studentTQuantile(p, nu float64) float64 {
+	if p == 0.5 {
+		return 0
+	}
+	tail := math.Min(p, 1-p)
+	x := betaQuantile(2*tail, nu/2, 0.5)
+	t := math.Sqrt(nu * (1 - x) / x)
+	if p < 0.5 {
+		return -t
+	}
+	return t
+}
+
+// gammaQuantile returns the p-quantile of Gamma(shape, 1) by bisection on the regularized
+// incomplete gamma function
+func gammaQuantile(p, shape float64) float64 {
+	lo, hi := 0.0, math.Max(1, shape)
+	for 1-regularizedGammaQ(shape, hi) < p {
+		hi *= 2
+	}
+	for iter := 0; iter < 200; iter++ {
+		mid := (lo + hi) / 2
+		if 1-regularizedGammaQ(shape, mid) < p {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return (lo + hi) / 2
+}
+
+// analyticNIGMarginals returns the closed-form marginal summaries of μ and σ²
+func analyticNIGMarginals(post NIGParameters, level float64) (AnalyticSummary, AnalyticSummary) {
+	tail := (1 - level) / 2
+	nu := 2 * post.Alpha
+	scale := math.Sqrt(post.Beta / (post.Alpha * post.Kappa))
+
+	mu := AnalyticSummary{
+		Mean:  finiteOrNil(post.Mu),
+		Lower: post.Mu + scale*studentTQuantile(tail, nu),
+		Upper: post.Mu + scale*studentTQuantile(1-tail, nu),
+	}
+	if post.Alpha > 1 {
+		mu.SD = finiteOrNil(math.Sqrt(post.Beta / (post.Kappa * (post.Alpha - 1))))
+	}
+
+	// σ² = bn / G with G ~ Gamma(an, 1), so its quantiles are reversed
+	sigma2 := AnalyticSummary{
+		Lower: post.Beta / gammaQuantile(1-tail, post.Alpha),
+		Upper: post.Beta / gammaQuantile(tail, post.Alpha),
+	}
+	if post.Alpha > 1 {
+		sigma2.Mean = finiteOrNil(post.Beta / (post.Alpha - 1))
+	}
+	if post.Alpha > 2 {
+		sigma2.SD = finiteOrNil(post.Beta / ((post.Alpha - 1) * math.Sqrt(post.Alpha-2)))
+	}
+	return mu, sigma2
+}
+
+// runNIGGibbs runs the Gibbs sampler and returns the retained (μ, σ²) draws
+func runNIGGibbs(prior NIGParameters, y []float64, draws, burnIn, thin int, seed int64) [][]float64 {
+	rng := rand.New(rand.NewSource(seed))
+	n := float64(len(y))
+	mean, _ := meanAndSD(y)
+	kappa := prior.Kappa + n
+	muCenter := (prior.Kappa*prior.Mu + n*mean) / kappa
+
+	// Start σ² at its prior scale so the first μ draw is sensible
+	sigma2 := prior.Beta / math.Max(prior.Alpha, 1)
+	var samples [][]float64
+	for iter := 0; iter < burnIn+draws*thin; iter++ {
+		mu := muCenter + math.Sqrt(sigma2/kappa)*rng.NormFloat64()
+
+		var residuals float64
+		for _, v := range y {
+			residuals += (v - mu) * (v - mu)
+		}
+		shape := prior.Alpha + (n+1)/2
+		scale := prior.Beta + 0.5*(residuals+prior.Kappa*(mu-prior.Mu)*(mu-prior.Mu))
+		sigma2 = sampleInverseGamma(rng, shape, scale)
+
+		if iter >= burnIn && (iter-burnIn)%thin == 0 {
+			samples = append(samples, []float64{mu, sigma2})
+		}
+	}
+	return samples
+}
+
+// parseNIGPrior reads the Normal–Inverse-Gamma prior parameters mu0, kappa0, a0 and b0
+func parseNIGPrior(r *http.Request) (NIGParameters, error) {
+	prior := NIGParameters{}
+	var err error
+	if prior.Mu, err = floatParam(r, "mu0", float64(numQuestions)/2); err != nil {
+		return prior, errors.New("Invalid 'mu0' parameter")
+	}
+	if prior.Kappa, err = floatParam(r, "kappa0", 1); err != nil || prior.Kappa <= 0 {
+		return prior, errors.New("Invalid 'kappa0' parameter (must be positive)")
+	}
+	if prior.Alpha, err = floatParam(r, "a0", 1); err != nil || prior.Alpha <= 0 {
+		return prior, errors.New("Invalid 'a0' parameter (must be positive)")
+	}
+	if prior.Beta, err = floatParam(r, "b0", 1); err != nil || prior.Beta <= 0 {
+		return prior, errors.New("Invalid 'b0' parameter (must be positive)")
+	}
+	return prior, nil
+}
+
+// Handler: Get Gibbs sampler for the Normal model
+// Samples the Normal–Inverse-Gamma posterior of the mean and variance of Totals by Gibbs
+// sampling and compares the draws with the closed-form conjugate posterior
+func getGibbsNormal(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	prior, err := parseNIGPrior(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	level, err := floatParam(r, "level", 0.95)
+	if err != nil || level <= 0 || level >= 1 {
+		http.Error(w, "Invalid 'level' parameter (must be between 0 and 1)", http.StatusBadRequest)
+		return
+	}
+	draws, err := intParam(r, "draws", 5000)
+	if err != nil || draws < 100 || draws > 100000 {
+		http.Error(w, "Invalid 'draws' parameter (must be between 100 and 100000)", http.StatusBadRequest)
+		return
+	}
+	burnIn, err := intParam(r, "burn_in", 500)
+	if err != nil || burnIn < 0 || burnIn > 100000 {
+		http.Error(w, "Invalid 'burn_in' parameter (must be between 0 and 100000)", http.StatusBadRequest)
+		return
+	}
+	thin, err := intParam(r, "thin", 1)
+	if err != nil || thin < 1 || thin > 100 {
+		http.Error(w, "Invalid 'thin' parameter (must be between 1 and 100)", http.StatusBadRequest)
+		return
+	}
+	seed, err := intParam(r, "seed", 1)
+	if err != nil {
+		http.Error(w, "Invalid 'seed' parameter", http.StatusBadRequest)
+		return
+	}
+
+	if len(grades) == 0 {
+		http.Error(w, "No data available", http.StatusInternalServerError)
+		return
+	}
+
+	y := totalColumn()
+	mean, sd := meanAndSD(y)
+	posterior := updateNIG(prior, y)
+	samples := runNIGGibbs(prior, y, draws, burnIn, thin, int64(seed))
+
+	chain := []chainResult{{draws: samples}}
+	analyticMu, analyticSigma2 := analyticNIGMarginals(posterior, level)
+	response := GibbsNormalResponse{
+		N:              len(y),
+		SampleMean:     mean,
+		SampleVariance: sd * sd,
+		Level:          level,
+		Draws:          draws,
+		BurnIn:         burnIn,
+		Thin:           thin,
+		Seed:           int64(seed),
+		Prior:          prior,
+		Posterior:      posterior,
+		Parameters: []GibbsParameterComparison{
+			{Name: "mu", Sampled: summarizeParameter(chain, 0, level), Analytic: analyticMu},
+			{Name: "sigma2", Sampled: summarizeParameter(chain, 1, level), Analytic: analyticSigma2},
+		},
+	}
+	for _, s := range samples {
+		response.Samples = append(response.Samples, GibbsDraw{Mu: s[0], Sigma2: s[1]})
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
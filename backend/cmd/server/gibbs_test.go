@@ -0,0 +1,122 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestGibbsNormal - ギブスサンプラーと解析解の一致のテスト
+func TestGibbsNormal(t *testing.T) {
+	setupTotalsTestData(3, 4, 5, 5, 6, 6, 6, 7, 7, 8, 9, 10)
+
+	var result GibbsNormalResponse
+	serveJSON(t, getGibbsNormal, "GET", "/api/gibbs/normal?draws=20000&seed=4", "", &result)
+
+	if len(result.Samples) != 20000 {
+		t.Errorf("expected 20000 joint draws, got %d", len(result.Samples))
+	}
+	if result.Posterior.Kappa != 13 || result.Posterior.Alpha != 7 {
+		t.Errorf("expected posterior kappa 13 and alpha 7, got %+v", result.Posterior)
+	}
+
+	for _, p := range result.Parameters {
+		if p.Analytic.Mean == nil || p.Analytic.SD == nil {
+			t.Fatalf("%s: expected analytic moments, got %+v", p.Name, p.Analytic)
+		}
+		// モンテカルロ誤差を考慮して解析的な標準偏差に対する相対誤差で比較する
+		sd := *p.Analytic.SD
+		if math.Abs(p.Sampled.Mean-*p.Analytic.Mean) > 0.1*sd {
+			t.Errorf("%s: sampled mean %.4f vs analytic %.4f", p.Name, p.Sampled.Mean, *p.Analytic.Mean)
+		}
+		if math.Abs(p.Sampled.SD-sd) > 0.05*sd {
+			t.Errorf("%s: sampled SD %.4f vs analytic %.4f", p.Name, p.Sampled.SD, *p.Analytic.SD)
+		}
+		if math.Abs(p.Sampled.Lower-p.Analytic.Lower) > 0.2*sd || math.Abs(p.Sampled.Upper-p.Analytic.Upper) > 0.2*sd {
+			t.Errorf("%s: sampled interval [%.4f, %.4f] vs analytic [%.4f, %.4f]", p.Name,
+				p.Sampled.Lower, p.Sampled.Upper, p.Analytic.Lower, p.Analytic.Upper)
+		}
+	}
+}
+
+// TestUpdateNIG - 共役事後分布の更新のテスト
+func TestUpdateNIG(t *testing.T) {
+	// y = (4, 6), ȳ = 5, Σ(y-ȳ)² = 2
+	post := updateNIG(NIGParameters{Mu: 3, Kappa: 2, Alpha: 1, Beta: 1}, []float64{4, 6})
+	// μn = (2·3 + 2·5)/4 = 4, bn = 1 + 1 + 2·2·(5-3)²/(2·4) = 4
+	expected := NIGParameters{Mu: 4, Kappa: 4, Alpha: 2, Beta: 4}
+	if post != expected {
+		t.Errorf("expected %+v, got %+v", expected, post)
+	}
+}
+
+// TestDistributionQuantiles - t分布とガンマ分布の分位点のテスト
+func TestDistributionQuantiles(t *testing.T) {
+	if q := studentTQuantile(0.975, 10); math.Abs(q-2.228139) > 1e-5 {
+		t.Errorf("expected t_10 0.975 quantile 2.228139, got %.6f", q)
+	}
+	if q := studentTQuantile(0.025, 10); math.Abs(q+2.228139) > 1e-5 {
+		t.Errorf("expected t_10 0.025 quantile -2.228139, got %.6f", q)
+	}
+	if q := gammaQuantile(0.5, 1); math.Abs(q-math.Ln2) > 1e-9 {
+		t.Errorf("expected Exp(1) median ln 2, got %.9f", q)
+	}
+}
+
+// TestGibbsNormalInvalidParams - 無効なパラメータのテスト
+func TestGibbsNormalInvalidParams(t *testing.T) {
+	setupTestData()
+
+	testCases := []struct {
+		name  string
+		query string
+	}{
+		{"invalid_mu0", "mu0=x"},
+		{"invalid_kappa0", "kappa0=0"},
+		{"invalid_a0", "a0=-1"},
+		{"invalid_b0", "b0=0"},
+		{"invalid_level", "level=2"},
+		{"invalid_draws", "draws=50"},
+		{"invalid_burn_in", "burn_in=-5"},
+		{"invalid_thin", "thin=0"},
+		{"invalid_seed", "seed=x"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "/api/gibbs/normal?"+tc.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(getGibbsNormal)
+			handler.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != http.StatusBadRequest {
+				t.Errorf("handler returned wrong status code: got %v want %v",
+					status, http.StatusBadRequest)
+			}
+		})
+	}
+}
+
+// TestGibbsNormalEmptyData - データが空の場合のテスト
+func TestGibbsNormalEmptyData(t *testing.T) {
+	grades = []Grade{}
+
+	req, err := http.NewRequest("GET", "/api/gibbs/normal", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(getGibbsNormal)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusInternalServerError {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"sort"
+)
+
+// Hierarchical Beta-Binomial model for item correct rates:
+//
+//	s_j ~ Binomial(n, θ_j),  θ_j ~ Beta(α, β),  α = μκ,  β = (1-μ)κ
+//
+// with the hyperprior p(α, β) ∝ (α+β)^(-5/2) (Gelman et al., BDA3 §5.3), which on the
+// (logit μ, log κ) scale is proportional to μ(1-μ)κ^(-1/2). Empirical Bayes maximizes the
+// marginal likelihood over (μ, κ); MCMC samples (logit μ, log κ) with θ integrated out and then
+// draws each θ_j from its conjugate Beta(α + s_j, β + n - s_j) conditional.
+
+// hierarchicalLogKappaBounds keeps κ within [e^-5, e^12] so the optimizer and sampler stay finite
+var hierarchicalLogKappaBounds = [2]float64{-5, 12}
+
+// HierarchicalItemEstimate represents the shrunken correct rate of one item
+type HierarchicalItemEstimate struct {
+	Question  string  `json:"question"`
+	Correct   int     `json:"correct"`
+	N         int     `json:"n"`
+	RawRate   float64 `json:"raw_rate"`
+	Estimate  float64 `json:"estimate"`
+	Lower     float64 `json:"lower"`
+	Upper     float64 `json:"upper"`
+	Shrinkage float64 `json:"shrinkage"`
+}
+
+// HyperparameterEstimate represents an estimated population parameter
+type HyperparameterEstimate struct {
+	Name     string   `json:"name"`
+	Estimate float64  `json:"estimate"`
+	Lower    *float64 `json:"lower,omitempty"`
+	Upper    *float64 `json:"upper,omitempty"`
+}
+
+// HierarchicalItemsResponse represents the fitted hierarchical model
+type HierarchicalItemsResponse struct {
+	Method                string                     `json:"method"`
+	N                     int                        `json:"n"`
+	Level                 float64                    `json:"level"`
+	Items                 []HierarchicalItemEstimate `json:"items"`
+	Hyperparameters       []HyperparameterEstimate   `json:"hyperparameters"`
+	LogMarginalLikelihood *float64                   `json:"log_marginal_likelihood,omitempty"`
+	Converged             bool                       `json:"converged"`
+	AcceptanceRate        *float64                   `json:"acceptance_rate,omitempty"`
+	Draws                 int                        `json:"draws,omitempty"`
+	BurnIn                int                        `json:"burn_in,omitempty"`
+	Seed                  int64                      `json:"seed,omitempty"`
+	Message               string                     `json:"message,omitempty"`
+}
+
+// itemBetaBinomial holds the per-item counts the hierarchical model is fitted to
+type itemBetaBinomial struct {
+	correct []int
+	n       int
+}
+
+// logMarginal returns Σ_j log p(s_j | α, β), dropping the binomial coefficients
+func (m itemBetaBinomial) logMarginal(alpha, beta float64) float64 {
+	var ll float64
+	for _, s := range m.correct {
+		ll += lbeta(alpha+float64(s), beta+float64(m.n-s)) - lbeta(alpha, beta)
+	}
+	return ll
+}
+
+// hyperparameters maps (logit μ, log κ) to (α, β)
+func hyperparameters(x []float64) (float64, float64) {
+	mu := sigmoid(x[0])
+	kappa := math.Exp(x[1])
+	return mu * kappa, (1 - mu) * kappa
+}
+
+// logPosterior returns the log posterior density of (logit μ, log κ), or -Inf outside the bounds
+func (m itemBetaBinomial) logPosterior(x []float64) float64 {
+	if x[1] < hierarchicalLogKappaBounds[0] || x[1] > hierarchicalLogKappaBounds[1] {
+		return math.Inf(-1)
+	}
+	alpha, beta := hyperparameters(x)
+	return m.logMarginal(alpha, beta) + logSigmoid(x[0]) + logSigmoid(-x[0]) - 0.5*x[1]
+}
+
+// initialHyperparameters returns a method-of-moments starting point on the (logit μ, log κ) scale
+func (m itemBetaBinomial) initialHyperparameters() []float64 {
+	rates := make([]float64, len(m.correct))
+	for j, s := range m.correct {
+		// Keep the start away from 0 and 1 so logit μ is finite
+		rates[j] = (float64(s) + 0.5) / (float64(m.n) + 1)
+	}
+	mean, sd := meanAndSD(rates)
+	kappa := 10.0
+	if variance := sd * sd; variance > 0 && variance < mean*(1-mean) {
+		kappa = mean*(1-mean)/variance - 1
+	}
+	logKappa := math.Max(hierarchicalLogKappaBounds[0], math.Min(hierarchicalLogKappaBounds[1], math.Log(kappa)))
+	return []float64{math.Log(mean / (1 - mean)), logKappa}
+}
+
+// newItemEstimate fills the raw counts of an item estimate
+func (m itemBetaBinomial) newItemEstimate(j int, label string) HierarchicalItemEstimate {
+	return HierarchicalItemEstimate{
+		Question: label,
+		Correct:  m.correct[j],
+		N:        m.n,
+		RawRate:  float64(m.correct[j]) / float64(m.n),
+	}
+}
+
+// fitHierarchicalEmpiricalBayes plugs the marginal maximum likelihood (α̂, β̂) into the conjugate
+// Beta posteriors of each item
+func fitHierarchicalEmpiricalBayes(m itemBetaBinomial, level float64) HierarchicalItemsResponse {
+	objective := func(x []float64) float64 {
+		if x[1] < hierarchicalLogKappaBounds[0] || x[1] > hierarchicalLogKappaBounds[1] {
+			return math.Inf(1)
+		}
+		alpha, beta := hyperparameters(x)
+		return -m.logMarginal(alpha, beta)
+	}
+	x, negLL, converged := nelderMead(objective, m.initialHyperparameters(), 0.5, 2000)
+	alpha, beta := hyperparameters(x)
+	kappa := alpha + beta
+
+	logMarginal := -negLL
+	response := HierarchicalItemsResponse{
+		Method:                "empirical-bayes",
+		N:                     m.n,
+		Level:                 level,
+		LogMarginalLikelihood: &logMarginal,
+		Converged:             converged,
+		Hyperparameters: []HyperparameterEstimate{
+			{Name: "mu", Estimate: alpha / kappa},
+			{Name: "kappa", Estimate: kappa},
+			{Name: "alpha", Estimate: alpha},
+			{Name: "beta", Estimate: beta},
+		},
+	}
+	if hierarchicalLogKappaBounds[1]-x[1] < 1e-3 {
+		response.Message = "kappa reached its upper bound: items show no extra-binomial variation, so estimates are fully pooled"
+	}
+
+	for j, label := range questionLabels() {
+		estimate := m.newItemEstimate(j, label)
+		a := alpha + float64(m.correct[j])
+		b := beta + float64(m.n-m.correct[j])
+		estimate.Estimate = a / (a + b)
+		estimate.Lower, estimate.Upper = betaCredibleInterval(a, b, level)
+		estimate.Shrinkage = kappa / (kappa + float64(m.n))
+		response.Items = append(response.Items, estimate)
+	}
+	return response
+}
+
+// fitHierarchicalMCMC samples the full posterior with random-walk Metropolis on
+// (logit μ, log κ), using the Laplace covariance at the posterior mode as the proposal
+func fitHierarchicalMCMC(m itemBetaBinomial, level float64, draws, burnIn int, seed int64) HierarchicalItemsResponse {
+	negLogPosterior := func(x []float64) float64 { return -m.logPosterior(x) }
+	mode, _, converged := nelderMead(negLogPosterior, m.initialHyperparameters(), 0.5, 2000)
+
+	response := HierarchicalItemsResponse{
+		Method:          "mcmc",
+		N:               m.n,
+		Level:           level,
+		Converged:       converged,
+		Draws:           draws,
+		BurnIn:          burnIn,
+		Seed:            seed,
+		Hyperparameters: []HyperparameterEstimate{},
+		Items:           []HierarchicalItemEstimate{},
+	}
+
+	// Fall back to a diagonal proposal when the mode sits on a bound or the Hessian is not SPD
+	proposal := [][]float64{{0.1, 0}, {0, 0.5}}
+	if covariance, err := invertSPD(numericalHessian(negLogPosterior, mode, 1e-3)); err == nil {
+		proposal = covariance
+	}
+	l, err := choleskyDecompose(proposal)
+	if err != nil {
+		response.Message = err.Error()
+		return response
+	}
+	scale := 2.38 / math.Sqrt(2)
+	rng := rand.New(rand.NewSource(seed))
+
+	current := append([]float64(nil), mode...)
+	currentLP := m.logPosterior(current)
+	accepted := 0
+	hyperDraws := make([][]float64, 4)
+	thetaDraws := make([][]float64, len(m.correct))
+	shrinkage := 0.0
+
+	for iter := 0; iter < burnIn+draws; iter++ {
+		step := matVec(l, []float64{rng.NormFloat64(), rng.NormFloat64()})
+		candidate := []float64{current[0] + scale*step[0], current[1] + scale*step[1]}
+		candidateLP := m.logPosterior(candidate)
+		if math.Log(rng.Float64()) < candidateLP-currentLP {
+			current, currentLP = candidate, candidateLP
+			if iter >= burnIn {
+				accepted++
+			}
+		}
+		if iter < burnIn {
+			continue
+		}
+
+		alpha, beta := hyperparameters(current)
+		kappa := alpha + beta
+		for k, v := range []float64{alpha / kappa, kappa, alpha, beta} {
+			hyperDraws[k] = append(hyperDraws[k], v)
+		}
+		shrinkage += kappa / (kappa + float64(m.n))
+		for j, s := range m.correct {
+			thetaDraws[j] = append(thetaDraws[j], sampleBeta(rng, alpha+float64(s), beta+float64(m.n-s)))
+		}
+	}
+
+	acceptanceRate := float64(accepted) / float64(draws)
+	response.AcceptanceRate = &acceptanceRate
+	lowerP := (1 - level) / 2
+
+	for k, name := range []string{"mu", "kappa", "alpha", "beta"} {
+		mean, lower, upper := posteriorSummary(hyperDraws[k], lowerP)
+		response.Hyperparameters = append(response.Hyperparameters, HyperparameterEstimate{
+			Name: name, Estimate: mean, Lower: &lower, Upper: &upper,
+		})
+	}
+	for j, label := range questionLabels() {
+		estimate := m.newItemEstimate(j, label)
+		estimate.Estimate, estimate.Lower, estimate.Upper = posteriorSummary(thetaDraws[j], lowerP)
+		estimate.Shrinkage = shrinkage / float64(draws)
+		response.Items = append(response.Items, estimate)
+	}
+	return response
+}
+
+// posteriorSummary returns the mean and equal-tailed interval of MCMC draws
+func posteriorSummary(draws []float64, lowerP float64) (float64, float64, float64) {
+	mean, _ := meanAndSD(draws)
+	sorted := append([]float64(nil), draws...)
+	sort.Float64s(sorted)
+	return mean, quantile(sorted, lowerP), quantile(sorted, 1-lowerP)
+}
+
+// Handler: Get hierarchical item estimates
+// Shrinks each item's correct rate towards a shared Beta(α, β) population prior, estimated by
+// empirical Bayes (default) or full Bayesian MCMC
+func getHierarchicalItems(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	method := r.URL.Query().Get("method")
+	switch method {
+	case "":
+		method = "empirical-bayes"
+	case "empirical-bayes", "mcmc":
+	default:
+		http.Error(w, "Invalid 'method' parameter (must be empirical-bayes or mcmc)", http.StatusBadRequest)
+		return
+	}
+
+	level, err := floatParam(r, "level", 0.95)
+	if err != nil || level <= 0 || level >= 1 {
+		http.Error(w, "Invalid 'level' parameter (must be between 0 and 1)", http.StatusBadRequest)
+		return
+	}
+	draws, err := intParam(r, "draws", 4000)
+	if err != nil || draws < 100 || draws > 100000 {
+		http.Error(w, "Invalid 'draws' parameter (must be between 100 and 100000)", http.StatusBadRequest)
+		return
+	}
+	burnIn, err := intParam(r, "burn_in", 1000)
+	if err != nil || burnIn < 0 || burnIn > 100000 {
+		http.Error(w, "Invalid 'burn_in' parameter (must be between 0 and 100000)", http.StatusBadRequest)
+		return
+	}
+	seed, err := intParam(r, "seed", 1)
+	if err != nil {
+		http.Error(w, "Invalid 'seed' parameter", http.StatusBadRequest)
+		return
+	}
+
+	if len(grades) == 0 {
+		http.Error(w, "No data available", http.StatusInternalServerError)
+		return
+	}
+
+	model := itemBetaBinomial{correct: make([]int, numQuestions), n: len(grades)}
+	for _, g := range grades {
+		for j := range model.correct {
+			value := getQuestionValue(g, j+1)
+			if value != 0 && value != 1 {
+				http.Error(w, fmt.Sprintf("student %d has non-binary Q%d", g.StudentID, j+1), http.StatusInternalServerError)
+				return
+			}
+			model.correct[j] += value
+		}
+	}
+
+	var response HierarchicalItemsResponse
+	if method == "mcmc" {
+		response = fitHierarchicalMCMC(model, level, draws, burnIn, int64(seed))
+	} else {
+		response = fitHierarchicalEmpiricalBayes(model, level)
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// setupItemCountTestData - 問題jの正答者数がcounts[j]になる20人のデータ
+func setupItemCountTestData(counts [numQuestions]int) {
+	grades = []Grade{}
+	for i := 0; i < 20; i++ {
+		g := Grade{StudentID: i + 1}
+		values := []*int{&g.Q1, &g.Q2, &g.Q3, &g.Q4, &g.Q5, &g.Q6, &g.Q7, &g.Q8, &g.Q9, &g.Q10}
+		for j, count := range counts {
+			if i < count {
+				*values[j] = 1
+				g.Total++
+			}
+		}
+		grades = append(grades, g)
+	}
+}
+
+// TestHierarchicalItemsEmpiricalBayes - 経験ベイズによる縮小推定のテスト
+func TestHierarchicalItemsEmpiricalBayes(t *testing.T) {
+	setupItemCountTestData([numQuestions]int{4, 6, 8, 9, 10, 10, 11, 12, 14, 16})
+
+	var result HierarchicalItemsResponse
+	serveJSON(t, getHierarchicalItems, "GET", "/api/hierarchical-items", "", &result)
+
+	if result.Method != "empirical-bayes" || !result.Converged || result.LogMarginalLikelihood == nil {
+		t.Fatalf("expected a converged empirical Bayes fit, got %+v", result)
+	}
+	mu := result.Hyperparameters[0].Estimate
+	if math.Abs(mu-0.5) > 0.05 {
+		t.Errorf("expected mu near the mean raw rate 0.5, got %.4f", mu)
+	}
+
+	// 推定値は素の正答率とμの間に縮小される
+	for _, item := range result.Items {
+		if (item.Estimate-item.RawRate)*(mu-item.RawRate) < 0 || math.Abs(item.Estimate-mu) > math.Abs(item.RawRate-mu)+1e-9 {
+			t.Errorf("%s: estimate %.4f is not shrunk from %.4f towards %.4f", item.Question, item.Estimate, item.RawRate, mu)
+		}
+		if item.Lower > item.Estimate || item.Upper < item.Estimate {
+			t.Errorf("%s: interval [%.4f, %.4f] excludes %.4f", item.Question, item.Lower, item.Upper, item.Estimate)
+		}
+		if item.Shrinkage <= 0 || item.Shrinkage >= 1 {
+			t.Errorf("%s: shrinkage %.4f outside (0, 1)", item.Question, item.Shrinkage)
+		}
+	}
+}
+
+// TestHierarchicalItemsFullPooling - 問題間にばらつきがない場合のテスト
+func TestHierarchicalItemsFullPooling(t *testing.T) {
+	setupItemCountTestData([numQuestions]int{10, 10, 10, 10, 10, 10, 10, 10, 10, 10})
+
+	var result HierarchicalItemsResponse
+	serveJSON(t, getHierarchicalItems, "GET", "/api/hierarchical-items?method=empirical-bayes", "", &result)
+
+	if result.Message == "" {
+		t.Error("expected a message about kappa reaching its bound")
+	}
+	for _, item := range result.Items {
+		if math.Abs(item.Estimate-0.5) > 1e-3 || item.Shrinkage < 0.99 {
+			t.Errorf("%s: expected full pooling at 0.5, got %.4f (shrinkage %.4f)", item.Question, item.Estimate, item.Shrinkage)
+		}
+	}
+}
+
+// TestHierarchicalItemsMCMC - MCMCによる推定のテスト
+func TestHierarchicalItemsMCMC(t *testing.T) {
+	setupItemCountTestData([numQuestions]int{4, 6, 8, 9, 10, 10, 11, 12, 14, 16})
+
+	var eb HierarchicalItemsResponse
+	serveJSON(t, getHierarchicalItems, "GET", "/api/hierarchical-items?method=empirical-bayes", "", &eb)
+	var mcmc HierarchicalItemsResponse
+	serveJSON(t, getHierarchicalItems, "GET", "/api/hierarchical-items?method=mcmc&draws=2000&burn_in=500&seed=7", "", &mcmc)
+
+	if mcmc.AcceptanceRate == nil || *mcmc.AcceptanceRate < 0.1 || *mcmc.AcceptanceRate > 0.9 {
+		t.Errorf("unexpected acceptance rate %v", mcmc.AcceptanceRate)
+	}
+	if len(mcmc.Hyperparameters) != 4 || mcmc.Hyperparameters[0].Lower == nil {
+		t.Fatalf("expected hyperparameters with intervals, got %+v", mcmc.Hyperparameters)
+	}
+	for j, item := range mcmc.Items {
+		if math.Abs(item.Estimate-eb.Items[j].Estimate) > 0.05 {
+			t.Errorf("%s: MCMC estimate %.4f far from empirical Bayes %.4f", item.Question, item.Estimate, eb.Items[j].Estimate)
+		}
+		// 完全ベイズの区間は超パラメータの不確実性を含むので広めになる
+		if item.Upper-item.Lower < 0.9*(eb.Items[j].Upper-eb.Items[j].Lower) {
+			t.Errorf("%s: MCMC interval narrower than empirical Bayes", item.Question)
+		}
+	}
+}
+
+// TestNelderMead - Rosenbrock関数の最小化のテスト
+func TestNelderMead(t *testing.T) {
+	rosenbrock := func(x []float64) float64 {
+		return (1-x[0])*(1-x[0]) + 100*(x[1]-x[0]*x[0])*(x[1]-x[0]*x[0])
+	}
+	x, value, converged := nelderMead(rosenbrock, []float64{-1.2, 1}, 0.5, 5000)
+	if !converged || math.Abs(x[0]-1) > 1e-3 || math.Abs(x[1]-1) > 1e-3 || value > 1e-6 {
+		t.Errorf("expected minimum at (1, 1), got %v (value %g, converged %v)", x, value, converged)
+	}
+}
+
+// TestHierarchicalItemsInvalidParams - 無効なパラメータのテスト
+func TestHierarchicalItemsInvalidParams(t *testing.T) {
+	setupTestData()
+
+	testCases := []struct {
+		name  string
+		query string
+	}{
+		{"invalid_method", "method=gibbs"},
+		{"invalid_level", "level=1.5"},
+		{"invalid_draws", "method=mcmc&draws=10"},
+		{"invalid_burn_in", "method=mcmc&burn_in=-1"},
+		{"invalid_seed", "seed=x"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "/api/hierarchical-items?"+tc.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(getHierarchicalItems)
+			handler.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != http.StatusBadRequest {
+				t.Errorf("handler returned wrong status code: got %v want %v",
+					status, http.StatusBadRequest)
+			}
+		})
+	}
+}
+
+// TestHierarchicalItemsEmptyData - データが空の場合のテスト
+func TestHierarchicalItemsEmptyData(t *testing.T) {
+	grades = []Grade{}
+
+	req, err := http.NewRequest("GET", "/api/hierarchical-items", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(getHierarchicalItems)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusInternalServerError {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusInternalServerError)
+	}
+}
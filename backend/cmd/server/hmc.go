@@ -0,0 +1,588 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+// Gradient-based MCMC for models with many continuous parameters.
+//
+// A model implements gradientModel; sampleGradientModel runs several chains in parallel with
+// either the No-U-Turn Sampler (Hoffman & Gelman 2014, Algorithm 6) or static HMC with a fixed
+// number of leapfrog steps. Warmup tunes the step size by dual averaging and a diagonal inverse
+// metric from the middle warmup window, following Stan's windowed adaptation.
+
+// gradientModel is a differentiable log density over an unconstrained parameter vector
+type gradientModel interface {
+	// dimension returns the number of parameters
+	dimension() int
+	// logDensityGradient returns log p(x) up to a constant and writes ∇ log p(x) into grad.
+	// It must be safe to call concurrently with distinct grad slices.
+	logDensityGradient(x, grad []float64) float64
+}
+
+// divergenceThreshold is the energy error above which a trajectory is declared divergent
+const divergenceThreshold = 1000.0
+
+// samplerConfig configures sampleGradientModel
+type samplerConfig struct {
+	method        string // "nuts" or "hmc"
+	chains        int
+	warmup        int
+	draws         int
+	seed          int64
+	targetAccept  float64
+	maxTreeDepth  int // NUTS only
+	leapfrogSteps int // static HMC only
+}
+
+// maxLeapfrogSteps returns the most gradient evaluations one iteration can take: the fixed
+// trajectory length for static HMC, or 2^max_tree_depth for NUTS
+func (c samplerConfig) maxLeapfrogSteps() int {
+	if c.method == "hmc" {
+		return c.leapfrogSteps
+	}
+	return 1 << c.maxTreeDepth
+}
+
+// chainResult holds the post-warmup output of one chain
+type chainResult struct {
+	draws            [][]float64 // draws × dimension
+	stepSize         float64
+	acceptStat       float64 // mean over draws
+	divergences      int
+	maxTreeDepthHits int
+	meanTreeDepth    float64
+	leapfrogSteps    int
+}
+
+// SamplerDiagnostics represents the run-level diagnostics of a gradient-based sampler
+type SamplerDiagnostics struct {
+	Method           string    `json:"method"`
+	Chains           int       `json:"chains"`
+	Warmup           int       `json:"warmup"`
+	Draws            int       `json:"draws"`
+	Seed             int64     `json:"seed"`
+	StepSizes        []float64 `json:"step_sizes"`
+	MeanAcceptStat   float64   `json:"mean_accept_stat"`
+	Divergences      int       `json:"divergences"`
+	MaxTreeDepthHits int       `json:"max_tree_depth_hits"`
+	MeanTreeDepth    *float64  `json:"mean_tree_depth,omitempty"`
+	LeapfrogSteps    int       `json:"leapfrog_steps"`
+	MaxRHat          float64   `json:"max_rhat"`
+	MinESS           float64   `json:"min_ess"`
+	Warnings         []string  `json:"warnings"`
+}
+
+// ParameterSummary represents the posterior summary of one parameter across chains
+type ParameterSummary struct {
+	Mean  float64 `json:"mean"`
+	SD    float64 `json:"sd"`
+	Lower float64 `json:"lower"`
+	Upper float64 `json:"upper"`
+	ESS   float64 `json:"ess"`
+	RHat  float64 `json:"rhat"`
+}
+
+// hmcChain holds the state of one chain
+type hmcChain struct {
+	model      gradientModel
+	config     samplerConfig
+	rng        *rand.Rand
+	invMetric  []float64 // diagonal inverse mass matrix
+	stepSize   float64
+	divergent  bool // set by the last transition
+	treeDepth  int
+	depthLimit bool
+	leapfrogs  int
+}
+
+// hamiltonian returns log p(x) - ½ rᵀ M⁻¹ r
+func (c *hmcChain) hamiltonian(logp float64, r []float64) float64 {
+	var kinetic float64
+	for a, v := range r {
+		kinetic += v * v * c.invMetric[a]
+	}
+	return logp - 0.5*kinetic
+}
+
+// leapfrog advances (x, r) by one step of size eps in place and returns the new log density
+func (c *hmcChain) leapfrog(x, r, grad []float64, eps float64) float64 {
+	c.leapfrogs++
+	for a := range r {
+		r[a] += 0.5 * eps * grad[a]
+	}
+	for a := range x {
+		x[a] += eps * c.invMetric[a] * r[a]
+	}
+	logp := c.model.logDensityGradient(x, grad)
+	for a := range r {
+		r[a] += 0.5 * eps * grad[a]
+	}
+	return logp
+}
+
+// sampleMomentum draws r ~ N(0, M)
+func (c *hmcChain) sampleMomentum() []float64 {
+	r := make([]float64, len(c.invMetric))
+	for a := range r {
+		r[a] = c.rng.NormFloat64() / math.Sqrt(c.invMetric[a])
+	}
+	return r
+}
+
+// findReasonableStepSize doubles or halves eps until a single leapfrog step's acceptance
+// probability crosses ½ (Hoffman & Gelman 2014, Algorithm 4)
+func (c *hmcChain) findReasonableStepSize(x []float64) float64 {
+	eps := 1.0
+	grad := make([]float64, len(x))
+	logp := c.model.logDensityGradient(x, grad)
+	r := c.sampleMomentum()
+	h0 := c.hamiltonian(logp, r)
+
+	logAccept := func() float64 {
+		x1 := append([]float64(nil), x...)
+		r1 := append([]float64(nil), r...)
+		g1 := append([]float64(nil), grad...)
+		logp1 := c.leapfrog(x1, r1, g1, eps)
+		h := c.hamiltonian(logp1, r1) - h0
+		if math.IsNaN(h) {
+			return math.Inf(-1)
+		}
+		return h
+	}
+
+	direction := -1.0
+	if logAccept() > math.Log(0.5) {
+		direction = 1.0
+	}
+	for iter := 0; iter < 100; iter++ {
+		la := logAccept()
+		if direction > 0 && !(la > math.Log(0.5)) || direction < 0 && !(la < math.Log(0.5)) {
+			break
+		}
+		eps *= math.Pow(2, direction)
+	}
+	return eps
+}
+
+// dualAveraging tunes the step size towards a target acceptance statistic (Nesterov 2009,
+// as adapted by Hoffman & Gelman 2014)
+type dualAveraging struct {
+	mu, target              float64
+	hBar, logEps, logEpsBar float64
+	t                       int
+}
+
+func newDualAveraging(eps, target float64) *dualAveraging {
+	return &dualAveraging{mu: math.Log(10 * eps), target: target, logEps: math.Log(eps)}
+}
+
+func (d *dualAveraging) update(acceptStat float64) float64 {
+	const gamma, t0, kappa = 0.05, 10.0, 0.75
+	d.t++
+	t := float64(d.t)
+	d.hBar = (1-1/(t+t0))*d.hBar + (d.target-acceptStat)/(t+t0)
+	d.logEps = d.mu - math.Sqrt(t)/gamma*d.hBar
+	weight := math.Pow(t, -kappa)
+	d.logEpsBar = weight*d.logEps + (1-weight)*d.logEpsBar
+	return math.Exp(d.logEps)
+}
+
+func (d *dualAveraging) final() float64 {
+	return math.Exp(d.logEpsBar)
+}
+
+// nutsTree is the result of building a subtree
+type nutsTree struct {
+	xMinus, rMinus, gradMinus []float64
+	xPlus, rPlus, gradPlus    []float64
+	xProposal, gradProposal   []float64
+	logpProposal              float64
+	n                         int
+	ok                        bool
+	alpha                     float64
+	nAlpha                    int
+}
+
+// noUTurn reports whether the trajectory between the two ends has not started to double back
+func (c *hmcChain) noUTurn(xMinus, xPlus, rMinus, rPlus []float64) bool {
+	var dotMinus, dotPlus float64
+	for a := range xMinus {
+		dx := xPlus[a] - xMinus[a]
+		dotMinus += dx * c.invMetric[a] * rMinus[a]
+		dotPlus += dx * c.invMetric[a] * rPlus[a]
+	}
+	return dotMinus >= 0 && dotPlus >= 0
+}
+
+// buildTree recursively builds a subtree of depth j in direction v (Algorithm 6)
+func (c *hmcChain) buildTree(x, r, grad []float64, logU float64, v float64, j int, eps, h0 float64) nutsTree {
+	if j == 0 {
+		x1 := append([]float64(nil), x...)
+		r1 := append([]float64(nil), r...)
+		g1 := append([]float64(nil), grad...)
+		logp := c.leapfrog(x1, r1, g1, v*eps)
+		h := c.hamiltonian(logp, r1)
+		if math.IsNaN(h) {
+			h = math.Inf(-1)
+		}
+		n := 0
+		if logU <= h {
+			n = 1
+		}
+		ok := h > logU-divergenceThreshold
+		if !ok {
+			c.divergent = true
+		}
+		return nutsTree{
+			xMinus: x1, rMinus: r1, gradMinus: g1,
+			xPlus: x1, rPlus: r1, gradPlus: g1,
+			xProposal: x1, gradProposal: g1, logpProposal: logp,
+			n: n, ok: ok,
+			alpha: math.Min(1, math.Exp(h-h0)), nAlpha: 1,
+		}
+	}
+
+	tree := c.buildTree(x, r, grad, logU, v, j-1, eps, h0)
+	if !tree.ok {
+		return tree
+	}
+	var other nutsTree
+	if v < 0 {
+		other = c.buildTree(tree.xMinus, tree.rMinus, tree.gradMinus, logU, v, j-1, eps, h0)
+		tree.xMinus, tree.rMinus, tree.gradMinus = other.xMinus, other.rMinus, other.gradMinus
+	} else {
+		other = c.buildTree(tree.xPlus, tree.rPlus, tree.gradPlus, logU, v, j-1, eps, h0)
+		tree.xPlus, tree.rPlus, tree.gradPlus = other.xPlus, other.rPlus, other.gradPlus
+	}
+	if total := tree.n + other.n; total > 0 && c.rng.Float64() < float64(other.n)/float64(total) {
+		tree.xProposal, tree.gradProposal, tree.logpProposal = other.xProposal, other.gradProposal, other.logpProposal
+	}
+	tree.alpha += other.alpha
+	tree.nAlpha += other.nAlpha
+	tree.ok = other.ok && c.noUTurn(tree.xMinus, tree.xPlus, tree.rMinus, tree.rPlus)
+	tree.n += other.n
+	return tree
+}
+
+// nutsTransition performs one NUTS transition from x and returns the new state and the
+// acceptance statistic used for adaptation
+func (c *hmcChain) nutsTransition(x, grad []float64, logp float64) ([]float64, []float64, float64, float64) {
+	r0 := c.sampleMomentum()
+	h0 := c.hamiltonian(logp, r0)
+	logU := h0 + math.Log(c.rng.Float64())
+
+	tree := nutsTree{
+		xMinus: x, rMinus: r0, gradMinus: grad,
+		xPlus: x, rPlus: r0, gradPlus: grad,
+		n: 1, ok: true,
+	}
+	current, currentGrad, currentLogp := x, grad, logp
+	c.divergent = false
+	c.depthLimit = false
+	depth := 0
+	var alpha float64
+	var nAlpha int
+
+	for tree.ok {
+		if depth >= c.config.maxTreeDepth {
+			c.depthLimit = true
+			break
+		}
+		v := 1.0
+		if c.rng.Float64() < 0.5 {
+			v = -1.0
+		}
+		var sub nutsTree
+		if v < 0 {
+			sub = c.buildTree(tree.xMinus, tree.rMinus, tree.gradMinus, logU, v, depth, c.stepSize, h0)
+			tree.xMinus, tree.rMinus, tree.gradMinus = sub.xMinus, sub.rMinus, sub.gradMinus
+		} else {
+			sub = c.buildTree(tree.xPlus, tree.rPlus, tree.gradPlus, logU, v, depth, c.stepSize, h0)
+			tree.xPlus, tree.rPlus, tree.gradPlus = sub.xPlus, sub.rPlus, sub.gradPlus
+		}
+		alpha, nAlpha = sub.alpha, sub.nAlpha
+		if sub.ok && c.rng.Float64() < float64(sub.n)/float64(tree.n) {
+			current, currentGrad, currentLogp = sub.xProposal, sub.gradProposal, sub.logpProposal
+		}
+		tree.n += sub.n
+		tree.ok = sub.ok && c.noUTurn(tree.xMinus, tree.xPlus, tree.rMinus, tree.rPlus)
+		depth++
+	}
+	c.treeDepth = depth
+
+	acceptStat := 0.0
+	if nAlpha > 0 {
+		acceptStat = alpha / float64(nAlpha)
+	}
+	return current, currentGrad, currentLogp, acceptStat
+}
+
+// hmcTransition performs one static HMC transition with a fixed number of leapfrog steps
+func (c *hmcChain) hmcTransition(x, grad []float64, logp float64) ([]float64, []float64, float64, float64) {
+	r := c.sampleMomentum()
+	h0 := c.hamiltonian(logp, r)
+	x1 := append([]float64(nil), x...)
+	g1 := append([]float64(nil), grad...)
+	logp1 := logp
+	c.divergent = false
+	for step := 0; step < c.config.leapfrogSteps; step++ {
+		logp1 = c.leapfrog(x1, r, g1, c.stepSize)
+		if h := c.hamiltonian(logp1, r); math.IsNaN(h) || h0-h > divergenceThreshold {
+			c.divergent = true
+			return x, grad, logp, 0
+		}
+	}
+	acceptStat := math.Min(1, math.Exp(c.hamiltonian(logp1, r)-h0))
+	if c.rng.Float64() < acceptStat {
+		return x1, g1, logp1, acceptStat
+	}
+	return x, grad, logp, acceptStat
+}
+
+// warmupWindows splits warmup into an initial fast window, a slow window used to estimate
+// the inverse metric, and a terminal fast window (15% / 75% / 10%)
+func warmupWindows(warmup int) (int, int) {
+	if warmup < 20 {
+		return warmup, warmup
+	}
+	initial := warmup * 15 / 100
+	terminal := warmup * 10 / 100
+	return initial, warmup - terminal
+}
+
+// run executes warmup and sampling for one chain from a random initial point in (-1, 1)
+func (c *hmcChain) run() chainResult {
+	d := c.model.dimension()
+	c.invMetric = make([]float64, d)
+	for a := range c.invMetric {
+		c.invMetric[a] = 1
+	}
+	x := make([]float64, d)
+	for a := range x {
+		x[a] = 2*c.rng.Float64() - 1
+	}
+	grad := make([]float64, d)
+	logp := c.model.logDensityGradient(x, grad)
+
+	transition := c.nutsTransition
+	if c.config.method == "hmc" {
+		transition = c.hmcTransition
+	}
+
+	c.stepSize = c.findReasonableStepSize(x)
+	adapter := newDualAveraging(c.stepSize, c.config.targetAccept)
+	slowStart, slowEnd := warmupWindows(c.config.warmup)
+	var windowDraws [][]float64
+
+	for iter := 0; iter < c.config.warmup; iter++ {
+		var acceptStat float64
+		x, grad, logp, acceptStat = transition(x, grad, logp)
+		c.stepSize = adapter.update(acceptStat)
+
+		if iter >= slowStart && iter < slowEnd {
+			windowDraws = append(windowDraws, append([]float64(nil), x...))
+		}
+		if iter == slowEnd-1 && len(windowDraws) >= 10 {
+			// Regularized sample variance, shrunk towards 1e-3 as in Stan
+			n := float64(len(windowDraws))
+			column := make([]float64, len(windowDraws))
+			for a := range c.invMetric {
+				for k, draw := range windowDraws {
+					column[k] = draw[a]
+				}
+				_, sd := meanAndSD(column)
+				c.invMetric[a] = (n/(n+5))*sd*sd + 1e-3*(5/(n+5))
+			}
+			c.stepSize = c.findReasonableStepSize(x)
+			adapter = newDualAveraging(c.stepSize, c.config.targetAccept)
+		}
+	}
+	if c.config.warmup > 0 {
+		c.stepSize = adapter.final()
+	}
+
+	result := chainResult{stepSize: c.stepSize}
+	c.leapfrogs = 0
+	var depthSum int
+	for iter := 0; iter < c.config.draws; iter++ {
+		var acceptStat float64
+		x, grad, logp, acceptStat = transition(x, grad, logp)
+		result.draws = append(result.draws, append([]float64(nil), x...))
+		result.acceptStat += acceptStat / float64(c.config.draws)
+		if c.divergent {
+			result.divergences++
+		}
+		if c.depthLimit {
+			result.maxTreeDepthHits++
+		}
+		depthSum += c.treeDepth
+	}
+	result.meanTreeDepth = float64(depthSum) / float64(c.config.draws)
+	result.leapfrogSteps = c.leapfrogs
+	return result
+}
+
+// sampleGradientModel runs config.chains chains in parallel and returns their results in order.
+// Chain k is seeded with config.seed + k, so output does not depend on goroutine scheduling.
+func sampleGradientModel(model gradientModel, config samplerConfig) []chainResult {
+	results := make([]chainResult, config.chains)
+	var wg sync.WaitGroup
+	for k := 0; k < config.chains; k++ {
+		wg.Add(1)
+		go func(k int) {
+			defer wg.Done()
+			chain := &hmcChain{
+				model:  model,
+				config: config,
+				rng:    rand.New(rand.NewSource(config.seed + int64(k))),
+			}
+			results[k] = chain.run()
+		}(k)
+	}
+	wg.Wait()
+	return results
+}
+
+// summarizeSampler aggregates the diagnostics of all chains. It reports R-hat and ESS extremes
+// over the supplied parameter summaries and adds warnings for common problems.
+func summarizeSampler(config samplerConfig, results []chainResult, summaries []ParameterSummary) SamplerDiagnostics {
+	diagnostics := SamplerDiagnostics{
+		Method:   config.method,
+		Chains:   config.chains,
+		Warmup:   config.warmup,
+		Draws:    config.draws,
+		Seed:     config.seed,
+		MinESS:   math.Inf(1),
+		Warnings: []string{},
+	}
+	var depthSum float64
+	for _, result := range results {
+		diagnostics.StepSizes = append(diagnostics.StepSizes, result.stepSize)
+		diagnostics.MeanAcceptStat += result.acceptStat / float64(len(results))
+		diagnostics.Divergences += result.divergences
+		diagnostics.MaxTreeDepthHits += result.maxTreeDepthHits
+		diagnostics.LeapfrogSteps += result.leapfrogSteps
+		depthSum += result.meanTreeDepth / float64(len(results))
+	}
+	if config.method == "nuts" {
+		diagnostics.MeanTreeDepth = &depthSum
+	}
+	for _, s := range summaries {
+		diagnostics.MaxRHat = math.Max(diagnostics.MaxRHat, s.RHat)
+		diagnostics.MinESS = math.Min(diagnostics.MinESS, s.ESS)
+	}
+	if len(summaries) == 0 {
+		diagnostics.MinESS = 0
+	}
+
+	if diagnostics.Divergences > 0 {
+		diagnostics.Warnings = append(diagnostics.Warnings,
+			"divergent transitions after warmup: increase target_accept or reparameterize")
+	}
+	if diagnostics.MaxTreeDepthHits > 0 {
+		diagnostics.Warnings = append(diagnostics.Warnings,
+			"transitions hit the maximum tree depth: increase max_tree_depth")
+	}
+	if diagnostics.MaxRHat > 1.01 {
+		diagnostics.Warnings = append(diagnostics.Warnings,
+			"R-hat above 1.01: chains have not mixed, run longer")
+	}
+	if diagnostics.MinESS < 100*float64(config.chains) {
+		diagnostics.Warnings = append(diagnostics.Warnings,
+			"effective sample size below 100 per chain: run longer")
+	}
+	return diagnostics
+}
+
+// summarizeParameter returns the posterior summary of parameter a pooled over chains
+func summarizeParameter(results []chainResult, a int, level float64) ParameterSummary {
+	chains := make([][]float64, len(results))
+	var pooled []float64
+	for k, result := range results {
+		chains[k] = make([]float64, len(result.draws))
+		for i, draw := range result.draws {
+			chains[k][i] = draw[a]
+		}
+		pooled = append(pooled, chains[k]...)
+	}
+	mean, sd := meanAndSD(pooled)
+	sort.Float64s(pooled)
+	tail := (1 - level) / 2
+	rhat, ess := splitRHatESS(chains)
+	return ParameterSummary{
+		Mean:  mean,
+		SD:    sd,
+		Lower: quantile(pooled, tail),
+		Upper: quantile(pooled, 1-tail),
+		ESS:   ess,
+		RHat:  rhat,
+	}
+}
+
+// splitRHatESS computes split-chain R-hat and the multi-chain effective sample size with
+// Geyer's initial monotone sequence (BDA3 §11.4-11.5)
+func splitRHatESS(chains [][]float64) (float64, float64) {
+	var split [][]float64
+	for _, chain := range chains {
+		half := len(chain) / 2
+		if half < 2 {
+			return math.NaN(), math.NaN()
+		}
+		split = append(split, chain[:half], chain[len(chain)-half:])
+	}
+	m := float64(len(split))
+	n := len(split[0])
+
+	means := make([]float64, len(split))
+	var w float64
+	for k, chain := range split {
+		mean, sd := meanAndSD(chain)
+		means[k] = mean
+		w += sd * sd / m
+	}
+	_, sdMeans := meanAndSD(means)
+	b := float64(n) * sdMeans * sdMeans
+	varPlus := float64(n-1)/float64(n)*w + b/float64(n)
+	total := m * float64(n)
+	if w == 0 {
+		// Constant draws: report perfect agreement and no autocorrelation
+		return 1, total
+	}
+	rhat := math.Sqrt(varPlus / w)
+
+	// Mean autocovariance across chains at lag t, normalized by n
+	autocovariance := func(t int) float64 {
+		var sum float64
+		for k, chain := range split {
+			var acov float64
+			for i := 0; i+t < n; i++ {
+				acov += (chain[i] - means[k]) * (chain[i+t] - means[k])
+			}
+			sum += acov / float64(n)
+		}
+		return sum / m
+	}
+	rho := func(t int) float64 {
+		return 1 - (w-autocovariance(t))/varPlus
+	}
+
+	tau := -1.0
+	previous := math.Inf(1)
+	for t := 0; t+1 < n; t += 2 {
+		pair := rho(t) + rho(t+1)
+		if pair <= 0 {
+			break
+		}
+		pair = math.Min(pair, previous)
+		previous = pair
+		tau += 2 * pair
+	}
+	if tau <= 0 {
+		tau = 1
+	}
+	return rhat, total / tau
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// correlatedGaussian - 相関0.9、標準偏差(1, 3)の2変量正規分布
+type correlatedGaussian struct{}
+
+func (correlatedGaussian) dimension() int { return 2 }
+
+func (correlatedGaussian) logDensityGradient(x, grad []float64) float64 {
+	const rho, s1, s2 = 0.9, 1.0, 3.0
+	z1, z2 := x[0]/s1, x[1]/s2
+	c := 1 / (1 - rho*rho)
+	grad[0] = -c * (z1 - rho*z2) / s1
+	grad[1] = -c * (z2 - rho*z1) / s2
+	return -0.5 * c * (z1*z1 - 2*rho*z1*z2 + z2*z2)
+}
+
+// TestSampleGradientModel - 既知の正規分布からのサンプリングのテスト
+func TestSampleGradientModel(t *testing.T) {
+	for _, method := range []string{"nuts", "hmc"} {
+		t.Run(method, func(t *testing.T) {
+			config := samplerConfig{
+				method: method, chains: 4, warmup: 500, draws: 1000, seed: 11,
+				targetAccept: 0.8, maxTreeDepth: 10, leapfrogSteps: 10,
+			}
+			results := sampleGradientModel(correlatedGaussian{}, config)
+
+			summaries := []ParameterSummary{
+				summarizeParameter(results, 0, 0.95),
+				summarizeParameter(results, 1, 0.95),
+			}
+			diagnostics := summarizeSampler(config, results, summaries)
+
+			if diagnostics.Divergences != 0 {
+				t.Errorf("expected no divergences, got %d", diagnostics.Divergences)
+			}
+			if diagnostics.MaxRHat > 1.05 {
+				t.Errorf("expected R-hat near 1, got %.4f", diagnostics.MaxRHat)
+			}
+			for a, sd := range []float64{1, 3} {
+				s := summaries[a]
+				if math.Abs(s.Mean) > 0.2*sd || math.Abs(s.SD-sd) > 0.15*sd {
+					t.Errorf("parameter %d: expected N(0, %.0f²), got mean %.3f sd %.3f", a, sd, s.Mean, s.SD)
+				}
+				if s.ESS < 200 {
+					t.Errorf("parameter %d: effective sample size %.1f too small", a, s.ESS)
+				}
+			}
+			if method == "nuts" && diagnostics.MeanTreeDepth == nil {
+				t.Error("expected a mean tree depth for NUTS")
+			}
+		})
+	}
+}
+
+// TestSamplerDeterministic - 同じシードで同じ結果になることのテスト
+func TestSamplerDeterministic(t *testing.T) {
+	config := samplerConfig{method: "nuts", chains: 2, warmup: 50, draws: 50, seed: 3, targetAccept: 0.8, maxTreeDepth: 6}
+	first := sampleGradientModel(correlatedGaussian{}, config)
+	second := sampleGradientModel(correlatedGaussian{}, config)
+	for k := range first {
+		for i := range first[k].draws {
+			if first[k].draws[i][0] != second[k].draws[i][0] || first[k].draws[i][1] != second[k].draws[i][1] {
+				t.Fatalf("chain %d draw %d differs between runs", k, i)
+			}
+		}
+	}
+}
+
+// TestSplitRHatESS - 独立な系列と混ざっていない系列のR-hatのテスト
+func TestSplitRHatESS(t *testing.T) {
+	// 互いに異なる位置にある系列 → R-hat が大きい
+	stuck := [][]float64{make([]float64, 100), make([]float64, 100)}
+	for i := range stuck[0] {
+		stuck[0][i] = float64(i%7) * 0.1
+		stuck[1][i] = 10 + float64(i%5)*0.1
+	}
+	if rhat, _ := splitRHatESS(stuck); rhat < 2 {
+		t.Errorf("expected a large R-hat for unmixed chains, got %.4f", rhat)
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// errNotPositiveDefinite is returned when a Cholesky decomposition fails
+var errNotPositiveDefinite = errors.New("matrix is not positive definite")
+
+// newMatrix allocates a rows×cols matrix of zeros
+func newMatrix(rows, cols int) [][]float64 {
+	m := make([][]float64, rows)
+	for i := range m {
+		m[i] = make([]float64, cols)
+	}
+	return m
+}
+
+// identityMatrix returns the n×n identity matrix
+func identityMatrix(n int) [][]float64 {
+	m := newMatrix(n, n)
+	for i := 0; i < n; i++ {
+		m[i][i] = 1.0
+	}
+	return m
+}
+
+// choleskyDecompose returns the lower triangular L with A = L·Lᵀ for a symmetric positive
+// definite matrix A
+func choleskyDecompose(a [][]float64) ([][]float64, error) {
+	n := len(a)
+	l := newMatrix(n, n)
+	for i := 0; i < n; i++ {
+		for j := 0; j <= i; j++ {
+			sum := a[i][j]
+			for k := 0; k < j; k++ {
+				sum -= l[i][k] * l[j][k]
+			}
+			if i == j {
+				if sum <= 0 || math.IsNaN(sum) {
+					return nil, errNotPositiveDefinite
+				}
+				l[i][i] = math.Sqrt(sum)
+			} else {
+				l[i][j] = sum / l[j][j]
+			}
+		}
+	}
+	return l, nil
+}
+
+// choleskySolve solves A·x = b given the Cholesky factor L of A
+func choleskySolve(l [][]float64, b []float64) []float64 {
+	n := len(l)
+	// Forward substitution L·y = b
+	y := make([]float64, n)
+	for i := 0; i < n; i++ {
+		sum := b[i]
+		for k := 0; k < i; k++ {
+			sum -= l[i][k] * y[k]
+		}
+		y[i] = sum / l[i][i]
+	}
+	// Back substitution Lᵀ·x = y
+	x := make([]float64, n)
+	for i := n - 1; i >= 0; i-- {
+		sum := y[i]
+		for k := i + 1; k < n; k++ {
+			sum -= l[k][i] * x[k]
+		}
+		x[i] = sum / l[i][i]
+	}
+	return x
+}
+
+// invertSPD inverts a symmetric positive definite matrix via its Cholesky factor
+func invertSPD(a [][]float64) ([][]float64, error) {
+	l, err := choleskyDecompose(a)
+	if err != nil {
+		return nil, err
+	}
+	n := len(a)
+	inverse := newMatrix(n, n)
+	for j := 0; j < n; j++ {
+		e := make([]float64, n)
+		e[j] = 1.0
+		column := choleskySolve(l, e)
+		for i := 0; i < n; i++ {
+			inverse[i][j] = column[i]
+		}
+	}
+	return inverse, nil
+}
+
+// matVec returns A·x
+func matVec(a [][]float64, x []float64) []float64 {
+	result := make([]float64, len(a))
+	for i, row := range a {
+		for j, v := range row {
+			result[i] += v * x[j]
+		}
+	}
+	return result
+}
+
+// dot returns the inner product of two vectors
+func dot(x, y []float64) float64 {
+	var sum float64
+	for i := range x {
+		sum += x[i] * y[i]
+	}
+	return sum
+}
+
+// matMul returns A·B
+func matMul(a, b [][]float64) [][]float64 {
+	result := newMatrix(len(a), len(b[0]))
+	for i, row := range a {
+		for k, v := range row {
+			for j, w := range b[k] {
+				result[i][j] += v * w
+			}
+		}
+	}
+	return result
+}
+
+// transpose returns Aᵀ
+func transpose(a [][]float64) [][]float64 {
+	result := newMatrix(len(a[0]), len(a))
+	for i, row := range a {
+		for j, v := range row {
+			result[j][i] = v
+		}
+	}
+	return result
+}
+
+// symmetricEigen returns the eigenvalues of a symmetric matrix in descending order and the
+// matching unit eigenvectors as the columns of V, using cyclic Jacobi rotations
+func symmetricEigen(a [][]float64) ([]float64, [][]float64) {
+	n := len(a)
+	m := newMatrix(n, n)
+	for i := range a {
+		copy(m[i], a[i])
+	}
+	v := identityMatrix(n)
+
+	for sweep := 0; sweep < 100; sweep++ {
+		var off float64
+		for i := 0; i < n; i++ {
+			for j := i + 1; j < n; j++ {
+				off += m[i][j] * m[i][j]
+			}
+		}
+		if off < 1e-22 {
+			break
+		}
+		for p := 0; p < n; p++ {
+			for q := p + 1; q < n; q++ {
+				if math.Abs(m[p][q]) < 1e-300 {
+					continue
+				}
+				// Rotation angle that annihilates m[p][q]
+				theta := (m[q][q] - m[p][p]) / (2 * m[p][q])
+				t := 1 / (math.Abs(theta) + math.Sqrt(theta*theta+1))
+				if theta < 0 {
+					t = -t
+				}
+				c := 1 / math.Sqrt(t*t+1)
+				s := t * c
+				for k := 0; k < n; k++ {
+					mkp, mkq := m[k][p], m[k][q]
+					m[k][p] = c*mkp - s*mkq
+					m[k][q] = s*mkp + c*mkq
+				}
+				for k := 0; k < n; k++ {
+					mpk, mqk := m[p][k], m[q][k]
+					m[p][k] = c*mpk - s*mqk
+					m[q][k] = s*mpk + c*mqk
+				}
+				for k := 0; k < n; k++ {
+					vkp, vkq := v[k][p], v[k][q]
+					v[k][p] = c*vkp - s*vkq
+					v[k][q] = s*vkp + c*vkq
+				}
+			}
+		}
+	}
+
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(x, y int) bool { return m[order[x]][order[x]] > m[order[y]][order[y]] })
+	values := make([]float64, n)
+	vectors := newMatrix(n, n)
+	for col, idx := range order {
+		values[col] = m[idx][idx]
+		for k := 0; k < n; k++ {
+			vectors[k][col] = v[k][idx]
+		}
+	}
+	return values, vectors
+}
@@ -0,0 +1,446 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// maxLogisticWork caps (draws + burn-in) × students for the Metropolis sampler
+const maxLogisticWork = 50000000
+
+// LogisticCoefficient represents one regression coefficient (on the log-odds scale).
+// For maximum likelihood Lower/Upper are Wald confidence limits; for the Bayesian fit
+// Estimate and StdError are the posterior mean and SD and Lower/Upper a credible interval.
+type LogisticCoefficient struct {
+	Term     string   `json:"term"`
+	Estimate float64  `json:"estimate"`
+	StdError float64  `json:"std_error"`
+	ZValue   *float64 `json:"z_value,omitempty"`
+	PValue   *float64 `json:"p_value,omitempty"`
+	Lower    float64  `json:"lower"`
+	Upper    float64  `json:"upper"`
+}
+
+// LogisticFit represents one fitted logistic regression
+type LogisticFit struct {
+	Method         string                `json:"method"`
+	Converged      bool                  `json:"converged"`
+	Iterations     int                   `json:"iterations"`
+	LogLikelihood  float64               `json:"log_likelihood"`
+	Coefficients   []LogisticCoefficient `json:"coefficients"`
+	AcceptanceRate *float64              `json:"acceptance_rate,omitempty"`
+	Message        string                `json:"message,omitempty"`
+}
+
+// StudentPassPrediction represents per-student predicted pass probabilities
+type StudentPassPrediction struct {
+	StudentID        int      `json:"student_id"`
+	Total            int      `json:"total"`
+	Pass             bool     `json:"pass"`
+	MLEProbability   *float64 `json:"mle_probability,omitempty"`
+	BayesProbability *float64 `json:"bayes_probability,omitempty"`
+}
+
+// LogisticRegressionResponse represents logistic regression of Total ≥ threshold on items
+type LogisticRegressionResponse struct {
+	Threshold   int                     `json:"threshold"`
+	Items       []string                `json:"items"`
+	PassCount   int                     `json:"pass_count"`
+	FailCount   int                     `json:"fail_count"`
+	Level       float64                 `json:"level"`
+	PriorSD     float64                 `json:"prior_sd"`
+	InterceptSD float64                 `json:"intercept_sd"`
+	MLE         *LogisticFit            `json:"mle,omitempty"`
+	Bayesian    *LogisticFit            `json:"bayesian,omitempty"`
+	Predictions []StudentPassPrediction `json:"predictions"`
+}
+
+// logisticDesign builds the design matrix (intercept + items) and the pass indicator
+func logisticDesign(items []int, threshold int) ([][]float64, []float64) {
+	x := make([][]float64, len(grades))
+	y := make([]float64, len(grades))
+	for i, g := range grades {
+		x[i] = make([]float64, len(items)+1)
+		x[i][0] = 1.0
+		for k, q := range items {
+			x[i][k+1] = float64(getQuestionValue(g, q))
+		}
+		if g.Total >= threshold {
+			y[i] = 1.0
+		}
+	}
+	return x, y
+}
+
+// logisticLogLikelihood returns Σ y·log p + (1-y)·log(1-p)
+func logisticLogLikelihood(x [][]float64, y, beta []float64) float64 {
+	var ll float64
+	for i := range x {
+		eta := dot(x[i], beta)
+		if y[i] == 1 {
+			ll += logSigmoid(eta)
+		} else {
+			ll += logSigmoid(-eta)
+		}
+	}
+	return ll
+}
+
+// fitLogisticNewton maximises the log-likelihood plus an optional Gaussian log-prior with the
+// given diagonal precision (all zero for maximum likelihood). Without a prior this is IRLS.
+// It returns the mode, the inverse Hessian (covariance) and the iteration count.
+func fitLogisticNewton(x [][]float64, y []float64, precision []float64) ([]float64, [][]float64, int, bool, error) {
+	p := len(precision)
+	beta := make([]float64, p)
+
+	const maxIterations = 100
+	for iter := 1; iter <= maxIterations; iter++ {
+		gradient := make([]float64, p)
+		hessian := newMatrix(p, p)
+		for i := range x {
+			mu := sigmoid(dot(x[i], beta))
+			weight := mu * (1 - mu)
+			for a := 0; a < p; a++ {
+				gradient[a] += x[i][a] * (y[i] - mu)
+				for b := 0; b <= a; b++ {
+					hessian[a][b] += weight * x[i][a] * x[i][b]
+				}
+			}
+		}
+		for a := 0; a < p; a++ {
+			gradient[a] -= precision[a] * beta[a]
+			hessian[a][a] += precision[a]
+			for b := 0; b < a; b++ {
+				hessian[b][a] = hessian[a][b]
+			}
+		}
+
+		l, err := choleskyDecompose(hessian)
+		if err != nil {
+			return beta, nil, iter, false, fmt.Errorf("information matrix is singular (an item may have no variation or be collinear)")
+		}
+		step := choleskySolve(l, gradient)
+
+		maxChange := 0.0
+		for a := 0; a < p; a++ {
+			beta[a] += step[a]
+			maxChange = math.Max(maxChange, math.Abs(step[a]))
+		}
+		if maxChange < 1e-8 {
+			covariance, err := invertSPD(hessian)
+			if err != nil {
+				return beta, nil, iter, false, err
+			}
+			return beta, covariance, iter, true, nil
+		}
+		// Coefficients running off to infinity indicate (quasi-)complete separation
+		for a := 0; a < p; a++ {
+			if math.Abs(beta[a]) > 30 {
+				return beta, nil, iter, false, fmt.Errorf("coefficients diverge: the items separate pass and fail perfectly")
+			}
+		}
+	}
+	return beta, nil, maxIterations, false, fmt.Errorf("did not converge in %d iterations", maxIterations)
+}
+
+// logisticTerms returns "intercept" followed by item labels
+func logisticTerms(items []int) []string {
+	terms := []string{"intercept"}
+	for _, q := range items {
+		terms = append(terms, fmt.Sprintf("q%d", q))
+	}
+	return terms
+}
+
+// fitLogisticMLE fits maximum likelihood estimates with Wald standard errors
+func fitLogisticMLE(x [][]float64, y []float64, items []int, level float64) *LogisticFit {
+	terms := logisticTerms(items)
+	beta, covariance, iterations, converged, err := fitLogisticNewton(x, y, make([]float64, len(terms)))
+
+	fit := &LogisticFit{
+		Method:        "mle",
+		Converged:     converged,
+		Iterations:    iterations,
+		LogLikelihood: logisticLogLikelihood(x, y, beta),
+		Coefficients:  []LogisticCoefficient{},
+	}
+	if err != nil {
+		fit.Message = err.Error()
+		return fit
+	}
+
+	z := normalQuantile(1 - (1-level)/2)
+	for a, term := range terms {
+		se := math.Sqrt(covariance[a][a])
+		zValue := beta[a] / se
+		pValue := 2 * (1 - normalCDF(math.Abs(zValue)))
+		fit.Coefficients = append(fit.Coefficients, LogisticCoefficient{
+			Term:     term,
+			Estimate: beta[a],
+			StdError: se,
+			ZValue:   finiteOrNil(zValue),
+			PValue:   finiteOrNil(pValue),
+			Lower:    beta[a] - z*se,
+			Upper:    beta[a] + z*se,
+		})
+	}
+	return fit
+}
+
+// fitLogisticBayes fits the model with independent Normal(0, sd²) priors, either by the Laplace
+// approximation at the posterior mode or by random-walk Metropolis tuned with its covariance.
+// It also returns the posterior expected pass probability of every student.
+func fitLogisticBayes(x [][]float64, y []float64, items []int, priorSD, interceptSD, level float64,
+	method string, draws, burnIn int, seed int64) (*LogisticFit, []float64) {
+	terms := logisticTerms(items)
+	precision := make([]float64, len(terms))
+	precision[0] = 1 / (interceptSD * interceptSD)
+	for a := 1; a < len(terms); a++ {
+		precision[a] = 1 / (priorSD * priorSD)
+	}
+
+	mode, covariance, iterations, converged, err := fitLogisticNewton(x, y, precision)
+	fit := &LogisticFit{
+		Method:        method,
+		Converged:     converged,
+		Iterations:    iterations,
+		LogLikelihood: logisticLogLikelihood(x, y, mode),
+		Coefficients:  []LogisticCoefficient{},
+	}
+	if err != nil {
+		fit.Message = err.Error()
+		return fit, nil
+	}
+
+	lowerP := (1 - level) / 2
+	upperP := 1 - lowerP
+
+	if method == "laplace" {
+		z := normalQuantile(upperP)
+		for a, term := range terms {
+			sd := math.Sqrt(covariance[a][a])
+			fit.Coefficients = append(fit.Coefficients, LogisticCoefficient{
+				Term:     term,
+				Estimate: mode[a],
+				StdError: sd,
+				Lower:    mode[a] - z*sd,
+				Upper:    mode[a] + z*sd,
+			})
+		}
+		probabilities := make([]float64, len(x))
+		for i := range x {
+			probabilities[i] = sigmoid(dot(x[i], mode))
+		}
+		return fit, probabilities
+	}
+
+	// Random-walk Metropolis with proposal N(0, (2.38²/d)·Σ_Laplace)
+	l, err := choleskyDecompose(covariance)
+	if err != nil {
+		fit.Message = err.Error()
+		return fit, nil
+	}
+	rng := rand.New(rand.NewSource(seed))
+	d := len(terms)
+	scale := 2.38 / math.Sqrt(float64(d))
+
+	logPosterior := func(beta []float64) float64 {
+		lp := logisticLogLikelihood(x, y, beta)
+		for a := range beta {
+			lp -= 0.5 * precision[a] * beta[a] * beta[a]
+		}
+		return lp
+	}
+
+	current := append([]float64(nil), mode...)
+	currentLP := logPosterior(current)
+	samples := make([][]float64, d)
+	probabilities := make([]float64, len(x))
+	accepted := 0
+
+	for iter := 0; iter < burnIn+draws; iter++ {
+		z := make([]float64, d)
+		for a := range z {
+			z[a] = rng.NormFloat64()
+		}
+		step := matVec(l, z)
+		proposal := make([]float64, d)
+		for a := range proposal {
+			proposal[a] = current[a] + scale*step[a]
+		}
+		proposalLP := logPosterior(proposal)
+		if math.Log(rng.Float64()) < proposalLP-currentLP {
+			current, currentLP = proposal, proposalLP
+			if iter >= burnIn {
+				accepted++
+			}
+		}
+
+		if iter >= burnIn {
+			for a := range current {
+				samples[a] = append(samples[a], current[a])
+			}
+			for i := range x {
+				probabilities[i] += sigmoid(dot(x[i], current))
+			}
+		}
+	}
+
+	for i := range probabilities {
+		probabilities[i] /= float64(draws)
+	}
+	acceptanceRate := float64(accepted) / float64(draws)
+	fit.AcceptanceRate = &acceptanceRate
+
+	for a, term := range terms {
+		mean, sd := meanAndSD(samples[a])
+		sorted := append([]float64(nil), samples[a]...)
+		sort.Float64s(sorted)
+		fit.Coefficients = append(fit.Coefficients, LogisticCoefficient{
+			Term:     term,
+			Estimate: mean,
+			StdError: sd,
+			Lower:    quantile(sorted, lowerP),
+			Upper:    quantile(sorted, upperP),
+		})
+	}
+	return fit, probabilities
+}
+
+// Handler: Get logistic regression
+// Regresses pass (Total ≥ threshold) on the chosen items by maximum likelihood (IRLS) and/or
+// Bayesian inference with Normal priors (Laplace approximation or Metropolis MCMC)
+func getLogisticRegression(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	thresholdStr := r.URL.Query().Get("threshold")
+	itemsStr := r.URL.Query().Get("items")
+
+	if thresholdStr == "" {
+		http.Error(w, "Missing 'threshold' parameter", http.StatusBadRequest)
+		return
+	}
+	if itemsStr == "" {
+		http.Error(w, "Missing 'items' parameter", http.StatusBadRequest)
+		return
+	}
+
+	threshold, err := strconv.Atoi(thresholdStr)
+	if err != nil {
+		http.Error(w, "Invalid 'threshold' parameter", http.StatusBadRequest)
+		return
+	}
+	items, err := parseQuestionList(itemsStr)
+	if err != nil {
+		http.Error(w, "Invalid 'items' parameter (must be a comma separated list of q1-q10)", http.StatusBadRequest)
+		return
+	}
+
+	method := r.URL.Query().Get("method")
+	switch method {
+	case "":
+		method = "both"
+	case "mle", "bayes", "both":
+	default:
+		http.Error(w, "Invalid 'method' parameter (must be mle, bayes or both)", http.StatusBadRequest)
+		return
+	}
+
+	bayesMethod := r.URL.Query().Get("bayes_method")
+	switch bayesMethod {
+	case "":
+		bayesMethod = "laplace"
+	case "laplace", "mcmc":
+	default:
+		http.Error(w, "Invalid 'bayes_method' parameter (must be laplace or mcmc)", http.StatusBadRequest)
+		return
+	}
+
+	priorSD, err := floatParam(r, "prior_sd", 2.5)
+	if err != nil || priorSD <= 0 {
+		http.Error(w, "Invalid 'prior_sd' parameter (must be positive)", http.StatusBadRequest)
+		return
+	}
+	interceptSD, err := floatParam(r, "intercept_sd", 10.0)
+	if err != nil || interceptSD <= 0 {
+		http.Error(w, "Invalid 'intercept_sd' parameter (must be positive)", http.StatusBadRequest)
+		return
+	}
+	level, err := floatParam(r, "level", 0.95)
+	if err != nil || level <= 0 || level >= 1 {
+		http.Error(w, "Invalid 'level' parameter (must be between 0 and 1)", http.StatusBadRequest)
+		return
+	}
+	draws, err := intParam(r, "draws", 4000)
+	if err != nil || draws < 100 || draws > 100000 {
+		http.Error(w, "Invalid 'draws' parameter (must be between 100 and 100000)", http.StatusBadRequest)
+		return
+	}
+	burnIn, err := intParam(r, "burn_in", 1000)
+	if err != nil || burnIn < 0 || burnIn > 100000 {
+		http.Error(w, "Invalid 'burn_in' parameter (must be between 0 and 100000)", http.StatusBadRequest)
+		return
+	}
+	seed, err := intParam(r, "seed", 1)
+	if err != nil {
+		http.Error(w, "Invalid 'seed' parameter", http.StatusBadRequest)
+		return
+	}
+
+	if len(grades) == 0 {
+		http.Error(w, "No data available", http.StatusInternalServerError)
+		return
+	}
+
+	if method != "mle" && bayesMethod == "mcmc" && draws+burnIn > maxLogisticWork/len(grades) {
+		http.Error(w, "Too much work: (draws + burn_in) × students must not exceed 50000000", http.StatusBadRequest)
+		return
+	}
+
+	x, y := logisticDesign(items, threshold)
+
+	response := LogisticRegressionResponse{
+		Threshold:   threshold,
+		Items:       logisticTerms(items)[1:],
+		Level:       level,
+		PriorSD:     priorSD,
+		InterceptSD: interceptSD,
+		Predictions: make([]StudentPassPrediction, len(grades)),
+	}
+	for i, g := range grades {
+		response.Predictions[i] = StudentPassPrediction{StudentID: g.StudentID, Total: g.Total, Pass: y[i] == 1}
+		if y[i] == 1 {
+			response.PassCount++
+		} else {
+			response.FailCount++
+		}
+	}
+
+	if method == "mle" || method == "both" {
+		response.MLE = fitLogisticMLE(x, y, items, level)
+		if response.MLE.Converged {
+			beta := make([]float64, len(response.MLE.Coefficients))
+			for a, c := range response.MLE.Coefficients {
+				beta[a] = c.Estimate
+			}
+			for i := range x {
+				response.Predictions[i].MLEProbability = finiteOrNil(sigmoid(dot(x[i], beta)))
+			}
+		}
+	}
+
+	if method == "bayes" || method == "both" {
+		fit, probabilities := fitLogisticBayes(x, y, items, priorSD, interceptSD, level, bayesMethod, draws, burnIn, int64(seed))
+		response.Bayesian = fit
+		for i, p := range probabilities {
+			response.Predictions[i].BayesProbability = finiteOrNil(p)
+		}
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// setupLogisticTestData - Q1と合否の2×2表が [[15,5],[5,15]] になるテストデータ
+func setupLogisticTestData() {
+	grades = []Grade{}
+	add := func(q1, total, count int) {
+		for i := 0; i < count; i++ {
+			grades = append(grades, Grade{StudentID: len(grades) + 1, Q1: q1, Total: total})
+		}
+	}
+	add(1, 8, 15) // Q1正解・合格
+	add(1, 3, 5)  // Q1正解・不合格
+	add(0, 8, 5)  // Q1不正解・合格
+	add(0, 3, 15) // Q1不正解・不合格
+}
+
+// TestLogisticRegressionMLE - 最尤推定（IRLS）の係数と標準誤差のテスト
+func TestLogisticRegressionMLE(t *testing.T) {
+	setupLogisticTestData()
+	// 切片 = log(5/15), 係数 = log(15/5) - log(5/15) = log 9
+	// SE(係数) = sqrt(1/15 + 1/5 + 1/5 + 1/15)
+
+	var result LogisticRegressionResponse
+	serveJSON(t, getLogisticRegression, "GET", "/api/logistic-regression?threshold=6&items=q1&method=mle", "", &result)
+
+	if result.MLE == nil || !result.MLE.Converged {
+		t.Fatalf("expected a converged MLE fit, got %+v", result.MLE)
+	}
+	if result.Bayesian != nil {
+		t.Error("expected no Bayesian fit for method=mle")
+	}
+
+	intercept := result.MLE.Coefficients[0]
+	slope := result.MLE.Coefficients[1]
+	if math.Abs(intercept.Estimate-math.Log(5.0/15.0)) > 1e-6 {
+		t.Errorf("expected intercept %.4f, got %.4f", math.Log(5.0/15.0), intercept.Estimate)
+	}
+	if math.Abs(slope.Estimate-math.Log(9)) > 1e-6 {
+		t.Errorf("expected q1 coefficient %.4f, got %.4f", math.Log(9), slope.Estimate)
+	}
+	expectedSE := math.Sqrt(1.0/15 + 1.0/5 + 1.0/5 + 1.0/15)
+	if math.Abs(slope.StdError-expectedSE) > 1e-6 {
+		t.Errorf("expected q1 standard error %.4f, got %.4f", expectedSE, slope.StdError)
+	}
+	if slope.PValue == nil || *slope.PValue > 0.01 {
+		t.Errorf("expected a significant q1 coefficient, got p=%v", slope.PValue)
+	}
+
+	// Q1正解者の予測合格確率は 15/20
+	if p := result.Predictions[0].MLEProbability; p == nil || math.Abs(*p-0.75) > 1e-6 {
+		t.Errorf("expected predicted probability 0.75, got %v", p)
+	}
+}
+
+// TestLogisticRegressionBayes - 正規事前分布による縮小推定のテスト
+func TestLogisticRegressionBayes(t *testing.T) {
+	setupLogisticTestData()
+
+	var laplace LogisticRegressionResponse
+	serveJSON(t, getLogisticRegression, "GET", "/api/logistic-regression?threshold=6&items=q1&method=bayes&prior_sd=1", "", &laplace)
+	if laplace.Bayesian == nil || !laplace.Bayesian.Converged {
+		t.Fatalf("expected a converged Bayesian fit, got %+v", laplace.Bayesian)
+	}
+	slope := laplace.Bayesian.Coefficients[1]
+	// 事前分布により最尤推定値 log 9 より0に近づく
+	if slope.Estimate <= 0 || slope.Estimate >= math.Log(9) {
+		t.Errorf("expected shrunken coefficient in (0, %.4f), got %.4f", math.Log(9), slope.Estimate)
+	}
+	if slope.Lower >= slope.Estimate || slope.Upper <= slope.Estimate {
+		t.Errorf("credible interval [%.4f, %.4f] does not contain the estimate", slope.Lower, slope.Upper)
+	}
+
+	var mcmc LogisticRegressionResponse
+	serveJSON(t, getLogisticRegression, "GET", "/api/logistic-regression?threshold=6&items=q1&method=bayes&bayes_method=mcmc&prior_sd=1&draws=4000&seed=7", "", &mcmc)
+	if mcmc.Bayesian == nil || mcmc.Bayesian.AcceptanceRate == nil {
+		t.Fatalf("expected an MCMC fit with acceptance rate, got %+v", mcmc.Bayesian)
+	}
+	if rate := *mcmc.Bayesian.AcceptanceRate; rate < 0.1 || rate > 0.9 {
+		t.Errorf("acceptance rate %.2f is outside a sensible range", rate)
+	}
+	if diff := math.Abs(mcmc.Bayesian.Coefficients[1].Estimate - slope.Estimate); diff > 0.3 {
+		t.Errorf("MCMC posterior mean differs from the Laplace mode by %.4f", diff)
+	}
+	if p := mcmc.Predictions[0].BayesProbability; p == nil || *p <= 0.5 {
+		t.Errorf("expected Bayesian pass probability above 0.5 for Q1-correct students, got %v", p)
+	}
+}
+
+// TestLogisticRegressionSeparation - 完全分離の場合は最尤推定が収束しないことを報告するテスト
+func TestLogisticRegressionSeparation(t *testing.T) {
+	grades = []Grade{
+		{StudentID: 1, Q1: 1, Total: 8},
+		{StudentID: 2, Q1: 1, Total: 9},
+		{StudentID: 3, Q1: 0, Total: 2},
+		{StudentID: 4, Q1: 0, Total: 3},
+	}
+
+	var result LogisticRegressionResponse
+	serveJSON(t, getLogisticRegression, "GET", "/api/logistic-regression?threshold=6&items=q1", "", &result)
+
+	if result.MLE == nil || result.MLE.Converged || result.MLE.Message == "" {
+		t.Errorf("expected a non-converged MLE fit with a message, got %+v", result.MLE)
+	}
+	// 事前分布があればベイズ推定は収束する
+	if result.Bayesian == nil || !result.Bayesian.Converged {
+		t.Errorf("expected the Bayesian fit to converge, got %+v", result.Bayesian)
+	}
+}
+
+// TestLogisticRegressionInvalidParams - 無効なパラメータのテスト
+func TestLogisticRegressionInvalidParams(t *testing.T) {
+	setupTestData()
+
+	testCases := []struct {
+		name  string
+		query string
+	}{
+		{"missing_threshold", "items=q1"},
+		{"missing_items", "threshold=6"},
+		{"invalid_items", "threshold=6&items=q0"},
+		{"invalid_method", "threshold=6&items=q1&method=ols"},
+		{"invalid_bayes_method", "threshold=6&items=q1&bayes_method=vi"},
+		{"invalid_prior_sd", "threshold=6&items=q1&prior_sd=0"},
+		{"invalid_level", "threshold=6&items=q1&level=1"},
+		{"invalid_draws", "threshold=6&items=q1&draws=10"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "/api/logistic-regression?"+tc.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(getLogisticRegression)
+			handler.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != http.StatusBadRequest {
+				t.Errorf("handler returned wrong status code: got %v want %v",
+					status, http.StatusBadRequest)
+			}
+		})
+	}
+}
+
+// TestLogisticRegressionEmptyData - データが空の場合のテスト
+func TestLogisticRegressionEmptyData(t *testing.T) {
+	grades = []Grade{}
+
+	req, err := http.NewRequest("GET", "/api/logistic-regression?threshold=6&items=q1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(getLogisticRegression)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusInternalServerError {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusInternalServerError)
+	}
+}
+
+// TestLogisticRegressionTooMuchWork - MCMCの (draws + burn_in) × 学生数が上限を超える場合のテスト
+func TestLogisticRegressionTooMuchWork(t *testing.T) {
+	setupTotalsTestData(make([]int, 300)...)
+	for i := range grades {
+		grades[i].Total = i % 11
+	}
+
+	query := "/api/logistic-regression?threshold=6&items=q1&method=bayes&draws=100000&burn_in=100000"
+	if rr := serveRequest(t, getLogisticRegression, "GET", query+"&bayes_method=mcmc", ""); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 above the work cap, got %d", rr.Code)
+	}
+	// ラプラス近似ではdrawsとburn_inを使わないので上限の対象外
+	var result LogisticRegressionResponse
+	serveJSON(t, getLogisticRegression, "GET", query, "", &result)
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"math"
+	"sort"
+)
+
+// Predictive information criteria from a pointwise log-likelihood matrix logLik[i][s] =
+// log p(y_i | θ_s) over S posterior draws (Vehtari, Gelman & Gabry 2017).
+
+// pointwiseElpd holds pointwise expected log predictive densities and their totals
+type pointwiseElpd struct {
+	elpd      []float64 // per observation
+	penalty   []float64 // per observation effective number of parameters
+	estimate  float64
+	se        float64
+	pEstimate float64
+}
+
+// newPointwiseElpd totals pointwise values with the standard error sqrt(n·Var(elpd_i))
+func newPointwiseElpd(elpd, penalty []float64) pointwiseElpd {
+	result := pointwiseElpd{elpd: elpd, penalty: penalty}
+	for i := range elpd {
+		result.estimate += elpd[i]
+		result.pEstimate += penalty[i]
+	}
+	if len(elpd) > 1 {
+		_, sd := meanAndSD(elpd)
+		result.se = math.Sqrt(float64(len(elpd))) * sd
+	}
+	return result
+}
+
+// computeWAIC returns the WAIC elpd, where p_waic is the posterior variance of the log density
+func computeWAIC(logLik [][]float64) pointwiseElpd {
+	elpd := make([]float64, len(logLik))
+	penalty := make([]float64, len(logLik))
+	for i, row := range logLik {
+		lppd := logSumExp(row) - math.Log(float64(len(row)))
+		_, sd := meanAndSD(row)
+		penalty[i] = sd * sd
+		elpd[i] = lppd - penalty[i]
+	}
+	return newPointwiseElpd(elpd, penalty)
+}
+
+// computePSISLOO returns the PSIS-LOO elpd and the Pareto shape k̂ of each observation
+func computePSISLOO(logLik [][]float64) (pointwiseElpd, []float64) {
+	elpd := make([]float64, len(logLik))
+	penalty := make([]float64, len(logLik))
+	paretoK := make([]float64, len(logLik))
+	for i, row := range logLik {
+		// Raw importance ratios for leaving observation i out are 1 / p(y_i | θ_s)
+		logWeights := make([]float64, len(row))
+		for s, ll := range row {
+			logWeights[s] = -ll
+		}
+		smoothed, k := paretoSmoothWeights(logWeights)
+		paretoK[i] = k
+
+		terms := make([]float64, len(row))
+		for s := range row {
+			terms[s] = smoothed[s] + row[s]
+		}
+		elpd[i] = logSumExp(terms)
+		penalty[i] = logSumExp(row) - math.Log(float64(len(row))) - elpd[i]
+	}
+	return newPointwiseElpd(elpd, penalty), paretoK
+}
+
+// paretoSmoothWeights replaces the largest importance ratios by expected order statistics of a
+// generalized Pareto distribution fitted to them and returns normalized log weights and k̂
+func paretoSmoothWeights(logWeights []float64) ([]float64, float64) {
+	s := len(logWeights)
+	lw := make([]float64, s)
+	maxWeight := math.Inf(-1)
+	for _, v := range logWeights {
+		maxWeight = math.Max(maxWeight, v)
+	}
+	for j, v := range logWeights {
+		lw[j] = v - maxWeight
+	}
+
+	tailLength := int(math.Ceil(math.Min(0.2*float64(s), 3*math.Sqrt(float64(s)))))
+	k := 0.0
+	if tailLength >= 5 && tailLength < s {
+		order := make([]int, s)
+		for j := range order {
+			order[j] = j
+		}
+		sort.Slice(order, func(a, b int) bool { return lw[order[a]] < lw[order[b]] })
+		tail := order[s-tailLength:]
+		cutoff := math.Exp(lw[order[s-tailLength-1]])
+
+		excess := make([]float64, tailLength)
+		for j, idx := range tail {
+			excess[j] = math.Exp(lw[idx]) - cutoff
+		}
+		// Identical weights leave nothing to smooth; k̂ = 0 marks them as reliable
+		if excess[tailLength-1] > 0 {
+			var sigma float64
+			k, sigma = fitGeneralizedPareto(excess)
+			if !math.IsInf(k, 0) && !math.IsNaN(k) {
+				for j, idx := range tail {
+					p := (float64(j) + 0.5) / float64(tailLength)
+					lw[idx] = math.Min(0, math.Log(generalizedParetoQuantile(p, k, sigma)+cutoff))
+				}
+			}
+		}
+	}
+
+	normalizer := logSumExp(lw)
+	for j := range lw {
+		lw[j] -= normalizer
+	}
+	return lw, k
+}
+
+// fitGeneralizedPareto estimates the shape k and scale σ of a generalized Pareto distribution
+// from ascending exceedances x, using the empirical Bayes method of Zhang & Stephens (2009)
+// with the weakly informative shrinkage of k towards ½ used by PSIS
+func fitGeneralizedPareto(x []float64) (float64, float64) {
+	const prior = 3.0
+	n := len(x)
+	m := 30 + int(math.Sqrt(float64(n)))
+	quartile := x[int(float64(n)/4+0.5)-1]
+
+	theta := make([]float64, m)
+	logLik := make([]float64, m)
+	for j := range theta {
+		theta[j] = 1/x[n-1] + (1-math.Sqrt(float64(m)/(float64(j+1)-0.5)))/prior/quartile
+		var k float64
+		for _, v := range x {
+			k += math.Log1p(-theta[j]*v) / float64(n)
+		}
+		logLik[j] = float64(n) * (math.Log(-theta[j]/k) - k - 1)
+	}
+
+	normalizer := logSumExp(logLik)
+	var thetaHat float64
+	for j := range theta {
+		thetaHat += theta[j] * math.Exp(logLik[j]-normalizer)
+	}
+	var k float64
+	for _, v := range x {
+		k += math.Log1p(-thetaHat*v) / float64(n)
+	}
+	sigma := -k / thetaHat
+	k = (float64(n)*k + 10*0.5) / (float64(n) + 10)
+	return k, sigma
+}
+
+// generalizedParetoQuantile returns the p-quantile of GPD(0, σ, k)
+func generalizedParetoQuantile(p, k, sigma float64) float64 {
+	if k == 0 {
+		return -sigma * math.Log1p(-p)
+	}
+	return sigma * math.Expm1(-k*math.Log1p(-p)) / k
+}
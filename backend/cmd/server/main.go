@@ -350,52 +350,22 @@ func getBayesTheorem(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Calculate Bayes theorem: P(Total≥threshold | Q_condition=value)
-	// This is actually a conditional probability
-	conditionMetCount := 0
-	bothConditionsMetCount := 0
-
-	for _, grade := range grades {
-		questionValue := getQuestionValue(grade, questionNum)
-
-		if questionValue == value {
-			conditionMetCount++
-			if grade.Total >= threshold {
-				bothConditionsMetCount++
-			}
-		}
-	}
-
-	// Calculate posterior probability
-	posteriorProbability := 0.0
-	if conditionMetCount > 0 {
-		posteriorProbability = float64(bothConditionsMetCount) / float64(conditionMetCount)
-	}
-
-	// Calculate prior and likelihood for reference
-	// Prior: P(Total≥threshold)
-	thresholdMetCount := 0
-	for _, grade := range grades {
-		if grade.Total >= threshold {
-			thresholdMetCount++
-		}
-	}
-	priorProbability := float64(thresholdMetCount) / float64(len(grades))
-
-	// Likelihood: P(Q_condition=value | Total≥threshold)
-	likelihoodProbability := 0.0
-	if thresholdMetCount > 0 {
-		likelihoodProbability = float64(bothConditionsMetCount) / float64(thresholdMetCount)
-	}
+	// This is the single-condition special case of getBayesQuery
+	counts := countBayes(
+		comparisonExpr{left: variableOperand(questionNum), op: "==", right: constantOperand(value)},
+		comparisonExpr{left: variableOperand(0), op: ">=", right: constantOperand(threshold)},
+	)
 
 	response := BayesTheoremResponse{
 		Condition:              condition,
 		ConditionValue:         value,
 		Threshold:              threshold,
-		PosteriorProbability:   posteriorProbability,
-		ConditionMetCount:      conditionMetCount,
-		BothConditionsMetCount: bothConditionsMetCount,
-		PriorProbability:       priorProbability,
-		LikelihoodProbability:  likelihoodProbability,
+		PosteriorProbability:   ratio(counts.both, counts.condition),
+		ConditionMetCount:      counts.condition,
+		BothConditionsMetCount: counts.both,
+		// Prior: P(Total≥threshold), Likelihood: P(Q_condition=value | Total≥threshold)
+		PriorProbability:      ratio(counts.hypothesis, counts.n),
+		LikelihoodProbability: ratio(counts.both, counts.hypothesis),
 	}
 
 	json.NewEncoder(w).Encode(response)
@@ -539,6 +509,44 @@ func main() {
 	router.HandleFunc("/api/conditional-probability", getConditionalProbability).Methods("GET")
 	router.HandleFunc("/api/correlation-matrix", getCorrelationMatrix).Methods("GET")
 	router.HandleFunc("/api/bayes", getBayesTheorem).Methods("GET")
+	router.HandleFunc("/api/bayes/query", getBayesQuery).Methods("GET")
+	router.HandleFunc("/api/naive-bayes", getNaiveBayes).Methods("GET")
+	router.HandleFunc("/api/logistic-regression", getLogisticRegression).Methods("GET")
+	router.HandleFunc("/api/predictive", getPosteriorPredictive).Methods("GET")
+	router.HandleFunc("/api/ppc", getPosteriorPredictiveCheck).Methods("GET")
+	router.HandleFunc("/api/hierarchical-items", getHierarchicalItems).Methods("GET")
+	router.HandleFunc("/api/irt/rasch", getRaschModel).Methods("GET")
+	router.HandleFunc("/api/gibbs/normal", getGibbsNormal).Methods("GET")
+	router.HandleFunc("/api/model-comparison", getModelComparison).Methods("GET")
+	router.HandleFunc("/api/sequential", getSequentialUpdate).Methods("GET")
+	router.HandleFunc("/api/prior-sensitivity", getPriorSensitivity).Methods("GET")
+	router.HandleFunc("/api/prior-elicitation", getPriorElicitation).Methods("GET")
+	router.HandleFunc("/api/bootstrap", getBootstrap).Methods("GET")
+	router.HandleFunc("/api/clusters", getClusters).Methods("GET")
+	router.HandleFunc("/api/q-matrix", getQMatrix).Methods("GET")
+	router.HandleFunc("/api/q-matrix", putQMatrix).Methods("PUT")
+	router.HandleFunc("/api/cognitive-diagnosis", getCognitiveDiagnosis).Methods("GET")
+	router.HandleFunc("/api/attempts", getAttempts).Methods("GET")
+	router.HandleFunc("/api/attempts", postAttempts).Methods("POST")
+	router.HandleFunc("/api/bkt", getBKT).Methods("GET")
+	router.HandleFunc("/api/factor-analysis", getFactorAnalysis).Methods("GET")
+	router.HandleFunc("/api/bayes-network", getBayesNetwork).Methods("GET")
+	router.HandleFunc("/api/association-rules", getAssociationRules).Methods("GET")
+	router.HandleFunc("/api/person-fit", getPersonFit).Methods("GET")
+	router.HandleFunc("/api/answer-copying", getAnswerCopying).Methods("GET")
+	router.HandleFunc("/api/cat/items", getCATItems).Methods("GET")
+	router.HandleFunc("/api/cat/sessions", postCATSession).Methods("POST")
+	router.HandleFunc("/api/cat/sessions/{id}", getCATSession).Methods("GET")
+	router.HandleFunc("/api/cat/sessions/{id}", deleteCATSession).Methods("DELETE")
+	router.HandleFunc("/api/cat/sessions/{id}/next", getCATNextItem).Methods("GET")
+	router.HandleFunc("/api/cat/sessions/{id}/responses", postCATResponse).Methods("POST")
+	router.HandleFunc("/api/standard-setting/angoff", postAngoff).Methods("POST")
+	router.HandleFunc("/api/standard-setting/bookmark", getBookmark).Methods("GET")
+	router.HandleFunc("/api/standard-setting/contrasting-groups", postContrastingGroups).Methods("POST")
+	router.HandleFunc("/api/partial-correlation", getPartialCorrelationMatrix).Methods("GET")
+	router.HandleFunc("/api/conditional-independence", getConditionalIndependence).Methods("GET")
+	router.HandleFunc("/api/contingency", getContingencyTable).Methods("GET")
+	router.HandleFunc("/api/conditional-probability-matrix", getConditionalProbabilityMatrix).Methods("GET")
 
 	// CORS middleware
 	c := cors.New(cors.Options{
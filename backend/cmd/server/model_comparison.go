@@ -0,0 +1,350 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// ParetoKSummary counts observations in the usual Pareto k̂ reliability bands
+type ParetoKSummary struct {
+	Max     float64 `json:"max"`
+	Good    int     `json:"good"`     // k̂ ≤ 0.5
+	OK      int     `json:"ok"`       // 0.5 < k̂ ≤ 0.7
+	Bad     int     `json:"bad"`      // 0.7 < k̂ ≤ 1
+	VeryBad int     `json:"very_bad"` // k̂ > 1
+}
+
+// InformationCriterion represents an elpd estimate on the log scale and its deviance form
+type InformationCriterion struct {
+	Elpd     float64 `json:"elpd"`
+	SE       float64 `json:"se"`
+	P        float64 `json:"p"`
+	Deviance float64 `json:"deviance"`
+}
+
+// ModelComparisonEntry represents one candidate model's fit and rank
+type ModelComparisonEntry struct {
+	Model                    string                 `json:"model"`
+	Rank                     int                    `json:"rank"`
+	Posterior                TotalsPosteriorSummary `json:"posterior"`
+	WAIC                     InformationCriterion   `json:"waic"`
+	LOO                      InformationCriterion   `json:"loo"`
+	ParetoK                  ParetoKSummary         `json:"pareto_k"`
+	ElpdDiff                 float64                `json:"elpd_diff"`
+	ElpdDiffSE               float64                `json:"elpd_diff_se"`
+	LogMarginalLikelihood    float64                `json:"log_marginal_likelihood"`
+	MarginalLikelihoodMethod string                 `json:"marginal_likelihood_method"`
+	LogBayesFactor           float64                `json:"log_bayes_factor"` // against the model with the highest marginal likelihood
+	PosteriorProbability     float64                `json:"posterior_probability"`
+}
+
+// ModelComparisonResponse represents the comparison of candidate models for Totals
+type ModelComparisonResponse struct {
+	N        int                    `json:"n"`
+	Draws    int                    `json:"draws"`
+	Seed     int64                  `json:"seed"`
+	Models   []ModelComparisonEntry `json:"models"`
+	Warnings []string               `json:"warnings"`
+}
+
+// parseTotalsModelList parses a comma separated list of Totals models
+func parseTotalsModelList(s string) ([]string, error) {
+	var models []string
+	seen := map[string]bool{}
+	for _, part := range strings.Split(s, ",") {
+		model, ok := parseTotalsModel(strings.TrimSpace(part))
+		if !ok {
+			return nil, errors.New("unknown model " + part)
+		}
+		if !seen[model] {
+			seen[model] = true
+			models = append(models, model)
+		}
+	}
+	return models, nil
+}
+
+// totalsLogLikelihoodMatrix returns log p(Total_i | θ_s) for every student and draw
+func totalsLogLikelihoodMatrix(draws []totalsDraw, totals []int) [][]float64 {
+	// Totals take only numQuestions+1 values, so evaluate each value once
+	byValue := make([][]float64, numQuestions+1)
+	for k := range byValue {
+		byValue[k] = make([]float64, len(draws))
+		for s, d := range draws {
+			byValue[k][s] = d.logPMF(k)
+		}
+	}
+	logLik := make([][]float64, len(totals))
+	for i, k := range totals {
+		logLik[i] = byValue[k]
+	}
+	return logLik
+}
+
+// sumLogChoose returns Σ_i log C(10, Total_i)
+func sumLogChoose(totals []int) float64 {
+	var sum float64
+	for _, k := range totals {
+		sum += logChoose(numQuestions, k)
+	}
+	return sum
+}
+
+// betaBinomialLogPosterior returns the log of likelihood × normalized prior on the
+// (logit μ, log κ) scale, whose integral is the marginal likelihood
+func betaBinomialLogPosterior(totals []int) func([]float64) float64 {
+	minLog, maxLog := math.Log(betaBinomialMinKappa), math.Log(betaBinomialMaxKappa)
+	constant := sumLogChoose(totals) - math.Log(maxLog-minLog)
+	histogram := make([]int, numQuestions+1)
+	for _, k := range totals {
+		histogram[k]++
+	}
+	return func(x []float64) float64 {
+		if x[1] < minLog || x[1] > maxLog {
+			return math.Inf(-1)
+		}
+		mu := sigmoid(x[0])
+		kappa := math.Exp(x[1])
+		a, b := mu*kappa, (1-mu)*kappa
+		// μ ~ Uniform(0, 1) has density μ(1-μ) on the logit scale
+		lp := constant + logSigmoid(x[0]) + logSigmoid(-x[0])
+		for k, count := range histogram {
+			if count > 0 {
+				lp += float64(count) * (lbeta(float64(k)+a, float64(numQuestions-k)+b) - lbeta(a, b))
+			}
+		}
+		return lp
+	}
+}
+
+// bridgeSamplingLogML estimates log ∫ q(x) dx from posterior draws with the iterative optimal
+// bridge of Meng & Wong (1996). The first half of the draws fits a multivariate Normal proposal
+// and the second half enters the bridge estimator.
+func bridgeSamplingLogML(logQ func([]float64) float64, draws [][]float64, rng *rand.Rand) (float64, error) {
+	half := len(draws) / 2
+	fit, bridge := draws[:half], draws[half:]
+	d := len(draws[0])
+
+	mean := make([]float64, d)
+	for _, x := range fit {
+		for a := range mean {
+			mean[a] += x[a] / float64(len(fit))
+		}
+	}
+	covariance := newMatrix(d, d)
+	for _, x := range fit {
+		for a := 0; a < d; a++ {
+			for b := 0; b < d; b++ {
+				covariance[a][b] += (x[a] - mean[a]) * (x[b] - mean[b]) / float64(len(fit)-1)
+			}
+		}
+	}
+	l, err := choleskyDecompose(covariance)
+	if err != nil {
+		return math.NaN(), err
+	}
+	var logDet float64
+	for a := 0; a < d; a++ {
+		logDet += 2 * math.Log(l[a][a])
+	}
+	logProposal := func(x []float64) float64 {
+		diff := make([]float64, d)
+		for a := range diff {
+			diff[a] = x[a] - mean[a]
+		}
+		z := choleskySolve(l, diff)
+		return -0.5*(float64(d)*math.Log(2*math.Pi)+logDet) - 0.5*dot(diff, z)
+	}
+
+	n1, n2 := len(bridge), len(bridge)
+	l1 := make([]float64, n1)
+	for i, x := range bridge {
+		l1[i] = logQ(x) - logProposal(x)
+	}
+	l2 := make([]float64, n2)
+	for j := range l2 {
+		z := make([]float64, d)
+		for a := range z {
+			z[a] = rng.NormFloat64()
+		}
+		step := matVec(l, z)
+		x := make([]float64, d)
+		for a := range x {
+			x[a] = mean[a] + step[a]
+		}
+		l2[j] = logQ(x) - logProposal(x)
+	}
+
+	// Work relative to the median ratio to avoid overflow
+	sorted := append([]float64(nil), l1...)
+	sort.Float64s(sorted)
+	shift := quantile(sorted, 0.5)
+	s1 := float64(n1) / float64(n1+n2)
+	s2 := float64(n2) / float64(n1+n2)
+
+	logR := 0.0
+	for iter := 0; iter < 1000; iter++ {
+		numerator := make([]float64, n2)
+		for j, v := range l2 {
+			numerator[j] = v - shift - logSumExp([]float64{math.Log(s1) + v - shift, math.Log(s2) + logR})
+		}
+		denominator := make([]float64, n1)
+		for i, v := range l1 {
+			denominator[i] = -logSumExp([]float64{math.Log(s1) + v - shift, math.Log(s2) + logR})
+		}
+		next := logSumExp(numerator) - math.Log(float64(n2)) - (logSumExp(denominator) - math.Log(float64(n1)))
+		if math.Abs(next-logR) < 1e-10 {
+			logR = next
+			break
+		}
+		logR = next
+	}
+	return logR + shift, nil
+}
+
+// totalsLogMarginalLikelihood returns log p(Totals | model) and how it was computed. The
+// Binomial model is conjugate; the Beta-Binomial model uses bridge sampling on its draws and
+// falls back to quadrature over its posterior grid if the bridge cannot be built.
+func totalsLogMarginalLikelihood(posterior totalsPosterior, draws []totalsDraw, totals []int,
+	rng *rand.Rand) (float64, string) {
+	switch p := posterior.(type) {
+	case binomialTotalsPosterior:
+		return sumLogChoose(totals) + lbeta(p.alpha, p.beta) - lbeta(1, 1), "analytic"
+	case *betaBinomialTotalsPosterior:
+		transformed := make([][]float64, len(draws))
+		for s, d := range draws {
+			transformed[s] = []float64{math.Log(d.alpha / d.beta), math.Log(d.alpha + d.beta)}
+		}
+		logML, err := bridgeSamplingLogML(betaBinomialLogPosterior(totals), transformed, rng)
+		if err != nil || math.IsNaN(logML) || math.IsInf(logML, 0) {
+			return p.logEvidence, "quadrature"
+		}
+		return logML, "bridge-sampling"
+	}
+	return math.NaN(), ""
+}
+
+// Handler: Get model comparison
+// Fits each candidate model for Totals and ranks them by PSIS-LOO, also reporting WAIC,
+// Pareto k̂ diagnostics, marginal likelihoods, Bayes factors and posterior model probabilities
+func getModelComparison(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	models := []string{"binomial", "beta-binomial"}
+	if modelsStr := r.URL.Query().Get("models"); modelsStr != "" {
+		var err error
+		models, err = parseTotalsModelList(modelsStr)
+		if err != nil {
+			http.Error(w, "Invalid 'models' parameter (must be a comma separated list of binomial and beta-binomial)", http.StatusBadRequest)
+			return
+		}
+	}
+	draws, err := intParam(r, "draws", 4000)
+	if err != nil || draws < 100 || draws > 20000 {
+		http.Error(w, "Invalid 'draws' parameter (must be between 100 and 20000)", http.StatusBadRequest)
+		return
+	}
+	seed, err := intParam(r, "seed", 1)
+	if err != nil {
+		http.Error(w, "Invalid 'seed' parameter", http.StatusBadRequest)
+		return
+	}
+
+	if len(grades) == 0 {
+		http.Error(w, "No data available", http.StatusInternalServerError)
+		return
+	}
+	totals, err := gradeTotals()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := ModelComparisonResponse{N: len(totals), Draws: draws, Seed: int64(seed), Warnings: []string{}}
+	rng := rand.New(rand.NewSource(int64(seed)))
+	looPointwise := make([][]float64, len(models))
+
+	for m, model := range models {
+		posterior := fitTotalsModel(model, totals)
+		samples := make([]totalsDraw, draws)
+		for s := range samples {
+			samples[s] = posterior.sample(rng)
+		}
+		logLik := totalsLogLikelihoodMatrix(samples, totals)
+		waic := computeWAIC(logLik)
+		loo, paretoK := computePSISLOO(logLik)
+		looPointwise[m] = loo.elpd
+
+		logML, method := totalsLogMarginalLikelihood(posterior, samples, totals, rng)
+
+		entry := ModelComparisonEntry{
+			Model:                    model,
+			Posterior:                posterior.summary(),
+			WAIC:                     InformationCriterion{Elpd: waic.estimate, SE: waic.se, P: waic.pEstimate, Deviance: -2 * waic.estimate},
+			LOO:                      InformationCriterion{Elpd: loo.estimate, SE: loo.se, P: loo.pEstimate, Deviance: -2 * loo.estimate},
+			LogMarginalLikelihood:    logML,
+			MarginalLikelihoodMethod: method,
+		}
+		for _, k := range paretoK {
+			entry.ParetoK.Max = math.Max(entry.ParetoK.Max, k)
+			switch {
+			case k <= 0.5:
+				entry.ParetoK.Good++
+			case k <= 0.7:
+				entry.ParetoK.OK++
+			case k <= 1:
+				entry.ParetoK.Bad++
+			default:
+				entry.ParetoK.VeryBad++
+			}
+		}
+		if entry.ParetoK.Bad+entry.ParetoK.VeryBad > 0 {
+			response.Warnings = append(response.Warnings, model+": some Pareto k̂ exceed 0.7, PSIS-LOO may be unreliable")
+		}
+		response.Models = append(response.Models, entry)
+	}
+
+	// Bayes factors against the model with the highest marginal likelihood, and posterior model
+	// probabilities under equal prior model probabilities
+	logMLs := make([]float64, len(models))
+	for m := range response.Models {
+		logMLs[m] = response.Models[m].LogMarginalLikelihood
+	}
+	normalizer := logSumExp(logMLs)
+	bestML := math.Inf(-1)
+	for _, v := range logMLs {
+		bestML = math.Max(bestML, v)
+	}
+
+	order := make([]int, len(models))
+	for m := range order {
+		order[m] = m
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return response.Models[order[a]].LOO.Elpd > response.Models[order[b]].LOO.Elpd
+	})
+	best := order[0]
+	for rank, m := range order {
+		entry := &response.Models[m]
+		entry.Rank = rank + 1
+		entry.LogBayesFactor = logMLs[m] - bestML
+		entry.PosteriorProbability = math.Exp(logMLs[m] - normalizer)
+		if m != best {
+			diff := make([]float64, len(totals))
+			for i := range diff {
+				diff[i] = looPointwise[m][i] - looPointwise[best][i]
+			}
+			d := newPointwiseElpd(diff, make([]float64, len(diff)))
+			entry.ElpdDiff = d.estimate
+			entry.ElpdDiffSE = d.se
+		}
+	}
+	sort.SliceStable(response.Models, func(a, b int) bool { return response.Models[a].Rank < response.Models[b].Rank })
+
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+)
+
+// TestModelComparisonOverdispersed - 過分散データではベータ二項モデルが選ばれるテスト
+func TestModelComparisonOverdispersed(t *testing.T) {
+	totals := []int{}
+	for i := 0; i < 10; i++ {
+		totals = append(totals, 0, 1, 9, 10, 5)
+	}
+	setupTotalsTestData(totals...)
+
+	var result ModelComparisonResponse
+	serveJSON(t, getModelComparison, "GET", "/api/model-comparison?draws=2000", "", &result)
+
+	if len(result.Models) != 2 || result.Models[0].Model != "beta-binomial" || result.Models[0].Rank != 1 {
+		t.Fatalf("expected beta-binomial ranked first, got %+v", result.Models)
+	}
+	best, other := result.Models[0], result.Models[1]
+	if best.ElpdDiff != 0 || other.ElpdDiff >= 0 || other.ElpdDiffSE <= 0 {
+		t.Errorf("unexpected elpd differences: best %.3f, other %.3f ± %.3f", best.ElpdDiff, other.ElpdDiff, other.ElpdDiffSE)
+	}
+	if best.MarginalLikelihoodMethod != "bridge-sampling" || other.MarginalLikelihoodMethod != "analytic" {
+		t.Errorf("unexpected marginal likelihood methods %s, %s", best.MarginalLikelihoodMethod, other.MarginalLikelihoodMethod)
+	}
+	if other.LogBayesFactor > -10 || best.PosteriorProbability < 0.99 {
+		t.Errorf("expected decisive evidence for beta-binomial, got log BF %.3f and probability %.4f",
+			other.LogBayesFactor, best.PosteriorProbability)
+	}
+	// WAICとPSIS-LOOはほぼ一致する
+	for _, m := range result.Models {
+		if math.Abs(m.WAIC.Elpd-m.LOO.Elpd) > 1 {
+			t.Errorf("%s: WAIC %.3f and LOO %.3f disagree", m.Model, m.WAIC.Elpd, m.LOO.Elpd)
+		}
+		if m.ParetoK.Good+m.ParetoK.OK+m.ParetoK.Bad+m.ParetoK.VeryBad != 50 {
+			t.Errorf("%s: Pareto k̂ counts do not cover all students: %+v", m.Model, m.ParetoK)
+		}
+	}
+}
+
+// TestModelComparisonBinomialData - 二項分布のデータでは単純なモデルが支持されるテスト
+func TestModelComparisonBinomialData(t *testing.T) {
+	rng := rand.New(rand.NewSource(8))
+	totals := make([]int, 200)
+	for i := range totals {
+		totals[i] = sampleBinomial(rng, numQuestions, 0.6)
+	}
+	setupTotalsTestData(totals...)
+
+	var result ModelComparisonResponse
+	serveJSON(t, getModelComparison, "GET", "/api/model-comparison?draws=2000", "", &result)
+
+	for _, m := range result.Models {
+		if m.Model == "binomial" && m.PosteriorProbability < 0.5 {
+			t.Errorf("expected the marginal likelihood to favour the binomial model, got probability %.4f", m.PosteriorProbability)
+		}
+		if m.Rank == 2 && math.Abs(m.ElpdDiff) > 2*m.ElpdDiffSE+1 {
+			t.Errorf("expected indistinguishable predictive accuracy, got diff %.3f ± %.3f", m.ElpdDiff, m.ElpdDiffSE)
+		}
+	}
+}
+
+// TestBridgeSamplingMatchesQuadrature - ブリッジサンプリングと格子求積の一致のテスト
+func TestBridgeSamplingMatchesQuadrature(t *testing.T) {
+	totals := []int{2, 3, 5, 5, 6, 7, 7, 8, 9, 10, 4, 6}
+	posterior := fitBetaBinomialGrid(totals)
+	rng := rand.New(rand.NewSource(2))
+	draws := make([]totalsDraw, 4000)
+	for s := range draws {
+		draws[s] = posterior.sample(rng)
+	}
+
+	logML, method := totalsLogMarginalLikelihood(posterior, draws, totals, rng)
+	quadrature := posterior.logEvidence
+	if method != "bridge-sampling" || math.Abs(logML-quadrature) > 0.05 {
+		t.Errorf("bridge sampling %.4f (%s) differs from quadrature %.4f", logML, method, quadrature)
+	}
+}
+
+// TestFitGeneralizedPareto - 指数分布（k = 0）と裾の重い分布の形状推定のテスト
+func TestFitGeneralizedPareto(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	sample := func(k float64) []float64 {
+		x := make([]float64, 2000)
+		for i := range x {
+			x[i] = generalizedParetoQuantile(rng.Float64(), k, 1)
+		}
+		sort.Float64s(x)
+		return x
+	}
+	if k, _ := fitGeneralizedPareto(sample(0)); math.Abs(k) > 0.1 {
+		t.Errorf("expected k near 0 for exponential data, got %.4f", k)
+	}
+	if k, _ := fitGeneralizedPareto(sample(0.8)); math.Abs(k-0.8) > 0.15 {
+		t.Errorf("expected k near 0.8, got %.4f", k)
+	}
+}
+
+// TestModelComparisonInvalidParams - 無効なパラメータのテスト
+func TestModelComparisonInvalidParams(t *testing.T) {
+	setupTestData()
+
+	testCases := []struct {
+		name  string
+		query string
+	}{
+		{"invalid_models", "models=binomial,poisson"},
+		{"invalid_draws", "draws=10"},
+		{"invalid_seed", "seed=x"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "/api/model-comparison?"+tc.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(getModelComparison)
+			handler.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != http.StatusBadRequest {
+				t.Errorf("handler returned wrong status code: got %v want %v",
+					status, http.StatusBadRequest)
+			}
+		})
+	}
+}
+
+// TestModelComparisonEmptyData - データが空の場合のテスト
+func TestModelComparisonEmptyData(t *testing.T) {
+	grades = []Grade{}
+
+	req, err := http.NewRequest("GET", "/api/model-comparison", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(getModelComparison)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusInternalServerError {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusInternalServerError)
+	}
+}
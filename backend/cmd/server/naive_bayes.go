@@ -0,0 +1,385 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// NaiveBayesItemParameters represents the class-conditional correct rates of one item
+type NaiveBayesItemParameters struct {
+	Item              string  `json:"item"`
+	PCorrectGivenPass float64 `json:"p_correct_given_pass"`
+	PCorrectGivenFail float64 `json:"p_correct_given_fail"`
+}
+
+// NaiveBayesPrediction represents the posterior pass probability for a partial response pattern
+type NaiveBayesPrediction struct {
+	Responses            map[string]int `json:"responses"`
+	PassProbability      float64        `json:"pass_probability"`
+	PriorPassProbability float64        `json:"prior_pass_probability"`
+}
+
+// CalibrationBin represents predicted vs observed pass rates within one probability bin
+type CalibrationBin struct {
+	Lower         float64  `json:"lower"`
+	Upper         float64  `json:"upper"`
+	Count         int      `json:"count"`
+	MeanPredicted *float64 `json:"mean_predicted"`
+	ObservedRate  *float64 `json:"observed_rate"`
+}
+
+// ClassifierEvaluation represents cross-validated performance of a pass/fail classifier
+type ClassifierEvaluation struct {
+	Folds       int              `json:"folds"`
+	Seed        int64            `json:"seed"`
+	Accuracy    float64          `json:"accuracy"`
+	BrierScore  float64          `json:"brier_score"`
+	ROCAUC      *float64         `json:"roc_auc"`
+	Calibration []CalibrationBin `json:"calibration"`
+}
+
+// NaiveBayesResponse represents a Bernoulli Naive Bayes model of Total ≥ threshold
+type NaiveBayesResponse struct {
+	Threshold            int                        `json:"threshold"`
+	Items                []string                   `json:"items"`
+	Alpha                float64                    `json:"alpha"`
+	PassCount            int                        `json:"pass_count"`
+	FailCount            int                        `json:"fail_count"`
+	PriorPassProbability float64                    `json:"prior_pass_probability"`
+	ItemParameters       []NaiveBayesItemParameters `json:"item_parameters"`
+	Prediction           *NaiveBayesPrediction      `json:"prediction"`
+	CrossValidation      ClassifierEvaluation       `json:"cross_validation"`
+}
+
+// naiveBayesModel is a Bernoulli Naive Bayes classifier; index 0 is fail and 1 is pass
+type naiveBayesModel struct {
+	items     []int
+	prior     [2]float64
+	pCorrect  [2][]float64
+	passCount int
+	failCount int
+}
+
+// trainNaiveBayes fits the model with Laplace (add-alpha) smoothing
+func trainNaiveBayes(data []Grade, items []int, threshold int, alpha float64) naiveBayesModel {
+	var classCount [2]int
+	correct := [2][]int{make([]int, len(items)), make([]int, len(items))}
+
+	for _, g := range data {
+		class := 0
+		if g.Total >= threshold {
+			class = 1
+		}
+		classCount[class]++
+		for k, q := range items {
+			correct[class][k] += getQuestionValue(g, q)
+		}
+	}
+
+	model := naiveBayesModel{
+		items:     items,
+		pCorrect:  [2][]float64{make([]float64, len(items)), make([]float64, len(items))},
+		failCount: classCount[0],
+		passCount: classCount[1],
+	}
+	n := float64(len(data))
+	for c := 0; c < 2; c++ {
+		model.prior[c] = (float64(classCount[c]) + alpha) / (n + 2*alpha)
+		for k := range items {
+			model.pCorrect[c][k] = (float64(correct[c][k]) + alpha) / (float64(classCount[c]) + 2*alpha)
+		}
+	}
+	return model
+}
+
+// passProbability returns P(pass | responses). Items missing from responses are marginalized
+// out, which for Naive Bayes simply means they do not contribute to the likelihood.
+func (m naiveBayesModel) passProbability(responses map[int]int) float64 {
+	logOdds := math.Log(m.prior[1]) - math.Log(m.prior[0])
+	for k, q := range m.items {
+		value, observed := responses[q]
+		if !observed {
+			continue
+		}
+		if value == 1 {
+			logOdds += math.Log(m.pCorrect[1][k]) - math.Log(m.pCorrect[0][k])
+		} else {
+			logOdds += math.Log(1-m.pCorrect[1][k]) - math.Log(1-m.pCorrect[0][k])
+		}
+	}
+	return sigmoid(logOdds)
+}
+
+// gradeResponses returns the responses of a grade to the given items
+func gradeResponses(g Grade, items []int) map[int]int {
+	responses := make(map[int]int, len(items))
+	for _, q := range items {
+		responses[q] = getQuestionValue(g, q)
+	}
+	return responses
+}
+
+// parseResponsePattern parses "q1:1,q2:0" into question number → response (0 or 1)
+func parseResponsePattern(s string) (map[int]int, error) {
+	responses := make(map[int]int)
+	for _, part := range strings.Split(s, ",") {
+		fields := strings.SplitN(strings.TrimSpace(part), ":", 2)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid response %q (must be qN:0 or qN:1)", part)
+		}
+		q, err := parseQuestion(fields[0])
+		if err != nil {
+			return nil, err
+		}
+		value, err := strconv.Atoi(fields[1])
+		if err != nil || (value != 0 && value != 1) {
+			return nil, fmt.Errorf("invalid response %q (must be qN:0 or qN:1)", part)
+		}
+		responses[q] = value
+	}
+	return responses, nil
+}
+
+// crossValidationFolds assigns each of n students to one of k folds after a seeded shuffle
+func crossValidationFolds(n, k int, seed int64) []int {
+	fold := make([]int, n)
+	for i, idx := range rand.New(rand.NewSource(seed)).Perm(n) {
+		fold[idx] = i % k
+	}
+	return fold
+}
+
+// calculateAUC returns the ROC AUC (Mann–Whitney U with average ranks for ties),
+// or nil when only one class is present
+func calculateAUC(scores []float64, labels []bool) *float64 {
+	order := make([]int, len(scores))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool { return scores[order[a]] < scores[order[b]] })
+
+	ranks := make([]float64, len(scores))
+	for i := 0; i < len(order); {
+		j := i
+		for j+1 < len(order) && scores[order[j+1]] == scores[order[i]] {
+			j++
+		}
+		averageRank := float64(i+j)/2 + 1
+		for k := i; k <= j; k++ {
+			ranks[order[k]] = averageRank
+		}
+		i = j + 1
+	}
+
+	var positives, negatives int
+	var rankSum float64
+	for i, label := range labels {
+		if label {
+			positives++
+			rankSum += ranks[i]
+		} else {
+			negatives++
+		}
+	}
+	if positives == 0 || negatives == 0 {
+		return nil
+	}
+	auc := (rankSum - float64(positives*(positives+1))/2) / float64(positives*negatives)
+	return &auc
+}
+
+// calculateCalibration groups predictions into equal-width probability bins
+func calculateCalibration(predictions []float64, labels []bool, bins int) []CalibrationBin {
+	counts := make([]int, bins)
+	sumPredicted := make([]float64, bins)
+	sumObserved := make([]float64, bins)
+	for i, p := range predictions {
+		b := int(p * float64(bins))
+		if b >= bins {
+			b = bins - 1
+		}
+		counts[b]++
+		sumPredicted[b] += p
+		if labels[i] {
+			sumObserved[b]++
+		}
+	}
+
+	calibration := make([]CalibrationBin, bins)
+	for b := 0; b < bins; b++ {
+		calibration[b] = CalibrationBin{
+			Lower: float64(b) / float64(bins),
+			Upper: float64(b+1) / float64(bins),
+			Count: counts[b],
+		}
+		if counts[b] > 0 {
+			calibration[b].MeanPredicted = finiteOrNil(sumPredicted[b] / float64(counts[b]))
+			calibration[b].ObservedRate = finiteOrNil(sumObserved[b] / float64(counts[b]))
+		}
+	}
+	return calibration
+}
+
+// evaluateClassifier summarises out-of-fold predictions
+func evaluateClassifier(predictions []float64, labels []bool, folds int, seed int64) ClassifierEvaluation {
+	var correct int
+	var brier float64
+	for i, p := range predictions {
+		label := 0.0
+		if labels[i] {
+			label = 1.0
+		}
+		if (p >= 0.5) == labels[i] {
+			correct++
+		}
+		brier += (p - label) * (p - label)
+	}
+	return ClassifierEvaluation{
+		Folds:       folds,
+		Seed:        seed,
+		Accuracy:    float64(correct) / float64(len(predictions)),
+		BrierScore:  brier / float64(len(predictions)),
+		ROCAUC:      calculateAUC(predictions, labels),
+		Calibration: calculateCalibration(predictions, labels, 10),
+	}
+}
+
+// Handler: Get Naive Bayes pass prediction
+// Trains a Bernoulli Naive Bayes model of Total ≥ threshold on the chosen items, predicts the
+// pass probability of an optional partial response pattern and reports k-fold cross-validation
+func getNaiveBayes(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	thresholdStr := r.URL.Query().Get("threshold")
+	if thresholdStr == "" {
+		http.Error(w, "Missing 'threshold' parameter", http.StatusBadRequest)
+		return
+	}
+	threshold, err := strconv.Atoi(thresholdStr)
+	if err != nil {
+		http.Error(w, "Invalid 'threshold' parameter", http.StatusBadRequest)
+		return
+	}
+
+	items := allQuestions()
+	if itemsStr := r.URL.Query().Get("items"); itemsStr != "" {
+		items, err = parseQuestionList(itemsStr)
+		if err != nil {
+			http.Error(w, "Invalid 'items' parameter (must be a comma separated list of q1-q10)", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var responses map[int]int
+	if responsesStr := r.URL.Query().Get("responses"); responsesStr != "" {
+		responses, err = parseResponsePattern(responsesStr)
+		if err != nil {
+			http.Error(w, "Invalid 'responses' parameter (must be like q1:1,q2:0)", http.StatusBadRequest)
+			return
+		}
+		inItems := make(map[int]bool, len(items))
+		for _, q := range items {
+			inItems[q] = true
+		}
+		for q := range responses {
+			if !inItems[q] {
+				http.Error(w, fmt.Sprintf("Invalid 'responses' parameter (q%d is not one of the configured items)", q), http.StatusBadRequest)
+				return
+			}
+		}
+	}
+
+	alpha, err := floatParam(r, "alpha", 1.0)
+	if err != nil || alpha <= 0 {
+		http.Error(w, "Invalid 'alpha' parameter (must be positive)", http.StatusBadRequest)
+		return
+	}
+	folds, err := intParam(r, "folds", 5)
+	if err != nil || folds < 2 {
+		http.Error(w, "Invalid 'folds' parameter (must be at least 2)", http.StatusBadRequest)
+		return
+	}
+	seed, err := intParam(r, "seed", 1)
+	if err != nil {
+		http.Error(w, "Invalid 'seed' parameter", http.StatusBadRequest)
+		return
+	}
+
+	if len(grades) == 0 {
+		http.Error(w, "No data available", http.StatusInternalServerError)
+		return
+	}
+
+	// The full model uses the same items as cross-validation, so its evaluation applies to the prediction
+	model := trainNaiveBayes(grades, items, threshold, alpha)
+
+	labels := make([]string, len(items))
+	for k, q := range items {
+		labels[k] = fmt.Sprintf("q%d", q)
+	}
+
+	itemParameters := make([]NaiveBayesItemParameters, len(items))
+	for k := range items {
+		itemParameters[k] = NaiveBayesItemParameters{
+			Item:              labels[k],
+			PCorrectGivenPass: model.pCorrect[1][k],
+			PCorrectGivenFail: model.pCorrect[0][k],
+		}
+	}
+
+	// k-fold cross-validation using only the configured items as features
+	if folds > len(grades) {
+		folds = len(grades)
+	}
+	foldOf := crossValidationFolds(len(grades), folds, int64(seed))
+	predictions := make([]float64, len(grades))
+	passed := make([]bool, len(grades))
+	for f := 0; f < folds; f++ {
+		var train []Grade
+		for i, g := range grades {
+			if foldOf[i] != f {
+				train = append(train, g)
+			}
+		}
+		foldModel := trainNaiveBayes(train, items, threshold, alpha)
+		for i, g := range grades {
+			if foldOf[i] == f {
+				predictions[i] = foldModel.passProbability(gradeResponses(g, items))
+			}
+		}
+	}
+	for i, g := range grades {
+		passed[i] = g.Total >= threshold
+	}
+
+	response := NaiveBayesResponse{
+		Threshold:            threshold,
+		Items:                labels,
+		Alpha:                alpha,
+		PassCount:            model.passCount,
+		FailCount:            model.failCount,
+		PriorPassProbability: model.prior[1],
+		ItemParameters:       itemParameters,
+		CrossValidation:      evaluateClassifier(predictions, passed, folds, int64(seed)),
+	}
+
+	if responses != nil {
+		named := make(map[string]int, len(responses))
+		for q, value := range responses {
+			named[fmt.Sprintf("q%d", q)] = value
+		}
+		response.Prediction = &NaiveBayesPrediction{
+			Responses:            named,
+			PassProbability:      model.passProbability(responses),
+			PriorPassProbability: model.prior[1],
+		}
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
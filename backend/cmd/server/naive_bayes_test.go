@@ -0,0 +1,146 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestNaiveBayesPrediction - 部分的な解答パターンからの合格確率予測テスト
+func TestNaiveBayesPrediction(t *testing.T) {
+	setupTestData()
+	// threshold=8 で合格は Student1 のみ（Laplace平滑化 alpha=1）
+	// P(pass) = (1+1)/(3+2) = 0.4
+	// P(Q1=1 | pass) = (1+1)/(1+2) = 2/3, P(Q1=1 | fail) = (1+1)/(2+2) = 0.5
+	// P(pass | Q1=1) = 0.4*(2/3) / (0.4*(2/3) + 0.6*0.5) = 8/17
+
+	var result NaiveBayesResponse
+	serveJSON(t, getNaiveBayes, "GET", "/api/naive-bayes?threshold=8&responses=q1:1", "", &result)
+
+	if math.Abs(result.PriorPassProbability-0.4) > 1e-9 {
+		t.Errorf("expected prior pass probability 0.4, got %.4f", result.PriorPassProbability)
+	}
+	if result.Prediction == nil {
+		t.Fatal("prediction is missing")
+	}
+	if math.Abs(result.Prediction.PassProbability-8.0/17.0) > 1e-9 {
+		t.Errorf("expected pass probability %.4f, got %.4f", 8.0/17.0, result.Prediction.PassProbability)
+	}
+	if len(result.ItemParameters) != 10 {
+		t.Errorf("expected parameters for 10 items, got %d", len(result.ItemParameters))
+	}
+	// 学生数が分割数より少ない場合は分割数を学生数に合わせる
+	if result.CrossValidation.Folds != 3 {
+		t.Errorf("expected folds to be capped at 3, got %d", result.CrossValidation.Folds)
+	}
+}
+
+// TestNaiveBayesCrossValidation - 完全に分離できるデータでの交差検証テスト
+func TestNaiveBayesCrossValidation(t *testing.T) {
+	// Q1の正誤が合否と一致するデータ
+	grades = []Grade{}
+	for i := 0; i < 20; i++ {
+		if i%2 == 0 {
+			grades = append(grades, Grade{StudentID: i + 1, Q1: 1, Total: 8})
+		} else {
+			grades = append(grades, Grade{StudentID: i + 1, Q1: 0, Total: 3})
+		}
+	}
+
+	var result NaiveBayesResponse
+	serveJSON(t, getNaiveBayes, "GET", "/api/naive-bayes?threshold=6&items=q1&folds=4&seed=42", "", &result)
+
+	cv := result.CrossValidation
+	if cv.Accuracy != 1.0 {
+		t.Errorf("expected accuracy 1.0, got %.4f", cv.Accuracy)
+	}
+	if cv.ROCAUC == nil || *cv.ROCAUC != 1.0 {
+		t.Errorf("expected ROC AUC 1.0, got %v", cv.ROCAUC)
+	}
+	if len(cv.Calibration) != 10 {
+		t.Errorf("expected 10 calibration bins, got %d", len(cv.Calibration))
+	}
+	binned := 0
+	for _, bin := range cv.Calibration {
+		binned += bin.Count
+	}
+	if binned != 20 {
+		t.Errorf("expected all 20 predictions to be binned, got %d", binned)
+	}
+	if result.Prediction != nil {
+		t.Error("expected no prediction without responses")
+	}
+}
+
+// TestCalculateAUC - ROC AUCの計算テスト
+func TestCalculateAUC(t *testing.T) {
+	scores := []float64{0.1, 0.4, 0.35, 0.8}
+	labels := []bool{false, false, true, true}
+
+	auc := calculateAUC(scores, labels)
+	if auc == nil || math.Abs(*auc-0.75) > 1e-9 {
+		t.Errorf("expected AUC 0.75, got %v", auc)
+	}
+
+	// 片方のクラスしかない場合は定義できない
+	if calculateAUC([]float64{0.2, 0.3}, []bool{true, true}) != nil {
+		t.Error("expected nil AUC with a single class")
+	}
+}
+
+// TestNaiveBayesInvalidParams - 無効なパラメータのテスト
+func TestNaiveBayesInvalidParams(t *testing.T) {
+	setupTestData()
+
+	testCases := []struct {
+		name  string
+		query string
+	}{
+		{"missing_threshold", "items=q1"},
+		{"invalid_threshold", "threshold=abc"},
+		{"invalid_items", "threshold=6&items=q1,q12"},
+		{"invalid_responses", "threshold=6&responses=q1:2"},
+		{"invalid_response_format", "threshold=6&responses=q1=1"},
+		{"response_outside_items", "threshold=6&items=q1,q2&responses=q3:1"},
+		{"invalid_alpha", "threshold=6&alpha=0"},
+		{"invalid_folds", "threshold=6&folds=1"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "/api/naive-bayes?"+tc.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(getNaiveBayes)
+			handler.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != http.StatusBadRequest {
+				t.Errorf("handler returned wrong status code: got %v want %v",
+					status, http.StatusBadRequest)
+			}
+		})
+	}
+}
+
+// TestNaiveBayesEmptyData - データが空の場合のテスト
+func TestNaiveBayesEmptyData(t *testing.T) {
+	grades = []Grade{}
+
+	req, err := http.NewRequest("GET", "/api/naive-bayes?threshold=6", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(getNaiveBayes)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusInternalServerError {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusInternalServerError)
+	}
+}
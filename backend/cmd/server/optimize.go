@@ -0,0 +1,113 @@
+package main
+
+import (
+	"math"
+	"sort"
+)
+
+// nelderMead minimizes f from x0 with the Nelder–Mead simplex method. It returns the best
+// point, its value and whether the simplex converged within maxIter iterations. f may return
+// +Inf to mark points outside the feasible region.
+func nelderMead(f func([]float64) float64, x0 []float64, step float64, maxIter int) ([]float64, float64, bool) {
+	const (
+		reflection  = 1.0
+		expansion   = 2.0
+		contraction = 0.5
+		shrink      = 0.5
+		tolerance   = 1e-10
+	)
+	d := len(x0)
+	simplex := make([][]float64, d+1)
+	values := make([]float64, d+1)
+	for i := range simplex {
+		simplex[i] = append([]float64(nil), x0...)
+		if i > 0 {
+			simplex[i][i-1] += step
+		}
+		values[i] = f(simplex[i])
+	}
+
+	order := make([]int, d+1)
+	for iter := 0; iter < maxIter; iter++ {
+		for i := range order {
+			order[i] = i
+		}
+		sort.Slice(order, func(a, b int) bool { return values[order[a]] < values[order[b]] })
+		best, worst, secondWorst := order[0], order[d], order[d-1]
+
+		if math.Abs(values[worst]-values[best]) <= tolerance*(math.Abs(values[best])+tolerance) {
+			return simplex[best], values[best], true
+		}
+
+		// Centroid of every point but the worst
+		centroid := make([]float64, d)
+		for _, i := range order[:d] {
+			for a := range centroid {
+				centroid[a] += simplex[i][a] / float64(d)
+			}
+		}
+		along := func(coef float64) []float64 {
+			x := make([]float64, d)
+			for a := range x {
+				x[a] = centroid[a] + coef*(simplex[worst][a]-centroid[a])
+			}
+			return x
+		}
+
+		reflected := along(-reflection)
+		reflectedValue := f(reflected)
+		switch {
+		case reflectedValue < values[best]:
+			expanded := along(-expansion)
+			if expandedValue := f(expanded); expandedValue < reflectedValue {
+				simplex[worst], values[worst] = expanded, expandedValue
+			} else {
+				simplex[worst], values[worst] = reflected, reflectedValue
+			}
+		case reflectedValue < values[secondWorst]:
+			simplex[worst], values[worst] = reflected, reflectedValue
+		default:
+			contracted := along(contraction)
+			if contractedValue := f(contracted); contractedValue < values[worst] {
+				simplex[worst], values[worst] = contracted, contractedValue
+				continue
+			}
+			for _, i := range order[1:] {
+				for a := range simplex[i] {
+					simplex[i][a] = simplex[best][a] + shrink*(simplex[i][a]-simplex[best][a])
+				}
+				values[i] = f(simplex[i])
+			}
+		}
+	}
+
+	best := 0
+	for i := range values {
+		if values[i] < values[best] {
+			best = i
+		}
+	}
+	return simplex[best], values[best], false
+}
+
+// numericalHessian approximates the Hessian of f at x by central differences with step h
+func numericalHessian(f func([]float64) float64, x []float64, h float64) [][]float64 {
+	d := len(x)
+	hessian := newMatrix(d, d)
+	at := func(da, db int, sa, sb float64) float64 {
+		p := append([]float64(nil), x...)
+		p[da] += sa * h
+		p[db] += sb * h
+		return f(p)
+	}
+	fx := f(x)
+	for a := 0; a < d; a++ {
+		hessian[a][a] = (at(a, a, 0.5, 0.5) - 2*fx + at(a, a, -0.5, -0.5)) / (h * h)
+		for b := a + 1; b < d; b++ {
+			v := (at(a, b, 1, 1) - at(a, b, 1, -1) - at(a, b, -1, 1) + at(a, b, -1, -1)) / (4 * h * h)
+			hessian[a][b] = v
+			hessian[b][a] = v
+		}
+	}
+	return hessian
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// PartialCorrelationResponse represents the item correlation matrix after partialling out ability
+type PartialCorrelationResponse struct {
+	Control        string      `json:"control"`
+	Matrix         [][]float64 `json:"matrix"`
+	QuestionLabels []string    `json:"question_labels"`
+}
+
+// MantelHaenszelStratum represents the 2×2 table of two items within one score stratum
+type MantelHaenszelStratum struct {
+	Score         int     `json:"score"`
+	N             int     `json:"n"`
+	BothCorrect   int     `json:"both_correct"`
+	OnlyACorrect  int     `json:"only_a_correct"`
+	OnlyBCorrect  int     `json:"only_b_correct"`
+	BothIncorrect int     `json:"both_incorrect"`
+	ExpectedBoth  float64 `json:"expected_both_correct"`
+	VarianceBoth  float64 `json:"variance_both_correct"`
+}
+
+// ConditionalIndependenceResponse represents a Mantel–Haenszel test of Qa ⊥ Qb | score
+type ConditionalIndependenceResponse struct {
+	ItemA              string                  `json:"item_a"`
+	ItemB              string                  `json:"item_b"`
+	Stratify           string                  `json:"stratify"`
+	Alpha              float64                 `json:"alpha"`
+	PartialCorrelation float64                 `json:"partial_correlation"`
+	Strata             []MantelHaenszelStratum `json:"strata"`
+	ChiSquare          *float64                `json:"chi_square"`
+	PValue             *float64                `json:"p_value"`
+	CommonOddsRatio    *float64                `json:"common_odds_ratio"`
+	RejectIndependence bool                    `json:"reject_independence"`
+}
+
+// parseControl validates the ability proxy to condition on: "total" (default) or "rest"
+func parseControl(s string) (string, bool) {
+	switch s {
+	case "", "total":
+		return "total", true
+	case "rest":
+		return "rest", true
+	default:
+		return "", false
+	}
+}
+
+// controlScores returns the conditioning score of every student for the item pair (a, b).
+// "rest" removes both items from Total so that they do not contribute to their own stratum.
+func controlScores(control string, a, b int) []float64 {
+	scores := make([]float64, len(grades))
+	for i, g := range grades {
+		scores[i] = float64(g.Total)
+		if control == "rest" {
+			scores[i] -= float64(getQuestionValue(g, a) + getQuestionValue(g, b))
+		}
+	}
+	return scores
+}
+
+// calculatePartialCorrelation calculates the first-order partial correlation r_ab·z
+func calculatePartialCorrelation(a, b int, control string) float64 {
+	x := itemColumn(a)
+	y := itemColumn(b)
+	z := controlScores(control, a, b)
+
+	rxy := pearson(x, y)
+	rxz := pearson(x, z)
+	ryz := pearson(y, z)
+
+	denom := (1 - rxz*rxz) * (1 - ryz*ryz)
+	if denom <= 0 {
+		return 0.0
+	}
+	r := (rxy - rxz*ryz) / math.Sqrt(denom)
+	// Guard against rounding pushing the value just outside [-1, 1]
+	return math.Max(-1.0, math.Min(1.0, r))
+}
+
+// Handler: Get partial correlation matrix
+// Calculates r(Qi, Qj | Total) or r(Qi, Qj | rest-score) for all question pairs
+func getPartialCorrelationMatrix(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	control, ok := parseControl(r.URL.Query().Get("control"))
+	if !ok {
+		http.Error(w, "Invalid 'control' parameter (must be total or rest)", http.StatusBadRequest)
+		return
+	}
+
+	if len(grades) == 0 {
+		http.Error(w, "No data available", http.StatusInternalServerError)
+		return
+	}
+
+	matrix := make([][]float64, numQuestions)
+	for i := 0; i < numQuestions; i++ {
+		matrix[i] = make([]float64, numQuestions)
+	}
+
+	for i := 1; i <= numQuestions; i++ {
+		matrix[i-1][i-1] = 1.0
+		for j := i + 1; j <= numQuestions; j++ {
+			value := calculatePartialCorrelation(i, j, control)
+			matrix[i-1][j-1] = value
+			matrix[j-1][i-1] = value
+		}
+	}
+
+	response := PartialCorrelationResponse{
+		Control:        control,
+		Matrix:         matrix,
+		QuestionLabels: questionLabels(),
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// Handler: Get conditional independence test
+// Mantel–Haenszel test of Qa ⊥ Qb stratified on Total or rest-score (local independence check)
+func getConditionalIndependence(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	aStr := r.URL.Query().Get("a")
+	bStr := r.URL.Query().Get("b")
+
+	if aStr == "" {
+		http.Error(w, "Missing 'a' parameter", http.StatusBadRequest)
+		return
+	}
+	if bStr == "" {
+		http.Error(w, "Missing 'b' parameter", http.StatusBadRequest)
+		return
+	}
+
+	a, err := parseQuestion(aStr)
+	if err != nil {
+		http.Error(w, "Invalid 'a' parameter (must be q1-q10)", http.StatusBadRequest)
+		return
+	}
+	b, err := parseQuestion(bStr)
+	if err != nil {
+		http.Error(w, "Invalid 'b' parameter (must be q1-q10)", http.StatusBadRequest)
+		return
+	}
+	if a == b {
+		http.Error(w, "Parameters 'a' and 'b' must be different items", http.StatusBadRequest)
+		return
+	}
+
+	stratify, ok := parseControl(r.URL.Query().Get("stratify"))
+	if !ok {
+		http.Error(w, "Invalid 'stratify' parameter (must be total or rest)", http.StatusBadRequest)
+		return
+	}
+
+	alpha := 0.05
+	if alphaStr := r.URL.Query().Get("alpha"); alphaStr != "" {
+		alpha, err = strconv.ParseFloat(alphaStr, 64)
+		if err != nil || alpha <= 0 || alpha >= 1 {
+			http.Error(w, "Invalid 'alpha' parameter (must be between 0 and 1)", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if len(grades) == 0 {
+		http.Error(w, "No data available", http.StatusInternalServerError)
+		return
+	}
+
+	// Build one 2×2 table per score stratum
+	scores := controlScores(stratify, a, b)
+	byScore := make(map[int]*MantelHaenszelStratum)
+	for i, g := range grades {
+		score := int(scores[i])
+		stratum, exists := byScore[score]
+		if !exists {
+			stratum = &MantelHaenszelStratum{Score: score}
+			byScore[score] = stratum
+		}
+		stratum.N++
+		va := getQuestionValue(g, a)
+		vb := getQuestionValue(g, b)
+		switch {
+		case va == 1 && vb == 1:
+			stratum.BothCorrect++
+		case va == 1:
+			stratum.OnlyACorrect++
+		case vb == 1:
+			stratum.OnlyBCorrect++
+		default:
+			stratum.BothIncorrect++
+		}
+	}
+
+	// Accumulate Mantel–Haenszel sums over strata in score order
+	var sumObserved, sumExpected, sumVariance, orNumerator, orDenominator float64
+	scoreKeys := make([]int, 0, len(byScore))
+	for score := range byScore {
+		scoreKeys = append(scoreKeys, score)
+	}
+	sort.Ints(scoreKeys)
+
+	strata := []MantelHaenszelStratum{}
+	for _, score := range scoreKeys {
+		stratum := byScore[score]
+		n := float64(stratum.N)
+		aCorrect := float64(stratum.BothCorrect + stratum.OnlyACorrect)
+		aIncorrect := n - aCorrect
+		bCorrect := float64(stratum.BothCorrect + stratum.OnlyBCorrect)
+		bIncorrect := n - bCorrect
+
+		stratum.ExpectedBoth = aCorrect * bCorrect / n
+		if stratum.N > 1 {
+			stratum.VarianceBoth = aCorrect * aIncorrect * bCorrect * bIncorrect / (n * n * (n - 1))
+		}
+
+		sumObserved += float64(stratum.BothCorrect)
+		sumExpected += stratum.ExpectedBoth
+		sumVariance += stratum.VarianceBoth
+		orNumerator += float64(stratum.BothCorrect*stratum.BothIncorrect) / n
+		orDenominator += float64(stratum.OnlyACorrect*stratum.OnlyBCorrect) / n
+
+		strata = append(strata, *stratum)
+	}
+
+	response := ConditionalIndependenceResponse{
+		ItemA:              aStr,
+		ItemB:              bStr,
+		Stratify:           stratify,
+		Alpha:              alpha,
+		PartialCorrelation: calculatePartialCorrelation(a, b, stratify),
+		Strata:             strata,
+		CommonOddsRatio:    finiteOrNil(orNumerator / orDenominator),
+	}
+
+	// The test is undefined when no stratum has variation in both items
+	if sumVariance > 0 {
+		// Continuity-corrected Mantel–Haenszel statistic (1 df)
+		diff := math.Max(math.Abs(sumObserved-sumExpected)-0.5, 0)
+		chiSquare := diff * diff / sumVariance
+		pValue := chiSquarePValue(chiSquare, 1)
+		response.ChiSquare = &chiSquare
+		response.PValue = &pValue
+		response.RejectIndependence = pValue < alpha
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
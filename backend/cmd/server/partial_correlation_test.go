@@ -0,0 +1,167 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestPartialCorrelationMatrix - 偏相関マトリックスの正常系テスト
+func TestPartialCorrelationMatrix(t *testing.T) {
+	setupTestData()
+
+	for _, control := range []string{"total", "rest"} {
+		t.Run(control, func(t *testing.T) {
+			var result PartialCorrelationResponse
+			serveJSON(t, getPartialCorrelationMatrix, "GET", "/api/partial-correlation?control="+control, "", &result)
+
+			if result.Control != control {
+				t.Errorf("expected control %s, got %s", control, result.Control)
+			}
+			if len(result.Matrix) != 10 {
+				t.Fatalf("expected 10 rows, got %d", len(result.Matrix))
+			}
+
+			// 対角成分は1、対称行列、値は-1〜1
+			for i := 0; i < 10; i++ {
+				if result.Matrix[i][i] != 1.0 {
+					t.Errorf("diagonal element [%d][%d] should be 1.0, got %.4f", i, i, result.Matrix[i][i])
+				}
+				for j := 0; j < 10; j++ {
+					if result.Matrix[i][j] != result.Matrix[j][i] {
+						t.Errorf("matrix not symmetric at [%d][%d]", i, j)
+					}
+					if result.Matrix[i][j] < -1.0 || result.Matrix[i][j] > 1.0 {
+						t.Errorf("partial correlation [%d][%d]=%.4f is out of range", i, j, result.Matrix[i][j])
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestPartialCorrelationInvalidControl - 無効なcontrolパラメータのテスト
+func TestPartialCorrelationInvalidControl(t *testing.T) {
+	setupTestData()
+
+	req, err := http.NewRequest("GET", "/api/partial-correlation?control=q1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(getPartialCorrelationMatrix)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusBadRequest)
+	}
+}
+
+// TestPartialCorrelationEmptyData - データが空の場合のテスト
+func TestPartialCorrelationEmptyData(t *testing.T) {
+	grades = []Grade{}
+
+	req, err := http.NewRequest("GET", "/api/partial-correlation", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(getPartialCorrelationMatrix)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusInternalServerError {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusInternalServerError)
+	}
+}
+
+// TestConditionalIndependence - Mantel-Haenszel検定の正常系テスト
+func TestConditionalIndependence(t *testing.T) {
+	// Total=5の1層のみ: (Q1,Q2) = (1,1)×3, (0,0)×3, (1,0)×1, (0,1)×1
+	// E[a] = 4*4/8 = 2, Var[a] = 4*4*4*4/(8*8*7) = 4/7
+	// χ² = (|3-2|-0.5)² / (4/7) = 0.4375, OR_MH = (3*3/8)/(1*1/8) = 9
+	grades = []Grade{
+		{StudentID: 1, Q1: 1, Q2: 1, Total: 5},
+		{StudentID: 2, Q1: 1, Q2: 1, Total: 5},
+		{StudentID: 3, Q1: 1, Q2: 1, Total: 5},
+		{StudentID: 4, Q1: 0, Q2: 0, Total: 5},
+		{StudentID: 5, Q1: 0, Q2: 0, Total: 5},
+		{StudentID: 6, Q1: 0, Q2: 0, Total: 5},
+		{StudentID: 7, Q1: 1, Q2: 0, Total: 5},
+		{StudentID: 8, Q1: 0, Q2: 1, Total: 5},
+	}
+
+	var result ConditionalIndependenceResponse
+	serveJSON(t, getConditionalIndependence, "GET", "/api/conditional-independence?a=q1&b=q2", "", &result)
+
+	if len(result.Strata) != 1 || result.Strata[0].N != 8 {
+		t.Fatalf("expected a single stratum of 8 students, got %+v", result.Strata)
+	}
+	if result.ChiSquare == nil || *result.ChiSquare < 0.4375-0.001 || *result.ChiSquare > 0.4375+0.001 {
+		t.Errorf("expected chi-square 0.4375, got %v", result.ChiSquare)
+	}
+	if result.CommonOddsRatio == nil || *result.CommonOddsRatio != 9.0 {
+		t.Errorf("expected common odds ratio 9.0, got %v", result.CommonOddsRatio)
+	}
+	if result.PValue == nil || *result.PValue < 0.50 || *result.PValue > 0.52 {
+		t.Errorf("expected p-value around 0.508, got %v", result.PValue)
+	}
+	if result.RejectIndependence {
+		t.Error("expected independence not to be rejected at alpha=0.05")
+	}
+}
+
+// TestConditionalIndependenceNoVariation - 層内に変動がない場合は検定統計量がnullになるテスト
+func TestConditionalIndependenceNoVariation(t *testing.T) {
+	setupTestData()
+	// Total=10, 7, 0 の各層は1人ずつなので分散は0
+
+	var result ConditionalIndependenceResponse
+	serveJSON(t, getConditionalIndependence, "GET", "/api/conditional-independence?a=q1&b=q2&stratify=rest", "", &result)
+
+	if result.ChiSquare != nil || result.PValue != nil {
+		t.Errorf("expected null chi-square and p-value, got %v and %v", result.ChiSquare, result.PValue)
+	}
+	if result.Stratify != "rest" {
+		t.Errorf("expected stratify rest, got %s", result.Stratify)
+	}
+}
+
+// TestConditionalIndependenceInvalidParams - 無効なパラメータのテスト
+func TestConditionalIndependenceInvalidParams(t *testing.T) {
+	setupTestData()
+
+	testCases := []struct {
+		name  string
+		query string
+	}{
+		{"missing_a", "b=q2"},
+		{"missing_b", "a=q1"},
+		{"invalid_a", "a=q0&b=q2"},
+		{"invalid_b", "a=q1&b=total"},
+		{"same_item", "a=q3&b=q3"},
+		{"invalid_stratify", "a=q1&b=q2&stratify=q5"},
+		{"invalid_alpha", "a=q1&b=q2&alpha=1.5"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "/api/conditional-independence?"+tc.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(getConditionalIndependence)
+			handler.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != http.StatusBadRequest {
+				t.Errorf("handler returned wrong status code: got %v want %v",
+					status, http.StatusBadRequest)
+			}
+		})
+	}
+}
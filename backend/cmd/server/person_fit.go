@@ -0,0 +1,429 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Person fit. Items are ordered from easiest to hardest by their proportion correct; a student
+// with raw score r fits the Guttman pattern when they answer exactly the r easiest items. Each
+// statistic measures how far a pattern departs from that ideal, so low-ability students who
+// answer hard items correctly (copying, lucky guessing) or able students who miss easy ones
+// (carelessness) stand out.
+
+// maxJMLIterations bounds the joint maximum likelihood iterations of the Rasch fit
+const maxJMLIterations = 200
+
+// raschJML holds joint maximum likelihood estimates of a Rasch model. Items and students with
+// extreme scores have infinite estimates and are left out (estimable = false).
+type raschJML struct {
+	difficulty      []float64
+	ability         []float64
+	itemEstimable   []bool
+	personEstimable []bool
+	converged       bool
+}
+
+// fitRaschJML estimates item difficulties and student abilities by alternating Newton steps on
+// the joint likelihood. Students and items with extreme scores are dropped repeatedly until none
+// remain, difficulties are centred at zero and scaled by (J-1)/J to remove most of the JML bias
+// (Wright & Douglas 1977), after which abilities are re-estimated from the corrected difficulties.
+func fitRaschJML(responses [][]int) raschJML {
+	fit := raschJML{
+		difficulty:      make([]float64, numQuestions),
+		ability:         make([]float64, len(responses)),
+		itemEstimable:   make([]bool, numQuestions),
+		personEstimable: make([]bool, len(responses)),
+	}
+	for j := range fit.itemEstimable {
+		fit.itemEstimable[j] = true
+	}
+	for i := range fit.personEstimable {
+		fit.personEstimable[i] = true
+	}
+
+	score := func(i int) (int, int) {
+		r, k := 0, 0
+		for j, x := range responses[i] {
+			if fit.itemEstimable[j] {
+				r += x
+				k++
+			}
+		}
+		return r, k
+	}
+	for changed := true; changed; {
+		changed = false
+		for i := range responses {
+			if r, k := score(i); fit.personEstimable[i] && (r == 0 || r == k) {
+				fit.personEstimable[i] = false
+				changed = true
+			}
+		}
+		for j := range fit.itemEstimable {
+			if !fit.itemEstimable[j] {
+				continue
+			}
+			s, m := 0, 0
+			for i, row := range responses {
+				if fit.personEstimable[i] {
+					s += row[j]
+					m++
+				}
+			}
+			if s == 0 || s == m {
+				fit.itemEstimable[j] = false
+				changed = true
+			}
+		}
+	}
+
+	var items []int
+	for j, ok := range fit.itemEstimable {
+		if ok {
+			items = append(items, j)
+		}
+	}
+	if len(items) < 2 {
+		for i := range fit.personEstimable {
+			fit.personEstimable[i] = false
+		}
+		return fit
+	}
+
+	// ability solves Σ_j σ(θ - b_j) = r by Newton's method
+	ability := func(r int, theta float64) float64 {
+		for iter := 0; iter < 50; iter++ {
+			expected, information := 0.0, 0.0
+			for _, j := range items {
+				p := sigmoid(theta - fit.difficulty[j])
+				expected += p
+				information += p * (1 - p)
+			}
+			step := math.Max(-1, math.Min(1, (float64(r)-expected)/information))
+			theta += step
+			if math.Abs(step) < 1e-10 {
+				break
+			}
+		}
+		return theta
+	}
+
+	for i := range responses {
+		if fit.personEstimable[i] {
+			r, k := score(i)
+			fit.ability[i] = math.Log(float64(r) / float64(k-r))
+		}
+	}
+	for iter := 0; iter < maxJMLIterations; iter++ {
+		change := 0.0
+		for _, j := range items {
+			observed, expected, information := 0.0, 0.0, 0.0
+			for i, row := range responses {
+				if !fit.personEstimable[i] {
+					continue
+				}
+				p := sigmoid(fit.ability[i] - fit.difficulty[j])
+				observed += float64(row[j])
+				expected += p
+				information += p * (1 - p)
+			}
+			step := math.Max(-1, math.Min(1, (expected-observed)/information))
+			fit.difficulty[j] += step
+			change = math.Max(change, math.Abs(step))
+		}
+		mean := 0.0
+		for _, j := range items {
+			mean += fit.difficulty[j]
+		}
+		mean /= float64(len(items))
+		for _, j := range items {
+			fit.difficulty[j] -= mean
+		}
+		for i := range responses {
+			if fit.personEstimable[i] {
+				r, _ := score(i)
+				updated := ability(r, fit.ability[i])
+				change = math.Max(change, math.Abs(updated-fit.ability[i]))
+				fit.ability[i] = updated
+			}
+		}
+		if change < 1e-6 {
+			fit.converged = true
+			break
+		}
+	}
+
+	correction := float64(len(items)-1) / float64(len(items))
+	for _, j := range items {
+		fit.difficulty[j] *= correction
+	}
+	for i := range responses {
+		if fit.personEstimable[i] {
+			r, _ := score(i)
+			fit.ability[i] = ability(r, fit.ability[i])
+		}
+	}
+	return fit
+}
+
+// guttmanErrors counts the item pairs in which the easier item (earlier in order) is missed and
+// the harder one answered correctly
+func guttmanErrors(row []int, order []int) int {
+	count, missed := 0, 0
+	for _, j := range order {
+		if row[j] == 1 {
+			count += missed
+		} else {
+			missed++
+		}
+	}
+	return count
+}
+
+// u3Statistic is van der Flier's U3: the log-odds weighted distance of a pattern from the Guttman
+// pattern with the same score, 0 for a perfect Guttman pattern and 1 for the reversed one.
+// weights holds logit(p_j) and order lists items from easiest to hardest.
+func u3Statistic(row []int, order []int, weights []float64, score int) float64 {
+	k := len(order)
+	if score == 0 || score == k {
+		return math.NaN()
+	}
+	var best, worst, observed float64
+	for rank, j := range order {
+		if rank < score {
+			best += weights[j]
+		}
+		if rank >= k-score {
+			worst += weights[j]
+		}
+		if row[j] == 1 {
+			observed += weights[j]
+		}
+	}
+	if best == worst {
+		return math.NaN()
+	}
+	return (best - observed) / (best - worst)
+}
+
+// lzStatistic is the standardized log-likelihood of a pattern (Drasgow, Levine & Williams 1985)
+// given each item's probability of a correct answer; large negative values indicate misfit
+func lzStatistic(row []int, probabilities []float64) float64 {
+	var logLik, expected, variance float64
+	for j, p := range probabilities {
+		if p <= 0 || p >= 1 {
+			// Deterministic items carry no information about fit
+			continue
+		}
+		logOdds := math.Log(p / (1 - p))
+		if row[j] == 1 {
+			logLik += math.Log(p)
+		} else {
+			logLik += math.Log(1 - p)
+		}
+		expected += p*math.Log(p) + (1-p)*math.Log(1-p)
+		variance += p * (1 - p) * logOdds * logOdds
+	}
+	if variance == 0 {
+		return math.NaN()
+	}
+	return (logLik - expected) / math.Sqrt(variance)
+}
+
+// PersonFitItem represents one item in easiest-to-hardest order
+type PersonFitItem struct {
+	Question   string   `json:"question"`
+	PValue     float64  `json:"p_value"`
+	Difficulty *float64 `json:"difficulty,omitempty"`
+}
+
+// PersonFitStudent represents the fit statistics of one student's response pattern
+type PersonFitStudent struct {
+	StudentID           int      `json:"student_id"`
+	Total               int      `json:"total"`
+	Pattern             string   `json:"pattern"`
+	Ability             *float64 `json:"ability,omitempty"`
+	GuttmanErrors       int      `json:"guttman_errors"`
+	NormalizedGuttman   *float64 `json:"normalized_guttman_errors"`
+	U3                  *float64 `json:"u3"`
+	Lz                  *float64 `json:"lz"`
+	Aberrant            bool     `json:"aberrant"`
+	UnexpectedCorrect   []string `json:"unexpected_correct"`
+	UnexpectedIncorrect []string `json:"unexpected_incorrect"`
+}
+
+// PersonFitResponse represents person-fit statistics ranked from most to least aberrant
+type PersonFitResponse struct {
+	Model      string             `json:"model"`
+	N          int                `json:"n"`
+	Alpha      float64            `json:"alpha"`
+	LzCritical float64            `json:"lz_critical"`
+	Converged  *bool              `json:"converged,omitempty"`
+	Flagged    int                `json:"flagged"`
+	Items      []PersonFitItem    `json:"items"`
+	Students   []PersonFitStudent `json:"students"`
+}
+
+// Handler: Get person fit
+// Computes Guttman errors, U3 and lz (with item p-values or a Rasch model as probabilities) for
+// every student and ranks response patterns from most to least aberrant
+func getPersonFit(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	model := r.URL.Query().Get("model")
+	if model == "" {
+		model = "pvalue"
+	}
+	if model != "pvalue" && model != "rasch" {
+		http.Error(w, "Invalid 'model' parameter (must be pvalue or rasch)", http.StatusBadRequest)
+		return
+	}
+	alpha, err := floatParam(r, "alpha", 0.05)
+	if err != nil || alpha <= 0 || alpha >= 0.5 {
+		http.Error(w, "Invalid 'alpha' parameter (must be between 0 and 0.5)", http.StatusBadRequest)
+		return
+	}
+	limit, err := intParam(r, "limit", 0)
+	if err != nil || limit < 0 {
+		http.Error(w, "Invalid 'limit' parameter (must be non-negative)", http.StatusBadRequest)
+		return
+	}
+	flaggedOnly := r.URL.Query().Get("flagged_only") == "true"
+
+	if len(grades) == 0 {
+		http.Error(w, "No data available", http.StatusInternalServerError)
+		return
+	}
+
+	responses, err := binaryResponseMatrix()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	n := len(responses)
+	labels := questionLabels()
+
+	// Proportions correct, kept away from 0 and 1 so that log-odds stay finite
+	pValues := make([]float64, numQuestions)
+	weights := make([]float64, numQuestions)
+	probabilities := make([]float64, numQuestions)
+	for j := range pValues {
+		for _, row := range responses {
+			pValues[j] += float64(row[j])
+		}
+		pValues[j] /= float64(n)
+		probabilities[j] = clampProbability(pValues[j], 0.5/float64(n))
+		weights[j] = math.Log(probabilities[j] / (1 - probabilities[j]))
+	}
+	order := make([]int, numQuestions)
+	for j := range order {
+		order[j] = j
+	}
+	sort.SliceStable(order, func(a, b int) bool { return pValues[order[a]] > pValues[order[b]] })
+
+	response := PersonFitResponse{
+		Model:      model,
+		N:          n,
+		Alpha:      alpha,
+		LzCritical: normalQuantile(alpha),
+		Students:   []PersonFitStudent{},
+	}
+
+	var jml raschJML
+	if model == "rasch" {
+		jml = fitRaschJML(responses)
+		response.Converged = &jml.converged
+	}
+	for _, j := range order {
+		item := PersonFitItem{Question: labels[j], PValue: pValues[j]}
+		if model == "rasch" && jml.itemEstimable[j] {
+			difficulty := jml.difficulty[j]
+			item.Difficulty = &difficulty
+		}
+		response.Items = append(response.Items, item)
+	}
+
+	for i, row := range responses {
+		total := 0
+		var pattern strings.Builder
+		for _, x := range row {
+			total += x
+			pattern.WriteByte(byte('0' + x))
+		}
+		student := PersonFitStudent{
+			StudentID:           grades[i].StudentID,
+			Total:               total,
+			Pattern:             pattern.String(),
+			GuttmanErrors:       guttmanErrors(row, order),
+			U3:                  finiteOrNil(u3Statistic(row, order, weights, total)),
+			UnexpectedCorrect:   []string{},
+			UnexpectedIncorrect: []string{},
+		}
+		if total > 0 && total < numQuestions {
+			normalized := float64(student.GuttmanErrors) / float64(total*(numQuestions-total))
+			student.NormalizedGuttman = &normalized
+		}
+		for rank, j := range order {
+			if rank < total && row[j] == 0 {
+				student.UnexpectedIncorrect = append(student.UnexpectedIncorrect, labels[j])
+			} else if rank >= total && row[j] == 1 {
+				student.UnexpectedCorrect = append(student.UnexpectedCorrect, labels[j])
+			}
+		}
+
+		switch model {
+		case "pvalue":
+			student.Lz = finiteOrNil(lzStatistic(row, probabilities))
+		case "rasch":
+			if jml.personEstimable[i] {
+				ability := jml.ability[i]
+				student.Ability = &ability
+				itemProbabilities := make([]float64, numQuestions)
+				for j := range itemProbabilities {
+					if jml.itemEstimable[j] {
+						itemProbabilities[j] = sigmoid(ability - jml.difficulty[j])
+					}
+				}
+				student.Lz = finiteOrNil(lzStatistic(row, itemProbabilities))
+			}
+		}
+		student.Aberrant = student.Lz != nil && *student.Lz < response.LzCritical
+		if student.Aberrant {
+			response.Flagged++
+		}
+		if !flaggedOnly || student.Aberrant {
+			response.Students = append(response.Students, student)
+		}
+	}
+
+	// Most aberrant first: by lz, then normalized Guttman errors; students without lz go last
+	sort.SliceStable(response.Students, func(a, b int) bool {
+		sa, sb := response.Students[a], response.Students[b]
+		if (sa.Lz == nil) != (sb.Lz == nil) {
+			return sa.Lz != nil
+		}
+		if sa.Lz != nil && *sa.Lz != *sb.Lz {
+			return *sa.Lz < *sb.Lz
+		}
+		ga, gb := -1.0, -1.0
+		if sa.NormalizedGuttman != nil {
+			ga = *sa.NormalizedGuttman
+		}
+		if sb.NormalizedGuttman != nil {
+			gb = *sb.NormalizedGuttman
+		}
+		if ga != gb {
+			return ga > gb
+		}
+		return sa.StudentID < sb.StudentID
+	})
+	if limit > 0 && len(response.Students) > limit {
+		response.Students = response.Students[:limit]
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
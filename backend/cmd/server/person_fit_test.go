@@ -0,0 +1,201 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// setupPersonFitTestData - Raschモデルから生成した学生に、難問だけ正解する異常パターンの学生を加える
+// 学生1-3: Q8-Q10のみ正解（Q1が最易、Q10が最難）
+func setupPersonFitTestData(n int) {
+	rng := rand.New(rand.NewSource(11))
+	grades = []Grade{}
+	for i := 1; i <= n; i++ {
+		row := make([]int, numQuestions)
+		if i <= 3 {
+			row[7], row[8], row[9] = 1, 1, 1
+		} else {
+			theta := rng.NormFloat64()
+			for j := range row {
+				b := -2 + 4*float64(j)/float64(numQuestions-1)
+				if rng.Float64() < sigmoid(theta-b) {
+					row[j] = 1
+				}
+			}
+		}
+		g := Grade{StudentID: i, Q1: row[0], Q2: row[1], Q3: row[2], Q4: row[3], Q5: row[4],
+			Q6: row[5], Q7: row[6], Q8: row[7], Q9: row[8], Q10: row[9]}
+		g.Total = g.Q1 + g.Q2 + g.Q3 + g.Q4 + g.Q5 + g.Q6 + g.Q7 + g.Q8 + g.Q9 + g.Q10
+		grades = append(grades, g)
+	}
+}
+
+// TestGuttmanStatistics - Guttmanパターンと逆Guttmanパターンの誤数とU3のテスト
+func TestGuttmanStatistics(t *testing.T) {
+	order := []int{0, 1, 2, 3, 4}
+	weights := []float64{2, 1, 0, -1, -2}
+
+	guttman := []int{1, 1, 0, 0, 0}
+	if got := guttmanErrors(guttman, order); got != 0 {
+		t.Errorf("expected 0 Guttman errors for a Guttman pattern, got %d", got)
+	}
+	if got := u3Statistic(guttman, order, weights, 2); got != 0 {
+		t.Errorf("expected U3 = 0 for a Guttman pattern, got %v", got)
+	}
+
+	reversed := []int{0, 0, 0, 1, 1}
+	if got := guttmanErrors(reversed, order); got != 6 {
+		t.Errorf("expected r(J-r) = 6 Guttman errors for a reversed pattern, got %d", got)
+	}
+	if got := u3Statistic(reversed, order, weights, 2); got != 1 {
+		t.Errorf("expected U3 = 1 for a reversed pattern, got %v", got)
+	}
+
+	if got := u3Statistic([]int{1, 1, 1, 1, 1}, order, weights, 5); !math.IsNaN(got) {
+		t.Errorf("expected U3 to be undefined for a perfect score, got %v", got)
+	}
+}
+
+// TestPersonFitFlagsAberrantPatterns - p値モデルで異常パターンが上位に並び検出されるかのテスト
+func TestPersonFitFlagsAberrantPatterns(t *testing.T) {
+	setupPersonFitTestData(300)
+
+	var result PersonFitResponse
+	serveJSON(t, getPersonFit, "GET", "/api/person-fit?model=pvalue", "", &result)
+
+	if result.N != 300 || len(result.Students) != 300 {
+		t.Fatalf("expected 300 students, got n=%d, %d listed", result.N, len(result.Students))
+	}
+	if math.Abs(result.LzCritical-(-1.6449)) > 1e-3 {
+		t.Errorf("expected lz critical value -1.645, got %v", result.LzCritical)
+	}
+	if result.Items[0].Question != "Q1" || result.Items[numQuestions-1].Question != "Q10" {
+		t.Errorf("expected items ordered Q1 (easiest) to Q10 (hardest), got %s..%s",
+			result.Items[0].Question, result.Items[numQuestions-1].Question)
+	}
+	for rank := 0; rank < 3; rank++ {
+		s := result.Students[rank]
+		if s.StudentID > 3 {
+			t.Errorf("rank %d: expected an aberrant student, got student %d", rank+1, s.StudentID)
+		}
+		if !s.Aberrant {
+			t.Errorf("student %d should be flagged (lz %v)", s.StudentID, s.Lz)
+		}
+		if s.GuttmanErrors != 21 || s.U3 == nil || *s.U3 < 0.9 {
+			t.Errorf("student %d: expected 21 Guttman errors and U3 near 1, got %d and %v",
+				s.StudentID, s.GuttmanErrors, s.U3)
+		}
+		if len(s.UnexpectedCorrect) != 3 || len(s.UnexpectedIncorrect) != 3 {
+			t.Errorf("student %d: expected 3 unexpected correct and 3 unexpected incorrect, got %v and %v",
+				s.StudentID, s.UnexpectedCorrect, s.UnexpectedIncorrect)
+		}
+	}
+	// Model-consistent students are flagged at roughly the nominal rate
+	if result.Flagged > 3+30 {
+		t.Errorf("too many students flagged: %d", result.Flagged)
+	}
+	// Students with lz come first in ascending order
+	for i := 1; i < len(result.Students); i++ {
+		a, b := result.Students[i-1].Lz, result.Students[i].Lz
+		if a == nil && b != nil {
+			t.Fatalf("student without lz ranked before one with lz at %d", i)
+		}
+		if a != nil && b != nil && *a > *b {
+			t.Fatalf("students not ranked by lz at %d: %v > %v", i, *a, *b)
+		}
+	}
+}
+
+// TestPersonFitRasch - Raschモデルでの難易度推定と異常パターン検出のテスト
+func TestPersonFitRasch(t *testing.T) {
+	setupPersonFitTestData(300)
+
+	var result PersonFitResponse
+	serveJSON(t, getPersonFit, "GET", "/api/person-fit?model=rasch&flagged_only=true", "", &result)
+
+	if result.Converged == nil || !*result.Converged {
+		t.Error("expected the JML fit to converge")
+	}
+	for _, item := range result.Items {
+		if item.Difficulty == nil {
+			t.Fatalf("%s: expected an estimable difficulty", item.Question)
+		}
+	}
+	// Difficulties recover the generating spread from -2 (Q1) to 2 (Q10)
+	first, last := *result.Items[0].Difficulty, *result.Items[numQuestions-1].Difficulty
+	if first > -1 || last < 1 {
+		t.Errorf("expected difficulties spread around -2..2, got %v..%v", first, last)
+	}
+	flagged := map[int]bool{}
+	for _, s := range result.Students {
+		if !s.Aberrant {
+			t.Errorf("student %d listed with flagged_only=true but not flagged", s.StudentID)
+		}
+		if s.Ability == nil {
+			t.Errorf("student %d: expected an ability estimate", s.StudentID)
+		}
+		flagged[s.StudentID] = true
+	}
+	for id := 1; id <= 3; id++ {
+		if !flagged[id] {
+			t.Errorf("expected aberrant student %d to be flagged under the Rasch model", id)
+		}
+	}
+	if len(result.Students) != result.Flagged {
+		t.Errorf("expected %d flagged students listed, got %d", result.Flagged, len(result.Students))
+	}
+}
+
+// TestPersonFitExtremeScores - 満点・0点の学生は統計量が未定義になるかのテスト
+func TestPersonFitExtremeScores(t *testing.T) {
+	setupTestData()
+
+	var result PersonFitResponse
+	serveJSON(t, getPersonFit, "GET", "/api/person-fit?model=rasch", "", &result)
+
+	for _, s := range result.Students {
+		if s.Total == 10 || s.Total == 0 {
+			if s.NormalizedGuttman != nil || s.U3 != nil || s.Lz != nil || s.Aberrant {
+				t.Errorf("student %d with score %d should have undefined fit statistics", s.StudentID, s.Total)
+			}
+		}
+	}
+}
+
+// TestPersonFitInvalidParams - 不正なパラメータのテスト
+func TestPersonFitInvalidParams(t *testing.T) {
+	setupTestData()
+
+	for _, query := range []string{"model=irt", "alpha=0", "alpha=0.6", "limit=-1"} {
+		req, err := http.NewRequest("GET", "/api/person-fit?"+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(getPersonFit).ServeHTTP(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", query, rr.Code)
+		}
+	}
+}
+
+// TestPersonFitNonBinary - 0/1以外の得点がエラーになるかのテスト
+func TestPersonFitNonBinary(t *testing.T) {
+	setupTestData()
+	grades[1].Q1 = 2
+
+	for _, query := range []string{"model=pvalue", "model=rasch"} {
+		req, err := http.NewRequest("GET", "/api/person-fit?"+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(getPersonFit).ServeHTTP(rr, req)
+		if rr.Code != http.StatusInternalServerError {
+			t.Errorf("%s: expected status 500 for a non-binary score, got %d", query, rr.Code)
+		}
+	}
+}
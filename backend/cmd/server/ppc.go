@@ -0,0 +1,293 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"sort"
+)
+
+// maxPPCWork caps replications × students × items so a request stays fast
+const maxPPCWork = 20000000
+
+// PPCStatistic represents one test statistic compared with its replicated distribution
+type PPCStatistic struct {
+	Name            string  `json:"name"`
+	Observed        float64 `json:"observed"`
+	ReplicatedMean  float64 `json:"replicated_mean"`
+	ReplicatedLower float64 `json:"replicated_lower"`
+	ReplicatedUpper float64 `json:"replicated_upper"`
+	PPP             float64 `json:"ppp"`
+	Extreme         bool    `json:"extreme"`
+}
+
+// PosteriorPredictiveCheckResponse represents the result of a posterior predictive check
+type PosteriorPredictiveCheckResponse struct {
+	Model             string                  `json:"model"`
+	Replications      int                     `json:"replications"`
+	Seed              int64                   `json:"seed"`
+	Posterior         *TotalsPosteriorSummary `json:"posterior,omitempty"`
+	SummaryStatistics []PPCStatistic          `json:"summary_statistics"`
+	ScoreDistribution []PPCStatistic          `json:"score_distribution"`
+	ItemPValues       []PPCStatistic          `json:"item_p_values"`
+	PairLogOddsRatios []PPCStatistic          `json:"pair_log_odds_ratios"`
+	ExtremeCount      int                     `json:"extreme_count"`
+}
+
+// responseReplicator draws one replicated students × items response matrix from the posterior
+type responseReplicator interface {
+	replicate(rng *rand.Rand, students int) [][]int
+}
+
+// totalsReplicator replicates item responses from a Totals model. Both Totals models treat the
+// items as exchangeable: student i answers every item correctly with the same rate p_i.
+type totalsReplicator struct {
+	posterior totalsPosterior
+}
+
+func (t totalsReplicator) replicate(rng *rand.Rand, students int) [][]int {
+	draw := t.posterior.sample(rng)
+	responses := make([][]int, students)
+	for i := range responses {
+		rate := draw.sampleStudentRate(rng)
+		responses[i] = make([]int, numQuestions)
+		for j := range responses[i] {
+			if rng.Float64() < rate {
+				responses[i][j] = 1
+			}
+		}
+	}
+	return responses
+}
+
+// bernoulliReplicator replicates item responses from independent per-item Bernoulli models
+// with θ_j ~ Beta(1, 1), so the posterior of θ_j is Beta(alpha[j], beta[j])
+type bernoulliReplicator struct {
+	alpha, beta []float64
+}
+
+func (b bernoulliReplicator) replicate(rng *rand.Rand, students int) [][]int {
+	theta := make([]float64, numQuestions)
+	for j := range theta {
+		theta[j] = sampleBeta(rng, b.alpha[j], b.beta[j])
+	}
+	responses := make([][]int, students)
+	for i := range responses {
+		responses[i] = make([]int, numQuestions)
+		for j := range responses[i] {
+			if rng.Float64() < theta[j] {
+				responses[i][j] = 1
+			}
+		}
+	}
+	return responses
+}
+
+// fitBernoulliItems fits the independent per-item Bernoulli model to a response matrix
+func fitBernoulliItems(responses [][]int) bernoulliReplicator {
+	b := bernoulliReplicator{alpha: make([]float64, numQuestions), beta: make([]float64, numQuestions)}
+	for j := 0; j < numQuestions; j++ {
+		correct := 0
+		for _, row := range responses {
+			correct += row[j]
+		}
+		b.alpha[j] = 1 + float64(correct)
+		b.beta[j] = 1 + float64(len(responses)-correct)
+	}
+	return b
+}
+
+// ppcStatistics computes every test statistic of a response matrix and its Totals, in a fixed
+// order: mean and SD of Totals, the proportion of each Total 0-10, item p-values, and the
+// Haldane-corrected log odds ratio of every item pair
+func ppcStatistics(responses [][]int, totals []int) []float64 {
+	n := float64(len(responses))
+	stats := []float64{}
+
+	totalValues := make([]float64, len(totals))
+	scoreCounts := make([]float64, numQuestions+1)
+	for i, k := range totals {
+		totalValues[i] = float64(k)
+		scoreCounts[k]++
+	}
+	mean, sd := meanAndSD(totalValues)
+	stats = append(stats, mean, sd)
+	for _, count := range scoreCounts {
+		stats = append(stats, count/n)
+	}
+
+	for j := 0; j < numQuestions; j++ {
+		correct := 0
+		for _, row := range responses {
+			correct += row[j]
+		}
+		stats = append(stats, float64(correct)/n)
+	}
+
+	for a := 0; a < numQuestions; a++ {
+		for b := a + 1; b < numQuestions; b++ {
+			var n11, n10, n01, n00 float64
+			for _, row := range responses {
+				switch {
+				case row[a] == 1 && row[b] == 1:
+					n11++
+				case row[a] == 1:
+					n10++
+				case row[b] == 1:
+					n01++
+				default:
+					n00++
+				}
+			}
+			stats = append(stats, math.Log((n11+0.5)*(n00+0.5)/((n10+0.5)*(n01+0.5))))
+		}
+	}
+	return stats
+}
+
+// rowTotals returns the number correct in each row of a response matrix
+func rowTotals(responses [][]int) []int {
+	totals := make([]int, len(responses))
+	for i, row := range responses {
+		for _, v := range row {
+			totals[i] += v
+		}
+	}
+	return totals
+}
+
+// summarizePPC compares an observed statistic with its replicated values. The PPP-value is the
+// mid-p P(T_rep > T_obs) + ½P(T_rep = T_obs), so discrete statistics are not biased upwards;
+// it is flagged extreme outside [0.025, 0.975].
+func summarizePPC(name string, observed float64, replicated []float64) PPCStatistic {
+	var above, equal, sum float64
+	for _, v := range replicated {
+		sum += v
+		if v > observed {
+			above++
+		} else if v == observed {
+			equal++
+		}
+	}
+	sorted := append([]float64(nil), replicated...)
+	sort.Float64s(sorted)
+
+	count := float64(len(replicated))
+	ppp := (above + 0.5*equal) / count
+	return PPCStatistic{
+		Name:            name,
+		Observed:        observed,
+		ReplicatedMean:  sum / count,
+		ReplicatedLower: quantile(sorted, 0.025),
+		ReplicatedUpper: quantile(sorted, 0.975),
+		PPP:             ppp,
+		Extreme:         ppp < 0.025 || ppp > 0.975,
+	}
+}
+
+// ppcStatisticNames returns the names matching the order of ppcStatistics
+func ppcStatisticNames() []string {
+	names := []string{"mean_total", "sd_total"}
+	for k := 0; k <= numQuestions; k++ {
+		names = append(names, fmt.Sprintf("total=%d", k))
+	}
+	labels := questionLabels()
+	names = append(names, labels...)
+	for a := 0; a < numQuestions; a++ {
+		for b := a + 1; b < numQuestions; b++ {
+			names = append(names, labels[a]+"-"+labels[b])
+		}
+	}
+	return names
+}
+
+// Handler: Get posterior predictive check
+// Fits a Binomial or Beta-Binomial model to Totals, or independent Bernoulli models to items
+// (default), replicates the class from the posterior and compares test statistics with the data
+func getPosteriorPredictiveCheck(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	model := "bernoulli"
+	if modelStr := r.URL.Query().Get("model"); modelStr != "" {
+		if _, ok := parseTotalsModel(modelStr); !ok && modelStr != "bernoulli" {
+			http.Error(w, "Invalid 'model' parameter (must be binomial, beta-binomial or bernoulli)", http.StatusBadRequest)
+			return
+		}
+		model = modelStr
+	}
+
+	replications, err := intParam(r, "replications", 1000)
+	if err != nil || replications < 100 {
+		http.Error(w, "Invalid 'replications' parameter (must be at least 100)", http.StatusBadRequest)
+		return
+	}
+	seed, err := intParam(r, "seed", 1)
+	if err != nil {
+		http.Error(w, "Invalid 'seed' parameter", http.StatusBadRequest)
+		return
+	}
+
+	if len(grades) == 0 {
+		http.Error(w, "No data available", http.StatusInternalServerError)
+		return
+	}
+	if replications > maxPPCWork/(len(grades)*numQuestions) {
+		http.Error(w, "Too much work: replications × students × items must not exceed 20000000", http.StatusBadRequest)
+		return
+	}
+
+	totals, err := gradeTotals()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	observedResponses := responseMatrix()
+
+	response := PosteriorPredictiveCheckResponse{
+		Model:        model,
+		Replications: replications,
+		Seed:         int64(seed),
+	}
+
+	var replicator responseReplicator
+	if model == "bernoulli" {
+		replicator = fitBernoulliItems(observedResponses)
+	} else {
+		posterior := fitTotalsModel(model, totals)
+		summary := posterior.summary()
+		response.Posterior = &summary
+		replicator = totalsReplicator{posterior: posterior}
+	}
+
+	observed := ppcStatistics(observedResponses, totals)
+	replicated := make([][]float64, len(observed))
+	rng := rand.New(rand.NewSource(int64(seed)))
+	for s := 0; s < replications; s++ {
+		responses := replicator.replicate(rng, len(grades))
+		for i, v := range ppcStatistics(responses, rowTotals(responses)) {
+			replicated[i] = append(replicated[i], v)
+		}
+	}
+
+	names := ppcStatisticNames()
+	for i, name := range names {
+		stat := summarizePPC(name, observed[i], replicated[i])
+		if stat.Extreme {
+			response.ExtremeCount++
+		}
+		switch {
+		case i < 2:
+			response.SummaryStatistics = append(response.SummaryStatistics, stat)
+		case i < 2+numQuestions+1:
+			response.ScoreDistribution = append(response.ScoreDistribution, stat)
+		case i < 2+2*numQuestions+1:
+			response.ItemPValues = append(response.ItemPValues, stat)
+		default:
+			response.PairLogOddsRatios = append(response.PairLogOddsRatios, stat)
+		}
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// setupPolarisedTestData - 全問正解と全問不正解の学生が多く、一部が半分正解のデータ
+func setupPolarisedTestData() {
+	grades = []Grade{}
+	for i := 0; i < 12; i++ {
+		grades = append(grades,
+			Grade{StudentID: 2*i + 1, Q1: 1, Q2: 1, Q3: 1, Q4: 1, Q5: 1, Q6: 1, Q7: 1, Q8: 1, Q9: 1, Q10: 1, Total: 10},
+			Grade{StudentID: 2*i + 2, Total: 0},
+		)
+	}
+	for i := 0; i < 6; i++ {
+		grades = append(grades, Grade{StudentID: 25 + i, Q1: i % 2, Q2: 1 - i%2, Q3: 1, Q4: 1, Q5: 1, Q6: 1, Total: 5})
+	}
+}
+
+func findPPCStatistic(stats []PPCStatistic, name string) *PPCStatistic {
+	for i := range stats {
+		if stats[i].Name == name {
+			return &stats[i]
+		}
+	}
+	return nil
+}
+
+// TestPosteriorPredictiveCheckShape - 検定統計量の構成のテスト
+func TestPosteriorPredictiveCheckShape(t *testing.T) {
+	setupTestData()
+
+	var result PosteriorPredictiveCheckResponse
+	serveJSON(t, getPosteriorPredictiveCheck, "GET", "/api/ppc?replications=200", "", &result)
+
+	if result.Model != "bernoulli" || result.Posterior != nil {
+		t.Errorf("expected the bernoulli model without a Totals posterior, got %s", result.Model)
+	}
+	if len(result.SummaryStatistics) != 2 || len(result.ScoreDistribution) != 11 ||
+		len(result.ItemPValues) != 10 || len(result.PairLogOddsRatios) != 45 {
+		t.Errorf("unexpected statistic counts: %d, %d, %d, %d", len(result.SummaryStatistics),
+			len(result.ScoreDistribution), len(result.ItemPValues), len(result.PairLogOddsRatios))
+	}
+	if q8 := findPPCStatistic(result.ItemPValues, "Q8"); q8 == nil || q8.Observed != 1.0/3.0 {
+		t.Errorf("expected observed Q8 p-value 1/3, got %+v", q8)
+	}
+	for _, stat := range result.PairLogOddsRatios {
+		if stat.PPP < 0 || stat.PPP > 1 || stat.ReplicatedLower > stat.ReplicatedUpper {
+			t.Errorf("invalid statistic %+v", stat)
+		}
+	}
+}
+
+// TestPosteriorPredictiveCheckMisfit - 二極化データに対するモデルの適合度のテスト
+func TestPosteriorPredictiveCheckMisfit(t *testing.T) {
+	setupPolarisedTestData()
+
+	// 独立ベルヌーイモデルは問題間の強い連関を再現できない
+	var bernoulli PosteriorPredictiveCheckResponse
+	serveJSON(t, getPosteriorPredictiveCheck, "GET", "/api/ppc?model=bernoulli&replications=200", "", &bernoulli)
+	if pair := findPPCStatistic(bernoulli.PairLogOddsRatios, "Q1-Q2"); pair == nil || !pair.Extreme {
+		t.Errorf("expected Q1-Q2 odds ratio to be extreme under the bernoulli model, got %+v", pair)
+	}
+
+	// 二項モデルは合計点のばらつきを再現できない
+	var binomial PosteriorPredictiveCheckResponse
+	serveJSON(t, getPosteriorPredictiveCheck, "GET", "/api/ppc?model=binomial&replications=200", "", &binomial)
+	if sd := findPPCStatistic(binomial.SummaryStatistics, "sd_total"); sd == nil || !sd.Extreme {
+		t.Errorf("expected sd_total to be extreme under the binomial model, got %+v", sd)
+	}
+	if binomial.Posterior == nil || binomial.Posterior.Model != "binomial" {
+		t.Errorf("expected a binomial posterior summary, got %+v", binomial.Posterior)
+	}
+
+	// ベータ二項モデルは過分散を再現できる
+	var betaBinomial PosteriorPredictiveCheckResponse
+	serveJSON(t, getPosteriorPredictiveCheck, "GET", "/api/ppc?model=beta-binomial&replications=200", "", &betaBinomial)
+	if sd := findPPCStatistic(betaBinomial.SummaryStatistics, "sd_total"); sd == nil || sd.Extreme {
+		t.Errorf("expected sd_total to be reproduced by the beta-binomial model, got %+v", sd)
+	}
+	if mean := findPPCStatistic(betaBinomial.SummaryStatistics, "mean_total"); mean == nil || mean.Extreme {
+		t.Errorf("expected mean_total to be reproduced, got %+v", mean)
+	}
+	if betaBinomial.ExtremeCount >= bernoulli.ExtremeCount {
+		t.Errorf("expected fewer extreme statistics for beta-binomial (%d) than bernoulli (%d)",
+			betaBinomial.ExtremeCount, bernoulli.ExtremeCount)
+	}
+}
+
+// TestSummarizePPC - 中央p値の計算のテスト
+func TestSummarizePPC(t *testing.T) {
+	stat := summarizePPC("x", 2, []float64{1, 2, 3, 4})
+	// P(T > 2) = 2/4, P(T = 2) = 1/4 → 0.5 + 0.125
+	if stat.PPP != 0.625 {
+		t.Errorf("expected mid-p 0.625, got %.4f", stat.PPP)
+	}
+	if stat.ReplicatedMean != 2.5 {
+		t.Errorf("expected replicated mean 2.5, got %.4f", stat.ReplicatedMean)
+	}
+}
+
+// TestPosteriorPredictiveCheckInvalidParams - 無効なパラメータのテスト
+func TestPosteriorPredictiveCheckInvalidParams(t *testing.T) {
+	setupTestData()
+
+	testCases := []struct {
+		name  string
+		query string
+	}{
+		{"invalid_model", "model=poisson"},
+		{"invalid_replications", "replications=10"},
+		{"invalid_seed", "seed=x"},
+		{"too_much_work", "replications=1000000"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "/api/ppc?"+tc.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(getPosteriorPredictiveCheck)
+			handler.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != http.StatusBadRequest {
+				t.Errorf("handler returned wrong status code: got %v want %v",
+					status, http.StatusBadRequest)
+			}
+		})
+	}
+}
+
+// TestPosteriorPredictiveCheckEmptyData - データが空の場合のテスト
+func TestPosteriorPredictiveCheckEmptyData(t *testing.T) {
+	grades = []Grade{}
+
+	req, err := http.NewRequest("GET", "/api/ppc", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(getPosteriorPredictiveCheck)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusInternalServerError {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// maxPredictiveWork caps simulations × cohort_size so a request stays fast
+const maxPredictiveWork = 5000000
+
+// PredictiveInterval represents a central predictive interval
+type PredictiveInterval struct {
+	Level float64 `json:"level"`
+	Lower float64 `json:"lower"`
+	Upper float64 `json:"upper"`
+}
+
+// PredictiveSummary represents the simulated predictive distribution of a cohort statistic
+type PredictiveSummary struct {
+	Mean      float64              `json:"mean"`
+	SD        float64              `json:"sd"`
+	Intervals []PredictiveInterval `json:"intervals"`
+}
+
+// PosteriorPredictiveResponse represents the predicted results of a future cohort
+type PosteriorPredictiveResponse struct {
+	Model              string                 `json:"model"`
+	CohortSize         int                    `json:"cohort_size"`
+	Threshold          int                    `json:"threshold"`
+	Simulations        int                    `json:"simulations"`
+	Seed               int64                  `json:"seed"`
+	Posterior          TotalsPosteriorSummary `json:"posterior"`
+	ObservedMeanTotal  float64                `json:"observed_mean_total"`
+	ObservedPassRate   float64                `json:"observed_pass_rate"`
+	MeanTotal          PredictiveSummary      `json:"mean_total"`
+	PassRate           PredictiveSummary      `json:"pass_rate"`
+	TotalDistribution  []float64              `json:"total_distribution"`
+	PassCountHistogram []int                  `json:"pass_count_histogram"`
+}
+
+// summarizePredictive returns the mean, SD and 50/80/95% central intervals of simulated values
+func summarizePredictive(values []float64) PredictiveSummary {
+	mean, sd := meanAndSD(values)
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	summary := PredictiveSummary{Mean: mean, SD: sd}
+	for _, level := range []float64{0.5, 0.8, 0.95} {
+		tail := (1 - level) / 2
+		summary.Intervals = append(summary.Intervals, PredictiveInterval{
+			Level: level,
+			Lower: quantile(sorted, tail),
+			Upper: quantile(sorted, 1-tail),
+		})
+	}
+	return summary
+}
+
+// Handler: Get posterior predictive distribution
+// Simulates a future cohort's Totals and pass rate (Total ≥ threshold) from the posterior of a
+// Beta-Binomial (default) or Binomial model fitted to the current Totals
+func getPosteriorPredictive(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	thresholdStr := r.URL.Query().Get("threshold")
+	if thresholdStr == "" {
+		http.Error(w, "Missing 'threshold' parameter", http.StatusBadRequest)
+		return
+	}
+	threshold, err := strconv.Atoi(thresholdStr)
+	if err != nil {
+		http.Error(w, "Invalid 'threshold' parameter", http.StatusBadRequest)
+		return
+	}
+
+	model := "beta-binomial"
+	if modelStr := r.URL.Query().Get("model"); modelStr != "" {
+		var ok bool
+		model, ok = parseTotalsModel(modelStr)
+		if !ok {
+			http.Error(w, "Invalid 'model' parameter (must be binomial or beta-binomial)", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// cohort_size defaults to the size of the current class
+	cohortSize, err := intParam(r, "cohort_size", 0)
+	if err != nil || cohortSize < 0 {
+		http.Error(w, "Invalid 'cohort_size' parameter (must be a positive integer)", http.StatusBadRequest)
+		return
+	}
+	simulations, err := intParam(r, "simulations", 5000)
+	if err != nil || simulations < 100 {
+		http.Error(w, "Invalid 'simulations' parameter (must be at least 100)", http.StatusBadRequest)
+		return
+	}
+	seed, err := intParam(r, "seed", 1)
+	if err != nil {
+		http.Error(w, "Invalid 'seed' parameter", http.StatusBadRequest)
+		return
+	}
+
+	if len(grades) == 0 {
+		http.Error(w, "No data available", http.StatusInternalServerError)
+		return
+	}
+
+	if cohortSize == 0 {
+		cohortSize = len(grades)
+	}
+	if cohortSize > maxPredictiveWork || simulations > maxPredictiveWork/cohortSize {
+		http.Error(w, "Too much work: simulations × cohort_size must not exceed 5000000", http.StatusBadRequest)
+		return
+	}
+
+	totals, err := gradeTotals()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var observedSum, observedPass int
+	for _, k := range totals {
+		observedSum += k
+		if k >= threshold {
+			observedPass++
+		}
+	}
+
+	posterior := fitTotalsModel(model, totals)
+	rng := rand.New(rand.NewSource(int64(seed)))
+
+	meanTotals := make([]float64, simulations)
+	passRates := make([]float64, simulations)
+	totalCounts := make([]int, numQuestions+1)
+	passCountHistogram := make([]int, cohortSize+1)
+
+	for s := 0; s < simulations; s++ {
+		draw := posterior.sample(rng)
+		var sum, passCount int
+		for i := 0; i < cohortSize; i++ {
+			k := draw.sampleTotal(rng)
+			totalCounts[k]++
+			sum += k
+			if k >= threshold {
+				passCount++
+			}
+		}
+		meanTotals[s] = float64(sum) / float64(cohortSize)
+		passRates[s] = float64(passCount) / float64(cohortSize)
+		passCountHistogram[passCount]++
+	}
+
+	totalDistribution := make([]float64, numQuestions+1)
+	for k, count := range totalCounts {
+		totalDistribution[k] = float64(count) / float64(simulations*cohortSize)
+	}
+
+	response := PosteriorPredictiveResponse{
+		Model:              model,
+		CohortSize:         cohortSize,
+		Threshold:          threshold,
+		Simulations:        simulations,
+		Seed:               int64(seed),
+		Posterior:          posterior.summary(),
+		ObservedMeanTotal:  float64(observedSum) / float64(len(totals)),
+		ObservedPassRate:   float64(observedPass) / float64(len(totals)),
+		MeanTotal:          summarizePredictive(meanTotals),
+		PassRate:           summarizePredictive(passRates),
+		TotalDistribution:  totalDistribution,
+		PassCountHistogram: passCountHistogram,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
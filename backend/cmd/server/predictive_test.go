@@ -0,0 +1,163 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// setupTotalsTestData - 指定した合計点の学生を並べたテストデータ
+func setupTotalsTestData(totals ...int) {
+	grades = []Grade{}
+	for i, total := range totals {
+		grades = append(grades, Grade{StudentID: i + 1, Total: total})
+	}
+}
+
+// TestPosteriorPredictiveBinomial - 二項モデルの予測分布テスト
+func TestPosteriorPredictiveBinomial(t *testing.T) {
+	// 20人全員が5点 → θの事後分布は Beta(101, 101)
+	totals := make([]int, 20)
+	for i := range totals {
+		totals[i] = 5
+	}
+	setupTotalsTestData(totals...)
+
+	var result PosteriorPredictiveResponse
+	body := serveJSON(t, getPosteriorPredictive, "GET", "/api/predictive?threshold=6&model=binomial&cohort_size=30&simulations=2000&seed=3", "", &result).Body.String()
+
+	if result.Posterior.Alpha != 101 || result.Posterior.Beta != 101 {
+		t.Errorf("expected Beta(101, 101) posterior, got Beta(%.1f, %.1f)", result.Posterior.Alpha, result.Posterior.Beta)
+	}
+	if math.Abs(result.MeanTotal.Mean-5.0) > 0.2 {
+		t.Errorf("expected predicted mean total near 5, got %.4f", result.MeanTotal.Mean)
+	}
+	if result.ObservedPassRate != 0 {
+		t.Errorf("expected observed pass rate 0, got %.4f", result.ObservedPassRate)
+	}
+
+	// 予測分布の確率の合計は1、ヒストグラムの合計はシミュレーション回数
+	var sum float64
+	for _, p := range result.TotalDistribution {
+		sum += p
+	}
+	if math.Abs(sum-1.0) > 1e-9 {
+		t.Errorf("total distribution sums to %.6f", sum)
+	}
+	histogramTotal := 0
+	for _, count := range result.PassCountHistogram {
+		histogramTotal += count
+	}
+	if histogramTotal != 2000 || len(result.PassCountHistogram) != 31 {
+		t.Errorf("unexpected pass count histogram: %d entries summing to %d", len(result.PassCountHistogram), histogramTotal)
+	}
+
+	// 予測区間は入れ子になっている
+	intervals := result.PassRate.Intervals
+	if len(intervals) != 3 || intervals[0].Lower < intervals[2].Lower || intervals[0].Upper > intervals[2].Upper {
+		t.Errorf("expected nested 50%%/80%%/95%% intervals, got %+v", intervals)
+	}
+
+	// 同じシードなら同じ結果になる
+	again := serveRequest(t, getPosteriorPredictive, "GET", "/api/predictive?threshold=6&model=binomial&cohort_size=30&simulations=2000&seed=3", "").Body.String()
+	if body != again {
+		t.Error("expected identical results for the same seed")
+	}
+}
+
+// TestPosteriorPredictiveBetaBinomial - 過分散データでのベータ二項モデルのテスト
+func TestPosteriorPredictiveBetaBinomial(t *testing.T) {
+	// 0点と10点に二極化したデータ → 学生間のばらつきが大きく κ は小さい
+	totals := []int{}
+	for i := 0; i < 15; i++ {
+		totals = append(totals, 0, 10)
+	}
+	setupTotalsTestData(totals...)
+
+	var result PosteriorPredictiveResponse
+	serveJSON(t, getPosteriorPredictive, "GET", "/api/predictive?threshold=6&simulations=1000", "", &result)
+
+	if result.Model != "beta-binomial" {
+		t.Errorf("expected beta-binomial model by default, got %s", result.Model)
+	}
+	if result.CohortSize != 30 {
+		t.Errorf("expected cohort size to default to the class size 30, got %d", result.CohortSize)
+	}
+	if result.Posterior.Kappa == nil || *result.Posterior.Kappa > 2 {
+		t.Errorf("expected a small kappa for polarised totals, got %v", result.Posterior.Kappa)
+	}
+	if math.Abs(result.PassRate.Mean-0.5) > 0.1 {
+		t.Errorf("expected predicted pass rate near 0.5, got %.4f", result.PassRate.Mean)
+	}
+	// 予測分布は両端に質量が集中する
+	if result.TotalDistribution[0] < result.TotalDistribution[5] || result.TotalDistribution[10] < result.TotalDistribution[5] {
+		t.Errorf("expected a U-shaped total distribution, got %v", result.TotalDistribution)
+	}
+}
+
+// TestBetaQuantile - ベータ分布の分位点のテスト
+func TestBetaQuantile(t *testing.T) {
+	if q := betaQuantile(0.25, 1, 1); math.Abs(q-0.25) > 1e-9 {
+		t.Errorf("expected Beta(1,1) quantile 0.25, got %.6f", q)
+	}
+	// Beta(2,1) の分布関数は x² → 0.25分位点は 0.5
+	if q := betaQuantile(0.25, 2, 1); math.Abs(q-0.5) > 1e-9 {
+		t.Errorf("expected Beta(2,1) quantile 0.5, got %.6f", q)
+	}
+}
+
+// TestPosteriorPredictiveInvalidParams - 無効なパラメータのテスト
+func TestPosteriorPredictiveInvalidParams(t *testing.T) {
+	setupTestData()
+
+	testCases := []struct {
+		name  string
+		query string
+	}{
+		{"missing_threshold", "model=binomial"},
+		{"invalid_threshold", "threshold=x"},
+		{"invalid_model", "threshold=6&model=poisson"},
+		{"invalid_cohort_size", "threshold=6&cohort_size=-1"},
+		{"invalid_simulations", "threshold=6&simulations=10"},
+		{"too_much_work", "threshold=6&simulations=100000&cohort_size=1000"},
+		{"overflowing_work", "threshold=6&simulations=4611686018427387904&cohort_size=4"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "/api/predictive?"+tc.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(getPosteriorPredictive)
+			handler.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != http.StatusBadRequest {
+				t.Errorf("handler returned wrong status code: got %v want %v",
+					status, http.StatusBadRequest)
+			}
+		})
+	}
+}
+
+// TestPosteriorPredictiveEmptyData - データが空の場合のテスト
+func TestPosteriorPredictiveEmptyData(t *testing.T) {
+	grades = []Grade{}
+
+	req, err := http.NewRequest("GET", "/api/predictive?threshold=6", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(getPosteriorPredictive)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusInternalServerError {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,371 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// sensitivityThreshold flags an analysis as prior sensitive when some prior moves the posterior
+// mean by more than this many reference posterior SDs
+const sensitivityThreshold = 0.5
+
+// namedBetaPrior is a Beta prior with a label
+type namedBetaPrior struct {
+	name string
+	BetaPrior
+}
+
+// namedNIGPrior is a Normal–Inverse-Gamma prior with a label
+type namedNIGPrior struct {
+	name string
+	NIGParameters
+}
+
+// defaultBetaPriors are compared for rate analyses; the first one is the reference
+var defaultBetaPriors = []namedBetaPrior{
+	{"uniform", BetaPrior{Alpha: 1, Beta: 1}},
+	{"jeffreys", BetaPrior{Alpha: 0.5, Beta: 0.5}},
+	{"skeptical", BetaPrior{Alpha: 10, Beta: 10}},
+	{"optimistic", BetaPrior{Alpha: 7, Beta: 3}},
+	{"pessimistic", BetaPrior{Alpha: 3, Beta: 7}},
+}
+
+// defaultNIGPriors are compared for the class mean; the first one is the reference
+var defaultNIGPriors = []namedNIGPrior{
+	{"default", NIGParameters{Mu: float64(numQuestions) / 2, Kappa: 1, Alpha: 1, Beta: 1}},
+	{"vague", NIGParameters{Mu: float64(numQuestions) / 2, Kappa: 0.01, Alpha: 0.01, Beta: 0.01}},
+	{"skeptical", NIGParameters{Mu: float64(numQuestions) / 2, Kappa: 20, Alpha: 5, Beta: 20}},
+	{"optimistic", NIGParameters{Mu: 0.8 * numQuestions, Kappa: 10, Alpha: 5, Beta: 20}},
+	{"pessimistic", NIGParameters{Mu: 0.3 * numQuestions, Kappa: 10, Alpha: 5, Beta: 20}},
+}
+
+// elicitationStrengths maps verbal certainty to the prior's effective sample size α+β
+var elicitationStrengths = map[string]float64{
+	"unsure":        2,
+	"somewhat-sure": 10,
+	"fairly-sure":   25,
+	"very-sure":     100,
+}
+
+// PriorSensitivityEntry represents the posterior under one prior
+type PriorSensitivityEntry struct {
+	Name               string   `json:"name"`
+	Prior              string   `json:"prior"`
+	PriorMean          *float64 `json:"prior_mean"`
+	PosteriorMean      float64  `json:"posterior_mean"`
+	PosteriorSD        *float64 `json:"posterior_sd"`
+	Lower              float64  `json:"lower"`
+	Upper              float64  `json:"upper"`
+	ShiftFromReference float64  `json:"shift_from_reference"`
+	WidthRatio         float64  `json:"width_ratio"`
+}
+
+// PriorSensitivityResponse represents how an analysis' posterior depends on the prior
+type PriorSensitivityResponse struct {
+	Analysis    string                  `json:"analysis"`
+	Description string                  `json:"description"`
+	Successes   *int                    `json:"successes,omitempty"`
+	Trials      *int                    `json:"trials,omitempty"`
+	Level       float64                 `json:"level"`
+	Reference   string                  `json:"reference"`
+	Results     []PriorSensitivityEntry `json:"results"`
+	MaxShift    float64                 `json:"max_shift"`
+	ReferenceSD float64                 `json:"reference_sd"`
+	Sensitive   bool                    `json:"sensitive"`
+}
+
+// PriorElicitationResponse represents Beta parameters elicited from a belief about a rate
+type PriorElicitationResponse struct {
+	Method   string  `json:"method"`
+	Alpha    float64 `json:"alpha"`
+	Beta     float64 `json:"beta"`
+	Mean     float64 `json:"mean"`
+	SD       float64 `json:"sd"`
+	Strength float64 `json:"strength"`
+	Lower95  float64 `json:"lower_95"`
+	Upper95  float64 `json:"upper_95"`
+}
+
+// parseBetaPriorList parses "1:1,0.5:0.5" into named Beta priors
+func parseBetaPriorList(s string) ([]namedBetaPrior, error) {
+	var priors []namedBetaPrior
+	for _, part := range strings.Split(s, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ":")
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid prior %q (must be alpha:beta)", part)
+		}
+		alpha, errA := strconv.ParseFloat(fields[0], 64)
+		beta, errB := strconv.ParseFloat(fields[1], 64)
+		if errA != nil || errB != nil || !(alpha > 0) || !(beta > 0) || math.IsInf(alpha, 0) || math.IsInf(beta, 0) {
+			return nil, fmt.Errorf("invalid prior %q (alpha and beta must be positive and finite)", part)
+		}
+		priors = append(priors, namedBetaPrior{strings.TrimSpace(part), BetaPrior{Alpha: alpha, Beta: beta}})
+	}
+	return priors, nil
+}
+
+// parseNIGPriorList parses "mu0:kappa0:a0:b0,..." into named Normal–Inverse-Gamma priors
+func parseNIGPriorList(s string) ([]namedNIGPrior, error) {
+	var priors []namedNIGPrior
+	for _, part := range strings.Split(s, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ":")
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("invalid prior %q (must be mu0:kappa0:a0:b0)", part)
+		}
+		values := make([]float64, 4)
+		for i, f := range fields {
+			v, err := strconv.ParseFloat(f, 64)
+			if err != nil || math.IsNaN(v) || math.IsInf(v, 0) || (i > 0 && v <= 0) {
+				return nil, fmt.Errorf("invalid prior %q (mu0 must be finite; kappa0, a0 and b0 must be positive and finite)", part)
+			}
+			values[i] = v
+		}
+		priors = append(priors, namedNIGPrior{strings.TrimSpace(part),
+			NIGParameters{Mu: values[0], Kappa: values[1], Alpha: values[2], Beta: values[3]}})
+	}
+	return priors, nil
+}
+
+// betaRateSensitivity computes the Beta posterior of s successes in n trials under each prior
+func betaRateSensitivity(successes, trials int, priors []namedBetaPrior, level float64) []PriorSensitivityEntry {
+	var entries []PriorSensitivityEntry
+	for _, p := range priors {
+		a := p.Alpha + float64(successes)
+		b := p.Beta + float64(trials-successes)
+		lower, upper := betaCredibleInterval(a, b, level)
+		priorMean := p.Alpha / (p.Alpha + p.Beta)
+		sd := math.Sqrt(a * b / ((a + b) * (a + b) * (a + b + 1)))
+		entries = append(entries, PriorSensitivityEntry{
+			Name:          p.name,
+			Prior:         fmt.Sprintf("Beta(%g, %g)", p.Alpha, p.Beta),
+			PriorMean:     &priorMean,
+			PosteriorMean: a / (a + b),
+			PosteriorSD:   &sd,
+			Lower:         lower,
+			Upper:         upper,
+		})
+	}
+	return entries
+}
+
+// classMeanSensitivity computes the marginal posterior of the mean Total under each prior
+func classMeanSensitivity(totals []float64, priors []namedNIGPrior, level float64) []PriorSensitivityEntry {
+	var entries []PriorSensitivityEntry
+	for _, p := range priors {
+		posterior := updateNIG(p.NIGParameters, totals)
+		mean, _ := analyticNIGMarginals(posterior, level)
+		priorMean := p.Mu
+		entries = append(entries, PriorSensitivityEntry{
+			Name:          p.name,
+			Prior:         fmt.Sprintf("NIG(%g, %g, %g, %g)", p.Mu, p.Kappa, p.Alpha, p.Beta),
+			PriorMean:     &priorMean,
+			PosteriorMean: posterior.Mu,
+			PosteriorSD:   mean.SD,
+			Lower:         mean.Lower,
+			Upper:         mean.Upper,
+		})
+	}
+	return entries
+}
+
+// Handler: Get prior sensitivity
+// Reruns an item rate, conditional probability or class mean analysis under several priors and
+// reports how far each prior moves the posterior from the reference (first) prior
+func getPriorSensitivity(w http.ResponseWriter, r *http.Request) {
+	analysis := r.URL.Query().Get("analysis")
+	switch analysis {
+	case "item-rate", "conditional-probability", "class-mean":
+	case "":
+		writeJSONError(w, http.StatusBadRequest, ErrorResponse{Error: "Missing 'analysis' parameter", Parameter: "analysis"})
+		return
+	default:
+		writeJSONError(w, http.StatusBadRequest, ErrorResponse{Error: "Invalid 'analysis' parameter (must be item-rate, conditional-probability or class-mean)", Parameter: "analysis"})
+		return
+	}
+	level, err := floatParam(r, "level", 0.95)
+	if err != nil || level <= 0 || level >= 1 {
+		writeJSONError(w, http.StatusBadRequest, ErrorResponse{Error: "Invalid 'level' parameter (must be between 0 and 1)", Parameter: "level"})
+		return
+	}
+
+	betaPriors := defaultBetaPriors
+	nigPriors := defaultNIGPriors
+	if priorsStr := r.URL.Query().Get("priors"); priorsStr != "" {
+		if analysis == "class-mean" {
+			nigPriors, err = parseNIGPriorList(priorsStr)
+		} else {
+			betaPriors, err = parseBetaPriorList(priorsStr)
+		}
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, ErrorResponse{Error: "Invalid 'priors' parameter: " + err.Error(), Parameter: "priors"})
+			return
+		}
+	}
+
+	response := PriorSensitivityResponse{Analysis: analysis, Level: level}
+	var successes, trials int
+	switch analysis {
+	case "item-rate":
+		q, err := parseQuestion(r.URL.Query().Get("question"))
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, ErrorResponse{Error: "Invalid 'question' parameter (must be q1-q10)", Parameter: "question"})
+			return
+		}
+		response.Description = fmt.Sprintf("P(Q%d=1)", q)
+		for _, g := range grades {
+			successes += getQuestionValue(g, q)
+		}
+		trials = len(grades)
+	case "conditional-probability":
+		var exprs [2]filterExpr
+		for i, name := range []string{"condition", "hypothesis"} {
+			expression := r.URL.Query().Get(name)
+			exprs[i], err = parseFilterExpression(expression)
+			if err != nil {
+				writeJSONError(w, http.StatusBadRequest, expressionErrorResponse(name, expression, err))
+				return
+			}
+		}
+		response.Description = fmt.Sprintf("P(%s | %s)", exprs[1], exprs[0])
+		counts := countBayes(exprs[0], exprs[1])
+		successes, trials = counts.both, counts.condition
+	}
+
+	if len(grades) == 0 {
+		writeJSONError(w, http.StatusInternalServerError, ErrorResponse{Error: "No data available"})
+		return
+	}
+
+	if analysis == "class-mean" {
+		response.Description = "mean Total"
+		response.Results = classMeanSensitivity(totalColumn(), nigPriors, level)
+	} else {
+		response.Successes, response.Trials = &successes, &trials
+		response.Results = betaRateSensitivity(successes, trials, betaPriors, level)
+	}
+
+	reference := response.Results[0]
+	response.Reference = reference.Name
+	if reference.PosteriorSD != nil {
+		response.ReferenceSD = *reference.PosteriorSD
+	}
+	referenceWidth := reference.Upper - reference.Lower
+	for i := range response.Results {
+		entry := &response.Results[i]
+		entry.ShiftFromReference = entry.PosteriorMean - reference.PosteriorMean
+		if referenceWidth > 0 {
+			entry.WidthRatio = (entry.Upper - entry.Lower) / referenceWidth
+		}
+		response.MaxShift = math.Max(response.MaxShift, math.Abs(entry.ShiftFromReference))
+	}
+	response.Sensitive = response.ReferenceSD > 0 && response.MaxShift > sensitivityThreshold*response.ReferenceSD
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// elicitBetaFromInterval finds the Beta prior with the given mean that puts probability prob on
+// [lower, upper], searching the strength κ = α+β by bisection on log κ
+func elicitBetaFromInterval(mean, lower, upper, prob float64) (float64, float64, error) {
+	coverage := func(logKappa float64) float64 {
+		kappa := math.Exp(logKappa)
+		a, b := mean*kappa, (1-mean)*kappa
+		return regularizedIncompleteBeta(upper, a, b) - regularizedIncompleteBeta(lower, a, b)
+	}
+	lo, hi := math.Log(0.1), math.Log(1e6)
+	if coverage(hi) < prob {
+		return 0, 0, errors.New("interval is too narrow for the requested probability")
+	}
+	if coverage(lo) > prob {
+		return 0, 0, errors.New("interval is too wide for the requested probability")
+	}
+	for iter := 0; iter < 100; iter++ {
+		mid := (lo + hi) / 2
+		if coverage(mid) < prob {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	kappa := math.Exp((lo + hi) / 2)
+	return mean * kappa, (1 - mean) * kappa, nil
+}
+
+// Handler: Get prior elicitation
+// Converts a belief such as "about 70% correct, fairly sure" (mean=0.7&certainty=fairly-sure)
+// or "90% sure it is between 60% and 80%" (mean=0.7&lower=0.6&upper=0.8&probability=0.9)
+// into Beta prior parameters
+func getPriorElicitation(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query()
+	if query.Get("mean") == "" {
+		http.Error(w, "Missing 'mean' parameter", http.StatusBadRequest)
+		return
+	}
+	mean, err := floatParam(r, "mean", 0)
+	if err != nil || mean <= 0 || mean >= 1 {
+		http.Error(w, "Invalid 'mean' parameter (must be between 0 and 1)", http.StatusBadRequest)
+		return
+	}
+
+	var alpha, beta float64
+	method := "strength"
+	if query.Get("lower") != "" || query.Get("upper") != "" {
+		method = "interval"
+		lower, errL := floatParam(r, "lower", 0)
+		upper, errU := floatParam(r, "upper", 0)
+		if errL != nil || errU != nil || !(0 <= lower && lower < mean && mean < upper && upper <= 1) {
+			http.Error(w, "Invalid 'lower'/'upper' parameters (must satisfy 0 ≤ lower < mean < upper ≤ 1)", http.StatusBadRequest)
+			return
+		}
+		probability, err := floatParam(r, "probability", 0.9)
+		if err != nil || probability <= 0 || probability >= 1 {
+			http.Error(w, "Invalid 'probability' parameter (must be between 0 and 1)", http.StatusBadRequest)
+			return
+		}
+		alpha, beta, err = elicitBetaFromInterval(mean, lower, upper, probability)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else {
+		strength := elicitationStrengths["fairly-sure"]
+		if certainty := query.Get("certainty"); certainty != "" {
+			var ok bool
+			strength, ok = elicitationStrengths[certainty]
+			if !ok {
+				http.Error(w, "Invalid 'certainty' parameter (must be unsure, somewhat-sure, fairly-sure or very-sure)", http.StatusBadRequest)
+				return
+			}
+		}
+		if query.Get("strength") != "" {
+			strength, err = floatParam(r, "strength", 0)
+			if err != nil || strength <= 0 {
+				http.Error(w, "Invalid 'strength' parameter (must be positive)", http.StatusBadRequest)
+				return
+			}
+		}
+		alpha, beta = mean*strength, (1-mean)*strength
+	}
+
+	lower95, upper95 := betaCredibleInterval(alpha, beta, 0.95)
+	kappa := alpha + beta
+	response := PriorElicitationResponse{
+		Method:   method,
+		Alpha:    alpha,
+		Beta:     beta,
+		Mean:     alpha / kappa,
+		SD:       math.Sqrt(alpha * beta / (kappa * kappa * (kappa + 1))),
+		Strength: kappa,
+		Lower95:  lower95,
+		Upper95:  upper95,
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// TestPriorSensitivityItemRate - 少数データでは事前分布の影響が大きいテスト
+func TestPriorSensitivityItemRate(t *testing.T) {
+	setupTestData()
+
+	var result PriorSensitivityResponse
+	serveJSON(t, getPriorSensitivity, "GET", "/api/prior-sensitivity?analysis=item-rate&question=q8", "", &result)
+
+	if *result.Successes != 1 || *result.Trials != 3 || result.Reference != "uniform" {
+		t.Fatalf("unexpected counts or reference: %+v", result)
+	}
+	if len(result.Results) != len(defaultBetaPriors) {
+		t.Fatalf("expected %d priors, got %d", len(defaultBetaPriors), len(result.Results))
+	}
+	// 一様事前分布 Beta(1,1) → Beta(2, 3)、平均 0.4
+	if math.Abs(result.Results[0].PosteriorMean-0.4) > 1e-12 || result.Results[0].ShiftFromReference != 0 {
+		t.Errorf("unexpected reference posterior %+v", result.Results[0])
+	}
+	// 楽観的事前分布 Beta(7,3) → Beta(8, 5)
+	optimistic := result.Results[3]
+	if math.Abs(optimistic.PosteriorMean-8.0/13.0) > 1e-12 || optimistic.WidthRatio >= 1 {
+		t.Errorf("unexpected optimistic posterior %+v", optimistic)
+	}
+	if !result.Sensitive {
+		t.Error("expected 3 students to be prior sensitive")
+	}
+}
+
+// TestPriorSensitivityLargeSample - 大量データでは事前分布の影響が小さいテスト
+func TestPriorSensitivityLargeSample(t *testing.T) {
+	totals := make([]int, 2000)
+	for i := range totals {
+		totals[i] = 3 + i%5
+	}
+	setupTotalsTestData(totals...)
+
+	var result PriorSensitivityResponse
+	serveJSON(t, getPriorSensitivity, "GET", "/api/prior-sensitivity?analysis=class-mean&priors="+url.QueryEscape("5:1:1:1,9:1:1:1"), "", &result)
+
+	if result.Description != "mean Total" || len(result.Results) != 2 {
+		t.Fatalf("unexpected response %+v", result)
+	}
+	if result.Sensitive || result.MaxShift > 0.01 {
+		t.Errorf("expected negligible prior influence with 2000 students, got shift %.4f", result.MaxShift)
+	}
+}
+
+// TestPriorSensitivityConditional - 条件付き確率の事前分布感度のテスト
+func TestPriorSensitivityConditional(t *testing.T) {
+	setupTestData()
+
+	query := "analysis=conditional-probability&condition=" + url.QueryEscape("q1==1") +
+		"&hypothesis=" + url.QueryEscape("q8==1") + "&priors=1:1,0.5:0.5"
+	var result PriorSensitivityResponse
+	serveJSON(t, getPriorSensitivity, "GET", "/api/prior-sensitivity?"+query, "", &result)
+
+	// Q1正解の2人のうちQ8正解は1人
+	if *result.Successes != 1 || *result.Trials != 2 {
+		t.Errorf("expected 1 of 2, got %d of %d", *result.Successes, *result.Trials)
+	}
+	if result.Results[1].Prior != "Beta(0.5, 0.5)" || result.Results[1].PosteriorMean != 0.5 {
+		t.Errorf("unexpected Jeffreys result %+v", result.Results[1])
+	}
+}
+
+// TestPriorElicitation - 言葉による信念からベータ分布への変換のテスト
+func TestPriorElicitation(t *testing.T) {
+	testCases := []struct {
+		name          string
+		query         string
+		alpha, beta   float64
+		intervalCheck bool
+	}{
+		{"fairly_sure", "mean=0.7&certainty=fairly-sure", 17.5, 7.5, false},
+		{"strength", "mean=0.7&strength=10", 7, 3, false},
+		{"interval", "mean=0.7&lower=0.6&upper=0.8&probability=0.9", 0, 0, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var result PriorElicitationResponse
+			serveJSON(t, getPriorElicitation, "GET", "/api/prior-elicitation?"+tc.query, "", &result)
+			if math.Abs(result.Mean-0.7) > 1e-9 {
+				t.Errorf("expected mean 0.7, got %.6f", result.Mean)
+			}
+			if tc.intervalCheck {
+				coverage := regularizedIncompleteBeta(0.8, result.Alpha, result.Beta) -
+					regularizedIncompleteBeta(0.6, result.Alpha, result.Beta)
+				if result.Method != "interval" || math.Abs(coverage-0.9) > 1e-6 {
+					t.Errorf("expected 90%% mass on [0.6, 0.8], got %.6f", coverage)
+				}
+			} else if math.Abs(result.Alpha-tc.alpha) > 1e-9 || math.Abs(result.Beta-tc.beta) > 1e-9 {
+				t.Errorf("expected Beta(%.1f, %.1f), got Beta(%.4f, %.4f)", tc.alpha, tc.beta, result.Alpha, result.Beta)
+			}
+		})
+	}
+}
+
+// TestPriorSensitivityInvalidParams - 無効なパラメータのテスト
+func TestPriorSensitivityInvalidParams(t *testing.T) {
+	setupTestData()
+
+	testCases := []struct {
+		name string
+		path string
+	}{
+		{"missing_analysis", "/api/prior-sensitivity"},
+		{"invalid_analysis", "/api/prior-sensitivity?analysis=variance"},
+		{"invalid_question", "/api/prior-sensitivity?analysis=item-rate&question=q11"},
+		{"invalid_priors", "/api/prior-sensitivity?analysis=item-rate&question=q1&priors=1:0"},
+		{"invalid_nig_priors", "/api/prior-sensitivity?analysis=class-mean&priors=1:1"},
+		{"nan_priors", "/api/prior-sensitivity?analysis=item-rate&question=q1&priors=NaN:1"},
+		{"infinite_priors", "/api/prior-sensitivity?analysis=item-rate&question=q1&priors=1:Inf"},
+		{"nan_nig_mean", "/api/prior-sensitivity?analysis=class-mean&priors=NaN:1:1:1"},
+		{"infinite_nig_mean", "/api/prior-sensitivity?analysis=class-mean&priors=-Inf:1:1:1"},
+		{"infinite_nig_scale", "/api/prior-sensitivity?analysis=class-mean&priors=5:1:1:Inf"},
+		{"missing_condition", "/api/prior-sensitivity?analysis=conditional-probability&hypothesis=q1==1"},
+		{"invalid_level", "/api/prior-sensitivity?analysis=class-mean&level=0"},
+		{"missing_mean", "/api/prior-elicitation"},
+		{"invalid_mean", "/api/prior-elicitation?mean=1.2"},
+		{"invalid_certainty", "/api/prior-elicitation?mean=0.7&certainty=maybe"},
+		{"invalid_interval", "/api/prior-elicitation?mean=0.7&lower=0.8&upper=0.9"},
+		{"interval_too_narrow", "/api/prior-elicitation?mean=0.5&lower=0.4999999&upper=0.5000001&probability=0.99"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", tc.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(getPriorSensitivity)
+			if req.URL.Path == "/api/prior-elicitation" {
+				handler = http.HandlerFunc(getPriorElicitation)
+			}
+			handler.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != http.StatusBadRequest {
+				t.Errorf("handler returned wrong status code: got %v want %v",
+					status, http.StatusBadRequest)
+			}
+		})
+	}
+}
+
+// TestPriorSensitivityExpressionError - 式の構文エラーが位置付きのJSONで返るかのテスト
+func TestPriorSensitivityExpressionError(t *testing.T) {
+	setupTestData()
+
+	rr := serveRequest(t, getPriorSensitivity, "GET", "/api/prior-sensitivity?analysis=conditional-probability&condition=q1==1&hypothesis="+url.QueryEscape("q2==1 &&"), "")
+	var result ErrorResponse
+	decodeJSON(t, rr, http.StatusBadRequest, &result)
+	if result.Parameter != "hypothesis" || result.Expression != "q2==1 &&" || result.Position == nil {
+		t.Errorf("expected the hypothesis expression and error position, got %+v", result)
+	}
+}
+
+// TestPriorSensitivityEmptyData - データが空の場合のテスト
+func TestPriorSensitivityEmptyData(t *testing.T) {
+	grades = []Grade{}
+
+	req, err := http.NewRequest("GET", "/api/prior-sensitivity?analysis=class-mean", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(getPriorSensitivity)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusInternalServerError {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// maxSkills bounds the number of skills, since cognitive diagnosis enumerates all 2^K profiles
+const maxSkills = 8
+
+// QMatrix maps every item to the skills it requires: Matrix[j][k] is 1 when question j+1 needs
+// skill k
+type QMatrix struct {
+	Skills []string `json:"skills"`
+	Matrix [][]int  `json:"matrix"`
+}
+
+// qMatrixStore holds the uploaded Q-matrix. Unlike grades it is written while the server runs,
+// so access goes through the lock.
+var qMatrixStore struct {
+	sync.RWMutex
+	current *QMatrix
+}
+
+// currentQMatrix returns the uploaded Q-matrix, or nil when none has been uploaded
+func currentQMatrix() *QMatrix {
+	qMatrixStore.RLock()
+	defer qMatrixStore.RUnlock()
+	return qMatrixStore.current
+}
+
+// validate checks that the Q-matrix has one 0/1 row per item, that every item needs at least
+// one skill and that every skill is measured by at least one item
+func (q QMatrix) validate() error {
+	if len(q.Skills) == 0 || len(q.Skills) > maxSkills {
+		return fmt.Errorf("skills must list between 1 and %d skills", maxSkills)
+	}
+	seen := make(map[string]bool)
+	for _, skill := range q.Skills {
+		if skill == "" || seen[skill] {
+			return fmt.Errorf("skill names must be unique and non-empty")
+		}
+		seen[skill] = true
+	}
+	if len(q.Matrix) != numQuestions {
+		return fmt.Errorf("matrix must have one row per item (%d rows)", numQuestions)
+	}
+	measured := make([]bool, len(q.Skills))
+	for j, row := range q.Matrix {
+		if len(row) != len(q.Skills) {
+			return fmt.Errorf("row Q%d must have one entry per skill (%d entries)", j+1, len(q.Skills))
+		}
+		required := 0
+		for k, v := range row {
+			if v != 0 && v != 1 {
+				return fmt.Errorf("row Q%d must contain only 0 and 1", j+1)
+			}
+			if v == 1 {
+				required++
+				measured[k] = true
+			}
+		}
+		if required == 0 {
+			return fmt.Errorf("item Q%d must require at least one skill", j+1)
+		}
+	}
+	for k, ok := range measured {
+		if !ok {
+			return fmt.Errorf("skill %q is not required by any item", q.Skills[k])
+		}
+	}
+	return nil
+}
+
+// skillsOf returns the names of the skills required by question j+1
+func (q QMatrix) skillsOf(j int) []string {
+	var skills []string
+	for k, v := range q.Matrix[j] {
+		if v == 1 {
+			skills = append(skills, q.Skills[k])
+		}
+	}
+	return skills
+}
+
+// Handler: Get the uploaded Q-matrix
+func getQMatrix(w http.ResponseWriter, r *http.Request) {
+	q := currentQMatrix()
+	if q == nil {
+		writeJSONError(w, http.StatusNotFound, ErrorResponse{Error: "No Q-matrix has been uploaded"})
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(q)
+}
+
+// Handler: Upload a Q-matrix
+// Replaces the Q-matrix used for cognitive diagnosis with the JSON body
+// {"skills": [...], "matrix": [[...], ...]}
+func putQMatrix(w http.ResponseWriter, r *http.Request) {
+	var q QMatrix
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&q); err != nil {
+		writeJSONError(w, http.StatusBadRequest, ErrorResponse{Error: "Invalid Q-matrix JSON: " + err.Error()})
+		return
+	}
+	if err := q.validate(); err != nil {
+		writeJSONError(w, http.StatusBadRequest, ErrorResponse{Error: "Invalid Q-matrix: " + err.Error()})
+		return
+	}
+
+	qMatrixStore.Lock()
+	qMatrixStore.current = &q
+	qMatrixStore.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(q)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestQMatrixUpload - Q行列のアップロードと取得のテスト
+func TestQMatrixUpload(t *testing.T) {
+	body := `{"skills":["add","sub"],"matrix":[[1,0],[1,0],[1,0],[0,1],[0,1],[0,1],[1,1],[1,1],[1,1],[1,1]]}`
+	if status := serveRequest(t, putQMatrix, "PUT", "/api/q-matrix", body).Code; status != http.StatusOK {
+		t.Fatalf("expected 200, got %d", status)
+	}
+
+	q := currentQMatrix()
+	if q == nil || len(q.Skills) != 2 || q.Matrix[6][1] != 1 {
+		t.Fatalf("unexpected stored Q-matrix %+v", q)
+	}
+	if skills := q.skillsOf(8); len(skills) != 2 || skills[0] != "add" {
+		t.Errorf("unexpected skills for Q9: %v", skills)
+	}
+
+	req, _ := http.NewRequest("GET", "/api/q-matrix", nil)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(getQMatrix).ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"sub"`) {
+		t.Errorf("unexpected GET response %d %s", rr.Code, rr.Body.String())
+	}
+}
+
+// TestQMatrixUploadInvalid - 不正なQ行列が拒否され、保存済みの行列が変わらないことのテスト
+func TestQMatrixUploadInvalid(t *testing.T) {
+	valid := `{"skills":["a"],"matrix":[[1],[1],[1],[1],[1],[1],[1],[1],[1],[1]]}`
+	if status := serveRequest(t, putQMatrix, "PUT", "/api/q-matrix", valid).Code; status != http.StatusOK {
+		t.Fatalf("expected 200, got %d", status)
+	}
+
+	for _, body := range []string{
+		`not json`,
+		`{"skills":[],"matrix":[]}`,
+		`{"skills":["a","a"],"matrix":[[1,1],[1,1],[1,1],[1,1],[1,1],[1,1],[1,1],[1,1],[1,1],[1,1]]}`,
+		`{"skills":["a"],"matrix":[[1],[1],[1]]}`,
+		`{"skills":["a"],"matrix":[[1],[1],[1],[1],[1],[1],[1],[1],[1],[0]]}`,
+		`{"skills":["a"],"matrix":[[1],[1],[1],[1],[1],[1],[1],[1],[1],[2]]}`,
+		`{"skills":["a","b"],"matrix":[[1,0],[1,0],[1,0],[1,0],[1,0],[1,0],[1,0],[1,0],[1,0],[1,0]]}`,
+		`{"skills":["a"],"matrix":[[1],[1],[1],[1],[1],[1],[1],[1],[1],[1]],"extra":1}`,
+	} {
+		if status := serveRequest(t, putQMatrix, "PUT", "/api/q-matrix", body).Code; status != http.StatusBadRequest {
+			t.Errorf("body %s: expected 400, got %d", body, status)
+		}
+	}
+	if q := currentQMatrix(); q == nil || q.Skills[0] != "a" {
+		t.Errorf("invalid uploads must not replace the stored Q-matrix, got %+v", q)
+	}
+}
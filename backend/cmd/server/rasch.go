@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// maxSamplerWork caps chains × (warmup + draws) × leapfrog steps × parameters so a request
+// stays fast. Leapfrog steps are counted at their worst case (see maxLeapfrogSteps).
+const maxSamplerWork = 2000000000
+
+// RaschItemParameter represents the posterior difficulty of one item
+type RaschItemParameter struct {
+	Question   string           `json:"question"`
+	RawRate    float64          `json:"raw_rate"`
+	Difficulty ParameterSummary `json:"difficulty"`
+}
+
+// RaschStudentAbility represents the posterior ability of one student
+type RaschStudentAbility struct {
+	StudentID int              `json:"student_id"`
+	Total     int              `json:"total"`
+	Ability   ParameterSummary `json:"ability"`
+}
+
+// RaschResponse represents a Bayesian Rasch model fitted by HMC or NUTS
+type RaschResponse struct {
+	N                 int                   `json:"n"`
+	Level             float64               `json:"level"`
+	DifficultyPriorSD float64               `json:"difficulty_prior_sd"`
+	Sampler           SamplerDiagnostics    `json:"sampler"`
+	Items             []RaschItemParameter  `json:"items"`
+	Students          []RaschStudentAbility `json:"students,omitempty"`
+}
+
+// raschModel is the Rasch model P(x_ij = 1) = σ(θ_i - b_j) with priors θ_i ~ N(0, 1), which fixes
+// the location and scale of the ability metric, and b_j ~ N(0, difficultySD²). The parameter
+// vector is (θ_1..θ_n, b_1..b_J).
+type raschModel struct {
+	responses    [][]int
+	difficultySD float64
+}
+
+func (m raschModel) dimension() int {
+	return len(m.responses) + numQuestions
+}
+
+func (m raschModel) logDensityGradient(x, grad []float64) float64 {
+	n := len(m.responses)
+	theta, b := x[:n], x[n:]
+	gradTheta, gradB := grad[:n], grad[n:]
+	precision := 1 / (m.difficultySD * m.difficultySD)
+
+	var logp float64
+	for j := range b {
+		logp -= 0.5 * precision * b[j] * b[j]
+		gradB[j] = -precision * b[j]
+	}
+	for i, row := range m.responses {
+		logp -= 0.5 * theta[i] * theta[i]
+		gradTheta[i] = -theta[i]
+		for j, x := range row {
+			eta := theta[i] - b[j]
+			var residual float64
+			if x == 1 {
+				logp += logSigmoid(eta)
+				residual = 1 - sigmoid(eta)
+			} else {
+				logp += logSigmoid(-eta)
+				residual = -sigmoid(eta)
+			}
+			gradTheta[i] += residual
+			gradB[j] -= residual
+		}
+	}
+	return logp
+}
+
+// parseSamplerConfig reads the shared sampler parameters of gradient-based endpoints
+func parseSamplerConfig(r *http.Request) (samplerConfig, error) {
+	config := samplerConfig{method: r.URL.Query().Get("sampler")}
+	switch config.method {
+	case "":
+		config.method = "nuts"
+	case "nuts", "hmc":
+	default:
+		return config, errors.New("Invalid 'sampler' parameter (must be nuts or hmc)")
+	}
+
+	var err error
+	if config.chains, err = intParam(r, "chains", 4); err != nil || config.chains < 1 || config.chains > 8 {
+		return config, errors.New("Invalid 'chains' parameter (must be between 1 and 8)")
+	}
+	if config.warmup, err = intParam(r, "warmup", 500); err != nil || config.warmup < 0 || config.warmup > 10000 {
+		return config, errors.New("Invalid 'warmup' parameter (must be between 0 and 10000)")
+	}
+	if config.draws, err = intParam(r, "draws", 500); err != nil || config.draws < 10 || config.draws > 10000 {
+		return config, errors.New("Invalid 'draws' parameter (must be between 10 and 10000)")
+	}
+	seed, err := intParam(r, "seed", 1)
+	if err != nil {
+		return config, errors.New("Invalid 'seed' parameter")
+	}
+	config.seed = int64(seed)
+	if config.targetAccept, err = floatParam(r, "target_accept", 0.8); err != nil ||
+		config.targetAccept <= 0 || config.targetAccept >= 1 {
+		return config, errors.New("Invalid 'target_accept' parameter (must be between 0 and 1)")
+	}
+	if config.maxTreeDepth, err = intParam(r, "max_tree_depth", 10); err != nil ||
+		config.maxTreeDepth < 1 || config.maxTreeDepth > 12 {
+		return config, errors.New("Invalid 'max_tree_depth' parameter (must be between 1 and 12)")
+	}
+	if config.leapfrogSteps, err = intParam(r, "leapfrog_steps", 20); err != nil ||
+		config.leapfrogSteps < 1 || config.leapfrogSteps > 1000 {
+		return config, errors.New("Invalid 'leapfrog_steps' parameter (must be between 1 and 1000)")
+	}
+	return config, nil
+}
+
+// Handler: Get Rasch model
+// Fits a Bayesian Rasch model to the response matrix with NUTS (default) or static HMC and
+// reports item difficulties, student abilities and sampler diagnostics
+func getRaschModel(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	config, err := parseSamplerConfig(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	level, err := floatParam(r, "level", 0.95)
+	if err != nil || level <= 0 || level >= 1 {
+		http.Error(w, "Invalid 'level' parameter (must be between 0 and 1)", http.StatusBadRequest)
+		return
+	}
+	difficultySD, err := floatParam(r, "difficulty_prior_sd", 3)
+	if err != nil || difficultySD <= 0 {
+		http.Error(w, "Invalid 'difficulty_prior_sd' parameter (must be positive)", http.StatusBadRequest)
+		return
+	}
+	includeStudents := r.URL.Query().Get("students") != "false"
+
+	if len(grades) == 0 {
+		http.Error(w, "No data available", http.StatusInternalServerError)
+		return
+	}
+
+	model := raschModel{difficultySD: difficultySD}
+	for _, g := range grades {
+		row := make([]int, numQuestions)
+		for j := range row {
+			row[j] = getQuestionValue(g, j+1)
+			if row[j] != 0 && row[j] != 1 {
+				http.Error(w, fmt.Sprintf("student %d has non-binary Q%d", g.StudentID, j+1), http.StatusInternalServerError)
+				return
+			}
+		}
+		model.responses = append(model.responses, row)
+	}
+	if config.chains*(config.warmup+config.draws)*config.maxLeapfrogSteps()*model.dimension() > maxSamplerWork {
+		http.Error(w, "Too much work: chains × (warmup + draws) × leapfrog steps × parameters must not exceed 2000000000", http.StatusBadRequest)
+		return
+	}
+
+	results := sampleGradientModel(model, config)
+
+	n := len(grades)
+	summaries := make([]ParameterSummary, model.dimension())
+	for a := range summaries {
+		summaries[a] = summarizeParameter(results, a, level)
+	}
+
+	response := RaschResponse{
+		N:                 n,
+		Level:             level,
+		DifficultyPriorSD: difficultySD,
+		Sampler:           summarizeSampler(config, results, summaries),
+	}
+	for j, label := range questionLabels() {
+		correct := 0
+		for _, row := range model.responses {
+			correct += row[j]
+		}
+		response.Items = append(response.Items, RaschItemParameter{
+			Question:   label,
+			RawRate:    float64(correct) / float64(n),
+			Difficulty: summaries[n+j],
+		})
+	}
+	if includeStudents {
+		for i, g := range grades {
+			response.Students = append(response.Students, RaschStudentAbility{
+				StudentID: g.StudentID,
+				Total:     g.Total,
+				Ability:   summaries[i],
+			})
+		}
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
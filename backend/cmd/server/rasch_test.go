@@ -0,0 +1,144 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// setupRaschTestData - 既知の難易度からRaschモデルで生成したデータ
+func setupRaschTestData(students int, difficulties []float64, seed int64) {
+	rng := rand.New(rand.NewSource(seed))
+	grades = []Grade{}
+	for i := 0; i < students; i++ {
+		ability := rng.NormFloat64()
+		g := Grade{StudentID: i + 1}
+		values := []*int{&g.Q1, &g.Q2, &g.Q3, &g.Q4, &g.Q5, &g.Q6, &g.Q7, &g.Q8, &g.Q9, &g.Q10}
+		for j, b := range difficulties {
+			if rng.Float64() < sigmoid(ability-b) {
+				*values[j] = 1
+				g.Total++
+			}
+		}
+		grades = append(grades, g)
+	}
+}
+
+// TestRaschModelRecovery - 難易度の推定のテスト
+func TestRaschModelRecovery(t *testing.T) {
+	difficulties := []float64{-2, -1.5, -1, -0.5, 0, 0, 0.5, 1, 1.5, 2}
+	setupRaschTestData(150, difficulties, 5)
+
+	var result RaschResponse
+	serveJSON(t, getRaschModel, "GET", "/api/irt/rasch?chains=2&warmup=300&draws=300&seed=2", "", &result)
+
+	if result.Sampler.Method != "nuts" || result.Sampler.Divergences != 0 {
+		t.Errorf("expected NUTS without divergences, got %+v", result.Sampler)
+	}
+	if result.Sampler.MaxRHat > 1.05 {
+		t.Errorf("expected R-hat near 1, got %.4f", result.Sampler.MaxRHat)
+	}
+	if len(result.Items) != 10 || len(result.Students) != 150 {
+		t.Fatalf("expected 10 items and 150 students, got %d and %d", len(result.Items), len(result.Students))
+	}
+	for j, item := range result.Items {
+		if math.Abs(item.Difficulty.Mean-difficulties[j]) > 0.6 {
+			t.Errorf("%s: difficulty %.3f far from true %.1f", item.Question, item.Difficulty.Mean, difficulties[j])
+		}
+	}
+
+	// 合計点が高い学生ほど能力の推定値が高い
+	for _, a := range result.Students {
+		for _, b := range result.Students {
+			if a.Total > b.Total && a.Ability.Mean <= b.Ability.Mean {
+				t.Fatalf("student %d (total %d) has ability %.3f not above student %d (total %d) at %.3f",
+					a.StudentID, a.Total, a.Ability.Mean, b.StudentID, b.Total, b.Ability.Mean)
+			}
+		}
+	}
+}
+
+// TestRaschGradient - 対数密度の勾配を数値微分と比較するテスト
+func TestRaschGradient(t *testing.T) {
+	model := raschModel{responses: [][]int{{1, 0, 1, 1, 0, 0, 1, 0, 1, 1}, {0, 0, 1, 0, 0, 0, 1, 0, 0, 1}}, difficultySD: 3}
+	x := make([]float64, model.dimension())
+	for a := range x {
+		x[a] = 0.1 * float64(a%5-2)
+	}
+	grad := make([]float64, len(x))
+	model.logDensityGradient(x, grad)
+
+	scratch := make([]float64, len(x))
+	for a := range x {
+		const h = 1e-6
+		plus := append([]float64(nil), x...)
+		minus := append([]float64(nil), x...)
+		plus[a] += h
+		minus[a] -= h
+		numeric := (model.logDensityGradient(plus, scratch) - model.logDensityGradient(minus, scratch)) / (2 * h)
+		if math.Abs(numeric-grad[a]) > 1e-5 {
+			t.Errorf("parameter %d: gradient %.6f, numeric %.6f", a, grad[a], numeric)
+		}
+	}
+}
+
+// TestRaschModelInvalidParams - 無効なパラメータのテスト
+func TestRaschModelInvalidParams(t *testing.T) {
+	setupTestData()
+
+	testCases := []struct {
+		name  string
+		query string
+	}{
+		{"invalid_sampler", "sampler=gibbs"},
+		{"invalid_chains", "chains=0"},
+		{"invalid_warmup", "warmup=-1"},
+		{"invalid_draws", "draws=5"},
+		{"invalid_target_accept", "target_accept=1"},
+		{"invalid_max_tree_depth", "max_tree_depth=20"},
+		{"invalid_leapfrog_steps", "sampler=hmc&leapfrog_steps=0"},
+		{"invalid_level", "level=0"},
+		{"invalid_prior_sd", "difficulty_prior_sd=-1"},
+		{"too_deep_trees", "chains=8&warmup=10000&draws=10000&max_tree_depth=12"},
+		{"too_many_leapfrog_steps", "sampler=hmc&chains=8&warmup=10000&draws=10000&leapfrog_steps=1000"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "/api/irt/rasch?"+tc.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(getRaschModel)
+			handler.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != http.StatusBadRequest {
+				t.Errorf("handler returned wrong status code: got %v want %v",
+					status, http.StatusBadRequest)
+			}
+		})
+	}
+}
+
+// TestRaschModelEmptyData - データが空の場合のテスト
+func TestRaschModelEmptyData(t *testing.T) {
+	grades = []Grade{}
+
+	req, err := http.NewRequest("GET", "/api/irt/rasch", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(getRaschModel)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusInternalServerError {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusInternalServerError)
+	}
+}
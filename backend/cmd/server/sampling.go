@@ -0,0 +1,84 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+)
+
+// sampleGamma draws from Gamma(shape, 1) using Marsaglia and Tsang's method
+func sampleGamma(rng *rand.Rand, shape float64) float64 {
+	if shape < 1 {
+		// Boost small shapes: Gamma(a) = Gamma(a+1) · U^(1/a)
+		return sampleGamma(rng, shape+1) * math.Pow(rng.Float64(), 1/shape)
+	}
+	d := shape - 1.0/3.0
+	c := 1 / math.Sqrt(9*d)
+	for {
+		x := rng.NormFloat64()
+		v := 1 + c*x
+		if v <= 0 {
+			continue
+		}
+		v = v * v * v
+		u := rng.Float64()
+		if u < 1-0.0331*x*x*x*x {
+			return d * v
+		}
+		if math.Log(u) < 0.5*x*x+d*(1-v+math.Log(v)) {
+			return d * v
+		}
+	}
+}
+
+// sampleBeta draws from Beta(a, b)
+func sampleBeta(rng *rand.Rand, a, b float64) float64 {
+	x := sampleGamma(rng, a)
+	y := sampleGamma(rng, b)
+	if x+y == 0 {
+		// Both gammas underflowed (tiny shapes); fall back to the mean
+		return a / (a + b)
+	}
+	return x / (x + y)
+}
+
+// sampleBinomial draws from Binomial(n, p) by summing Bernoulli trials (n is small here)
+func sampleBinomial(rng *rand.Rand, n int, p float64) int {
+	k := 0
+	for i := 0; i < n; i++ {
+		if rng.Float64() < p {
+			k++
+		}
+	}
+	return k
+}
+
+// lbeta returns log B(a, b)
+func lbeta(a, b float64) float64 {
+	la, _ := math.Lgamma(a)
+	lb, _ := math.Lgamma(b)
+	lab, _ := math.Lgamma(a + b)
+	return la + lb - lab
+}
+
+// logSumExp returns log Σ exp(x_i) without overflow
+func logSumExp(x []float64) float64 {
+	maxValue := math.Inf(-1)
+	for _, v := range x {
+		if v > maxValue {
+			maxValue = v
+		}
+	}
+	if math.IsInf(maxValue, -1) {
+		return maxValue
+	}
+	var sum float64
+	for _, v := range x {
+		sum += math.Exp(v - maxValue)
+	}
+	return maxValue + math.Log(sum)
+}
+
+// sampleInverseGamma draws from InverseGamma(shape, scale), i.e. scale / Gamma(shape, 1)
+func sampleInverseGamma(rng *rand.Rand, shape, scale float64) float64 {
+	return scale / sampleGamma(rng, shape)
+}
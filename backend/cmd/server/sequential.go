@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+)
+
+// SequentialItemPosterior represents the Beta posterior of one item's correct rate
+type SequentialItemPosterior struct {
+	Question string  `json:"question"`
+	Alpha    float64 `json:"alpha"`
+	Beta     float64 `json:"beta"`
+	Mean     float64 `json:"mean"`
+	Lower    float64 `json:"lower"`
+	Upper    float64 `json:"upper"`
+}
+
+// SequentialStep represents the posterior after the first Step students. Step 0 is the prior.
+type SequentialStep struct {
+	Step      int                       `json:"step"`
+	StudentID *int                      `json:"student_id"`
+	Total     *int                      `json:"total"`
+	ClassMean AnalyticSummary           `json:"class_mean"`
+	Variance  AnalyticSummary           `json:"variance"`
+	Posterior NIGParameters             `json:"posterior"`
+	Items     []SequentialItemPosterior `json:"items"`
+}
+
+// SequentialUpdateResponse represents the sequence of posteriors as grades arrive
+type SequentialUpdateResponse struct {
+	N         int              `json:"n"`
+	Level     float64          `json:"level"`
+	Every     int              `json:"every"`
+	MeanPrior NIGParameters    `json:"mean_prior"`
+	ItemPrior BetaPrior        `json:"item_prior"`
+	Steps     []SequentialStep `json:"steps"`
+}
+
+// sequentialUpdater holds the running sufficient statistics of the conjugate models
+type sequentialUpdater struct {
+	meanPrior NIGParameters
+	itemPrior BetaPrior
+	level     float64
+	n         int
+	sum       float64
+	sumSq     float64
+	correct   []int
+}
+
+// add folds one student's grade into the running statistics
+func (u *sequentialUpdater) add(g Grade) {
+	total := float64(g.Total)
+	u.n++
+	u.sum += total
+	u.sumSq += total * total
+	for j := range u.correct {
+		u.correct[j] += getQuestionValue(g, j+1)
+	}
+}
+
+// step summarizes the current posterior
+func (u *sequentialUpdater) step() SequentialStep {
+	n := u.n
+	posterior := u.meanPrior
+	if n > 0 {
+		mean := u.sum / float64(n)
+		// Clamp the rounding error of the one-pass sum of squares
+		sumSquares := math.Max(0, u.sumSq-u.sum*mean)
+		posterior = updateNIGSufficient(u.meanPrior, float64(n), mean, sumSquares)
+	}
+	mean, variance := analyticNIGMarginals(posterior, u.level)
+	s := SequentialStep{Step: n, ClassMean: mean, Variance: variance, Posterior: posterior}
+	for j, label := range questionLabels() {
+		a := u.itemPrior.Alpha + float64(u.correct[j])
+		b := u.itemPrior.Beta + float64(n-u.correct[j])
+		lower, upper := betaCredibleInterval(a, b, u.level)
+		s.Items = append(s.Items, SequentialItemPosterior{
+			Question: label, Alpha: a, Beta: b, Mean: a / (a + b), Lower: lower, Upper: upper,
+		})
+	}
+	return s
+}
+
+// Handler: Get sequential Bayesian updating
+// Replays grades in arrival order, updating a Normal–Inverse-Gamma posterior for the class mean
+// Total and Beta posteriors for item correct rates after each student. With stream=true every
+// step is written and flushed as one line of newline-delimited JSON.
+func getSequentialUpdate(w http.ResponseWriter, r *http.Request) {
+	meanPrior, err := parseNIGPrior(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	itemPrior := BetaPrior{}
+	if itemPrior.Alpha, err = floatParam(r, "alpha", 1.0); err != nil || itemPrior.Alpha <= 0 {
+		http.Error(w, "Invalid 'alpha' parameter (must be positive)", http.StatusBadRequest)
+		return
+	}
+	if itemPrior.Beta, err = floatParam(r, "beta", 1.0); err != nil || itemPrior.Beta <= 0 {
+		http.Error(w, "Invalid 'beta' parameter (must be positive)", http.StatusBadRequest)
+		return
+	}
+	level, err := floatParam(r, "level", 0.95)
+	if err != nil || level <= 0 || level >= 1 {
+		http.Error(w, "Invalid 'level' parameter (must be between 0 and 1)", http.StatusBadRequest)
+		return
+	}
+	every, err := intParam(r, "every", 1)
+	if err != nil || every < 1 {
+		http.Error(w, "Invalid 'every' parameter (must be a positive integer)", http.StatusBadRequest)
+		return
+	}
+	streamStr := r.URL.Query().Get("stream")
+	if streamStr != "" && streamStr != "true" && streamStr != "false" {
+		http.Error(w, "Invalid 'stream' parameter (must be true or false)", http.StatusBadRequest)
+		return
+	}
+	stream := streamStr == "true"
+
+	if len(grades) == 0 {
+		http.Error(w, "No data available", http.StatusInternalServerError)
+		return
+	}
+
+	updater := &sequentialUpdater{
+		meanPrior: meanPrior,
+		itemPrior: itemPrior,
+		level:     level,
+		correct:   make([]int, numQuestions),
+	}
+
+	// Steps are emitted for the prior, every `every` students and the final student
+	var emit func(SequentialStep)
+	response := SequentialUpdateResponse{
+		N:         len(grades),
+		Level:     level,
+		Every:     every,
+		MeanPrior: meanPrior,
+		ItemPrior: itemPrior,
+	}
+	if stream {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		encoder := json.NewEncoder(w)
+		flusher, _ := w.(http.Flusher)
+		emit = func(s SequentialStep) {
+			encoder.Encode(s)
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		emit = func(s SequentialStep) { response.Steps = append(response.Steps, s) }
+	}
+
+	emit(updater.step())
+	for i, g := range grades {
+		updater.add(g)
+		if (i+1)%every == 0 || i == len(grades)-1 {
+			s := updater.step()
+			studentID, total := g.StudentID, g.Total
+			s.StudentID, s.Total = &studentID, &total
+			emit(s)
+		}
+	}
+
+	if !stream {
+		json.NewEncoder(w).Encode(response)
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestSequentialUpdate - 学生ごとの逐次更新のテスト
+func TestSequentialUpdate(t *testing.T) {
+	setupTestData()
+
+	var result SequentialUpdateResponse
+	serveJSON(t, getSequentialUpdate, "GET", "/api/sequential", "", &result)
+
+	if len(result.Steps) != 4 {
+		t.Fatalf("expected prior plus 3 steps, got %d", len(result.Steps))
+	}
+	prior := result.Steps[0]
+	if prior.Step != 0 || prior.StudentID != nil || prior.Items[0].Mean != 0.5 {
+		t.Errorf("expected step 0 to be the prior, got %+v", prior)
+	}
+	// 2人目まで: Q8 は 1人正解・1人不正解 → Beta(2, 2)
+	second := result.Steps[2]
+	if *second.StudentID != 2 || second.Items[7].Alpha != 2 || second.Items[7].Beta != 2 {
+		t.Errorf("unexpected Q8 posterior after 2 students: %+v", second.Items[7])
+	}
+
+	// 累積した十分統計量からの事後分布は、それまでの全データからの更新と一致する
+	prefix := []float64{10, 7, 0}
+	for i, step := range result.Steps[1:] {
+		expected := updateNIG(result.MeanPrior, prefix[:i+1])
+		got := step.Posterior
+		if got.Kappa != expected.Kappa || got.Alpha != expected.Alpha ||
+			math.Abs(got.Mu-expected.Mu) > 1e-9 || math.Abs(got.Beta-expected.Beta) > 1e-9 {
+			t.Errorf("step %d: expected posterior %+v, got %+v", step.Step, expected, got)
+		}
+	}
+}
+
+// TestSequentialUpdateNarrows - データが増えるにつれて区間が狭くなるテスト
+func TestSequentialUpdateNarrows(t *testing.T) {
+	totals := make([]int, 25)
+	for i := range totals {
+		totals[i] = 4 + i%5
+	}
+	setupTotalsTestData(totals...)
+
+	var result SequentialUpdateResponse
+	serveJSON(t, getSequentialUpdate, "GET", "/api/sequential?every=10", "", &result)
+
+	steps := []int{}
+	for _, s := range result.Steps {
+		steps = append(steps, s.Step)
+	}
+	if len(steps) != 4 || steps[1] != 10 || steps[2] != 20 || steps[3] != 25 {
+		t.Fatalf("expected steps 0, 10, 20, 25, got %v", steps)
+	}
+	for i := 2; i < len(result.Steps); i++ {
+		previous, current := result.Steps[i-1].ClassMean, result.Steps[i].ClassMean
+		if current.Upper-current.Lower >= previous.Upper-previous.Lower {
+			t.Errorf("step %d: interval width %.4f did not narrow from %.4f", result.Steps[i].Step,
+				current.Upper-current.Lower, previous.Upper-previous.Lower)
+		}
+	}
+	if mean := *result.Steps[3].ClassMean.Mean; math.Abs(mean-6) > 0.1 {
+		t.Errorf("expected class mean near 6, got %.4f", mean)
+	}
+}
+
+// TestSequentialUpdateStream - NDJSONによるストリーミングのテスト
+func TestSequentialUpdateStream(t *testing.T) {
+	setupTestData()
+
+	rr := serveRequest(t, getSequentialUpdate, "GET", "/api/sequential?stream=true", "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v, body %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	if contentType := rr.Header().Get("Content-Type"); contentType != "application/x-ndjson" {
+		t.Errorf("expected application/x-ndjson, got %s", contentType)
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(rr.Body.String()))
+	lines := 0
+	for scanner.Scan() {
+		var step SequentialStep
+		if err := json.Unmarshal(scanner.Bytes(), &step); err != nil {
+			t.Fatalf("line %d is not a step: %v", lines+1, err)
+		}
+		if step.Step != lines {
+			t.Errorf("expected step %d, got %d", lines, step.Step)
+		}
+		lines++
+	}
+	if lines != 4 {
+		t.Errorf("expected 4 lines, got %d", lines)
+	}
+}
+
+// TestSequentialUpdateInvalidParams - 無効なパラメータのテスト
+func TestSequentialUpdateInvalidParams(t *testing.T) {
+	setupTestData()
+
+	testCases := []struct {
+		name  string
+		query string
+	}{
+		{"invalid_kappa0", "kappa0=0"},
+		{"invalid_alpha", "alpha=-1"},
+		{"invalid_beta", "beta=x"},
+		{"invalid_level", "level=1"},
+		{"invalid_every", "every=0"},
+		{"invalid_stream", "stream=yes"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "/api/sequential?"+tc.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(getSequentialUpdate)
+			handler.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != http.StatusBadRequest {
+				t.Errorf("handler returned wrong status code: got %v want %v",
+					status, http.StatusBadRequest)
+			}
+		})
+	}
+}
+
+// TestSequentialUpdateEmptyData - データが空の場合のテスト
+func TestSequentialUpdateEmptyData(t *testing.T) {
+	grades = []Grade{}
+
+	req, err := http.NewRequest("GET", "/api/sequential", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(getSequentialUpdate)
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusInternalServerError {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusInternalServerError)
+	}
+}
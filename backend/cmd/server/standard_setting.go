@@ -0,0 +1,494 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Standard setting. Each method turns panel judgments into a raw cut score on the total; a
+// student passes when their total is at least the integer cut score, as in getBayesTheorem.
+// Request bodies carry the judgments and query parameters the options.
+
+// maxStandardSettingReplicates bounds the bootstrap replicates of the contrasting-groups method
+const maxStandardSettingReplicates = 100000
+
+// CutScoreSummary represents a recommended cut score, its uncertainty and the resulting pass rates
+type CutScoreSummary struct {
+	N                int                 `json:"n"`
+	Level            float64             `json:"level"`
+	RawCut           float64             `json:"raw_cut"`
+	RawCutInterval   *ConfidenceInterval `json:"raw_cut_interval"`
+	CutScore         int                 `json:"cut_score"`
+	CutScoreInterval *ConfidenceInterval `json:"cut_score_interval"`
+	PassRate         float64             `json:"pass_rate"`
+	PassRateInterval *ConfidenceInterval `json:"pass_rate_interval"`
+}
+
+// roundCut rounds a raw cut to the nearest attainable integer cut score, halves rounding up; the
+// small tolerance keeps sums of decimal ratings such as 10 × 0.55 from falling just below a half
+func roundCut(raw float64) int {
+	return int(math.Max(0, math.Min(numQuestions, math.Floor(raw+0.5+1e-9))))
+}
+
+// passRate returns the proportion of students whose total is at least cut
+func passRate(cut int) float64 {
+	passed := 0
+	for _, g := range grades {
+		if g.Total >= cut {
+			passed++
+		}
+	}
+	return float64(passed) / float64(len(grades))
+}
+
+// summarizeCut rounds the raw cut and its interval (nil when unavailable) and reports the pass
+// rates on the current data; a higher cut passes fewer students, so the pass rate interval runs
+// from the upper cut to the lower one
+func summarizeCut(raw float64, interval *ConfidenceInterval, level float64) CutScoreSummary {
+	summary := CutScoreSummary{
+		N:        len(grades),
+		Level:    level,
+		RawCut:   raw,
+		CutScore: roundCut(raw),
+	}
+	summary.PassRate = passRate(summary.CutScore)
+	if interval != nil {
+		lower, upper := roundCut(interval.Lower), roundCut(interval.Upper)
+		summary.RawCutInterval = interval
+		summary.CutScoreInterval = &ConfidenceInterval{Lower: float64(lower), Upper: float64(upper)}
+		summary.PassRateInterval = &ConfidenceInterval{Lower: passRate(upper), Upper: passRate(lower)}
+	}
+	return summary
+}
+
+// judgeInterval returns the mean of the judges' cuts and its t interval, nil for a single judge
+func judgeInterval(cuts []float64, level float64) (float64, *ConfidenceInterval) {
+	mean, sd := meanAndSD(cuts)
+	if len(cuts) < 2 {
+		return mean, nil
+	}
+	half := studentTQuantile(0.5+level/2, float64(len(cuts)-1)) * sd / math.Sqrt(float64(len(cuts)))
+	return mean, &ConfidenceInterval{Lower: mean - half, Upper: mean + half}
+}
+
+// parseLevel reads the level query parameter shared by the standard-setting endpoints
+func parseLevel(r *http.Request) (float64, error) {
+	level, err := floatParam(r, "level", 0.95)
+	if err != nil || level <= 0 || level >= 1 {
+		return 0, errors.New("Invalid 'level' parameter (must be between 0 and 1)")
+	}
+	return level, nil
+}
+
+// AngoffRatings is the JSON body of the Angoff method: for every judge, the probability that a
+// minimally competent student answers each item correctly
+type AngoffRatings struct {
+	Ratings [][]float64 `json:"ratings"`
+}
+
+// AngoffItem represents the judges' ratings of one item next to its observed p-value
+type AngoffItem struct {
+	Question   string  `json:"question"`
+	MeanRating float64 `json:"mean_rating"`
+	SD         float64 `json:"sd"`
+	PValue     float64 `json:"p_value"`
+}
+
+// AngoffResponse represents the cut score from Angoff ratings
+type AngoffResponse struct {
+	Method    string       `json:"method"`
+	Judges    int          `json:"judges"`
+	JudgeCuts []float64    `json:"judge_cuts"`
+	Items     []AngoffItem `json:"items"`
+	CutScoreSummary
+}
+
+// Handler: Post Angoff standard setting
+// Takes the JSON body {"ratings": [[p_1..p_10], ...]} with one row per judge; each judge's cut is
+// the expected total of a minimally competent student and the recommended cut their mean
+func postAngoff(w http.ResponseWriter, r *http.Request) {
+	level, err := parseLevel(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, ErrorResponse{Error: err.Error(), Parameter: "level"})
+		return
+	}
+	var body AngoffRatings
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&body); err != nil {
+		writeJSONError(w, http.StatusBadRequest, ErrorResponse{Error: "Invalid Angoff ratings JSON: " + err.Error()})
+		return
+	}
+	if len(body.Ratings) == 0 {
+		writeJSONError(w, http.StatusBadRequest, ErrorResponse{Error: "ratings must have at least one judge"})
+		return
+	}
+	for judge, row := range body.Ratings {
+		if len(row) != numQuestions {
+			writeJSONError(w, http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("judge %d must rate all %d items", judge+1, numQuestions)})
+			return
+		}
+		for j, p := range row {
+			if p < 0 || p > 1 {
+				writeJSONError(w, http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("judge %d rating of Q%d must be between 0 and 1", judge+1, j+1)})
+				return
+			}
+		}
+	}
+
+	if len(grades) == 0 {
+		writeJSONError(w, http.StatusInternalServerError, ErrorResponse{Error: "No data available"})
+		return
+	}
+
+	response := AngoffResponse{Method: "angoff", Judges: len(body.Ratings)}
+	for _, row := range body.Ratings {
+		cut := 0.0
+		for _, p := range row {
+			cut += p
+		}
+		response.JudgeCuts = append(response.JudgeCuts, cut)
+	}
+	for j, label := range questionLabels() {
+		column := make([]float64, len(body.Ratings))
+		for judge, row := range body.Ratings {
+			column[judge] = row[j]
+		}
+		item := AngoffItem{Question: label}
+		item.MeanRating, item.SD = meanAndSD(column)
+		for _, x := range itemColumn(j + 1) {
+			item.PValue += x
+		}
+		item.PValue /= float64(len(grades))
+		response.Items = append(response.Items, item)
+	}
+	raw, interval := judgeInterval(response.JudgeCuts, level)
+	response.CutScoreSummary = summarizeCut(raw, interval, level)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// BookmarkPage represents one page of the ordered item booklet
+type BookmarkPage struct {
+	Page       int     `json:"page"`
+	Question   string  `json:"question"`
+	Difficulty float64 `json:"difficulty"`
+	Location   float64 `json:"location"`
+}
+
+// BookmarkResponse represents the cut score from bookmark placements
+type BookmarkResponse struct {
+	Method        string              `json:"method"`
+	ResponseProb  float64             `json:"response_probability"`
+	Booklet       []BookmarkPage      `json:"booklet"`
+	Bookmarks     []int               `json:"bookmarks"`
+	ThetaCut      float64             `json:"theta_cut"`
+	ThetaInterval *ConfidenceInterval `json:"theta_interval"`
+	CutScoreSummary
+}
+
+// expectedTotal is the test characteristic curve: the expected total at ability theta, with
+// items that could not be calibrated contributing their observed p-value
+func expectedTotal(theta float64, fit raschJML, pValues []float64) float64 {
+	total := 0.0
+	for j := range pValues {
+		if fit.itemEstimable[j] {
+			total += sigmoid(theta - fit.difficulty[j])
+		} else {
+			total += pValues[j]
+		}
+	}
+	return total
+}
+
+// Handler: Get Bookmark standard setting
+// Orders the Rasch-calibrated items by difficulty into a booklet and turns the judges'
+// bookmarks (the page of the last item a minimally competent student masters with probability
+// rp) into an ability cut, mapped to a raw cut through the test characteristic curve
+func getBookmark(w http.ResponseWriter, r *http.Request) {
+	level, err := parseLevel(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, ErrorResponse{Error: err.Error(), Parameter: "level"})
+		return
+	}
+	rp, err := floatParam(r, "rp", 0.67)
+	if err != nil || rp <= 0 || rp >= 1 {
+		writeJSONError(w, http.StatusBadRequest, ErrorResponse{Error: "Invalid 'rp' parameter (must be between 0 and 1)", Parameter: "rp"})
+		return
+	}
+	var bookmarks []int
+	if s := r.URL.Query().Get("bookmarks"); s != "" {
+		for _, part := range strings.Split(s, ",") {
+			page, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil {
+				writeJSONError(w, http.StatusBadRequest, ErrorResponse{Error: "Invalid 'bookmarks' parameter (must be page numbers such as 6,7,6)", Parameter: "bookmarks"})
+				return
+			}
+			bookmarks = append(bookmarks, page)
+		}
+	}
+
+	if len(grades) == 0 {
+		writeJSONError(w, http.StatusInternalServerError, ErrorResponse{Error: "No data available"})
+		return
+	}
+
+	responses, err := binaryResponseMatrix()
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+	fit := fitRaschJML(responses)
+	pValues := make([]float64, numQuestions)
+	response := BookmarkResponse{Method: "bookmark", ResponseProb: rp, Booklet: []BookmarkPage{}, Bookmarks: bookmarks}
+	labels := questionLabels()
+	for j := range pValues {
+		for _, row := range responses {
+			pValues[j] += float64(row[j])
+		}
+		pValues[j] /= float64(len(responses))
+		if fit.itemEstimable[j] {
+			response.Booklet = append(response.Booklet, BookmarkPage{
+				Question:   labels[j],
+				Difficulty: fit.difficulty[j],
+				Location:   fit.difficulty[j] + math.Log(rp/(1-rp)),
+			})
+		}
+	}
+	if len(response.Booklet) < 2 {
+		writeJSONError(w, http.StatusInternalServerError, ErrorResponse{Error: "Too few items can be calibrated to build a booklet"})
+		return
+	}
+	sort.SliceStable(response.Booklet, func(a, b int) bool {
+		return response.Booklet[a].Difficulty < response.Booklet[b].Difficulty
+	})
+	for k := range response.Booklet {
+		response.Booklet[k].Page = k + 1
+	}
+	if len(bookmarks) == 0 {
+		writeJSONError(w, http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("Missing 'bookmarks' parameter (pages 1-%d, one per judge)", len(response.Booklet)), Parameter: "bookmarks"})
+		return
+	}
+	cuts := make([]float64, len(bookmarks))
+	for i, page := range bookmarks {
+		if page < 1 || page > len(response.Booklet) {
+			writeJSONError(w, http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("Invalid 'bookmarks' parameter (pages must be between 1 and %d)", len(response.Booklet)), Parameter: "bookmarks"})
+			return
+		}
+		cuts[i] = response.Booklet[page-1].Location
+	}
+
+	var interval *ConfidenceInterval
+	response.ThetaCut, response.ThetaInterval = judgeInterval(cuts, level)
+	if response.ThetaInterval != nil {
+		interval = &ConfidenceInterval{
+			Lower: expectedTotal(response.ThetaInterval.Lower, fit, pValues),
+			Upper: expectedTotal(response.ThetaInterval.Upper, fit, pValues),
+		}
+	}
+	response.CutScoreSummary = summarizeCut(expectedTotal(response.ThetaCut, fit, pValues), interval, level)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// ContrastingGroups is the JSON body of the contrasting-groups method: the students judged to be
+// masters and non-masters independently of their scores
+type ContrastingGroups struct {
+	Masters    []int `json:"masters"`
+	NonMasters []int `json:"non_masters"`
+}
+
+// ContrastingGroupsCut represents the classification errors of one candidate cut score
+type ContrastingGroupsCut struct {
+	CutScore          int     `json:"cut_score"`
+	FalsePassRate     float64 `json:"false_pass_rate"`
+	FalseFailRate     float64 `json:"false_fail_rate"`
+	Misclassification float64 `json:"misclassification"`
+}
+
+// ContrastingGroupsResponse represents the cut score from contrasting groups
+type ContrastingGroupsResponse struct {
+	Method     string                 `json:"method"`
+	Masters    int                    `json:"masters"`
+	NonMasters int                    `json:"non_masters"`
+	Replicates int                    `json:"replicates"`
+	Candidates []ContrastingGroupsCut `json:"candidates"`
+	CutScoreSummary
+}
+
+// totalCounts returns how many of the totals fall on each score 0..10, with totals above the
+// maximum counted in a final bucket (see clampTotal)
+func totalCounts(totals []int) []int {
+	counts := make([]int, numQuestions+2)
+	for _, total := range totals {
+		counts[clampTotal(total)]++
+	}
+	return counts
+}
+
+// clampTotal maps a total to its bucket in totalCounts; negative totals share the bucket of 0
+func clampTotal(total int) int {
+	if total < 0 {
+		return 0
+	}
+	if total > numQuestions+1 {
+		return numQuestions + 1
+	}
+	return total
+}
+
+// bestContrastingCut returns the cut score that minimizes the sum of the false pass rate among
+// non-masters and the false fail rate among masters, taking the lower median of tied cuts.
+// The groups are given as totalCounts, so each call costs O(items) whatever the group sizes.
+func bestContrastingCut(masters, nonMasters []int) (int, []ContrastingGroupsCut) {
+	nMasters, nNonMasters := 0, 0
+	for k := range masters {
+		nMasters += masters[k]
+		nNonMasters += nonMasters[k]
+	}
+	candidates := make([]ContrastingGroupsCut, numQuestions+1)
+	best := math.Inf(1)
+	// falseFail counts masters below the cut and falsePass non-masters at or above it
+	falseFail, falsePass := 0, nNonMasters
+	for c := range candidates {
+		if c > 0 {
+			falseFail += masters[c-1]
+			falsePass -= nonMasters[c-1]
+		}
+		candidates[c] = ContrastingGroupsCut{
+			CutScore:      c,
+			FalsePassRate: float64(falsePass) / float64(nNonMasters),
+			FalseFailRate: float64(falseFail) / float64(nMasters),
+		}
+		candidates[c].Misclassification = candidates[c].FalsePassRate + candidates[c].FalseFailRate
+		best = math.Min(best, candidates[c].Misclassification)
+	}
+	var tied []int
+	for c, candidate := range candidates {
+		if candidate.Misclassification <= best+1e-12 {
+			tied = append(tied, c)
+		}
+	}
+	return tied[(len(tied)-1)/2], candidates
+}
+
+// Handler: Post contrasting-groups standard setting
+// Takes the JSON body {"masters": [ids], "non_masters": [ids]} and recommends the cut score that
+// best separates the two groups' totals, with a stratified bootstrap interval
+func postContrastingGroups(w http.ResponseWriter, r *http.Request) {
+	level, err := parseLevel(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, ErrorResponse{Error: err.Error(), Parameter: "level"})
+		return
+	}
+	replicates, err := intParam(r, "replicates", 2000)
+	if err != nil || replicates < 100 || replicates > maxStandardSettingReplicates {
+		writeJSONError(w, http.StatusBadRequest, ErrorResponse{Error: "Invalid 'replicates' parameter (must be between 100 and 100000)", Parameter: "replicates"})
+		return
+	}
+	seed, err := intParam(r, "seed", 1)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, ErrorResponse{Error: "Invalid 'seed' parameter", Parameter: "seed"})
+		return
+	}
+	var body ContrastingGroups
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&body); err != nil {
+		writeJSONError(w, http.StatusBadRequest, ErrorResponse{Error: "Invalid contrasting groups JSON: " + err.Error()})
+		return
+	}
+	if len(body.Masters) == 0 || len(body.NonMasters) == 0 {
+		writeJSONError(w, http.StatusBadRequest, ErrorResponse{Error: "masters and non_masters must both list at least one student"})
+		return
+	}
+
+	if len(grades) == 0 {
+		writeJSONError(w, http.StatusInternalServerError, ErrorResponse{Error: "No data available"})
+		return
+	}
+
+	totals := make(map[int]int, len(grades))
+	for _, g := range grades {
+		totals[g.StudentID] = g.Total
+	}
+	assigned := make(map[int]bool)
+	groupTotals := func(ids []int) ([]int, error) {
+		values := make([]int, len(ids))
+		for i, id := range ids {
+			total, ok := totals[id]
+			if !ok {
+				return nil, fmt.Errorf("unknown student %d", id)
+			}
+			if assigned[id] {
+				return nil, fmt.Errorf("student %d is listed more than once", id)
+			}
+			assigned[id] = true
+			values[i] = total
+		}
+		return values, nil
+	}
+	masters, err := groupTotals(body.Masters)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, ErrorResponse{Error: "Invalid contrasting groups: " + err.Error()})
+		return
+	}
+	nonMasters, err := groupTotals(body.NonMasters)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, ErrorResponse{Error: "Invalid contrasting groups: " + err.Error()})
+		return
+	}
+
+	if replicates > maxBootstrapWork/(len(masters)+len(nonMasters)) {
+		writeJSONError(w, http.StatusBadRequest, ErrorResponse{Error: "Too much work: replicates × students must not exceed 50000000", Parameter: "replicates"})
+		return
+	}
+
+	cut, candidates := bestContrastingCut(totalCounts(masters), totalCounts(nonMasters))
+
+	// Stratified bootstrap: masters and non-masters are resampled separately, straight into
+	// per-total counts
+	rng := rand.New(rand.NewSource(int64(seed)))
+	cuts := make([]float64, replicates)
+	sampleMasters := make([]int, numQuestions+2)
+	sampleNonMasters := make([]int, numQuestions+2)
+	for b := range cuts {
+		for k := range sampleMasters {
+			sampleMasters[k], sampleNonMasters[k] = 0, 0
+		}
+		for range masters {
+			sampleMasters[clampTotal(masters[rng.Intn(len(masters))])]++
+		}
+		for range nonMasters {
+			sampleNonMasters[clampTotal(nonMasters[rng.Intn(len(nonMasters))])]++
+		}
+		c, _ := bestContrastingCut(sampleMasters, sampleNonMasters)
+		cuts[b] = float64(c)
+	}
+	sort.Float64s(cuts)
+	interval := &ConfidenceInterval{
+		Lower: quantile(cuts, (1-level)/2),
+		Upper: quantile(cuts, (1+level)/2),
+	}
+
+	response := ContrastingGroupsResponse{
+		Method:          "contrasting-groups",
+		Masters:         len(masters),
+		NonMasters:      len(nonMasters),
+		Replicates:      replicates,
+		Candidates:      candidates,
+		CutScoreSummary: summarizeCut(float64(cut), interval, level),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"testing"
+)
+
+// angoffRatingsBody - 判定者ごとに全項目へ同じ評定値を付けたリクエストボディを作る
+func angoffRatingsBody(values ...float64) string {
+	var ratings [][]float64
+	for _, v := range values {
+		row := make([]float64, numQuestions)
+		for j := range row {
+			row[j] = v
+		}
+		ratings = append(ratings, row)
+	}
+	body, _ := json.Marshal(AngoffRatings{Ratings: ratings})
+	return string(body)
+}
+
+// TestAngoff - Angoff法の合格点・信頼区間・合格率のテスト
+func TestAngoff(t *testing.T) {
+	setupTestData()
+
+	var result AngoffResponse
+	serveJSON(t, postAngoff, "POST", "/api/standard-setting/angoff",
+		angoffRatingsBody(0.6, 0.7, 0.8), &result)
+
+	if result.Judges != 3 || len(result.JudgeCuts) != 3 || math.Abs(result.JudgeCuts[0]-6) > 1e-9 {
+		t.Fatalf("expected judge cuts 6, 7, 8, got %v", result.JudgeCuts)
+	}
+	if math.Abs(result.RawCut-7) > 1e-9 || result.CutScore != 7 {
+		t.Errorf("expected raw cut 7 and cut score 7, got %v and %d", result.RawCut, result.CutScore)
+	}
+	// Mean 7, SD 1 over 3 judges: 7 ± t(0.975, 2)·1/√3 = 7 ± 2.484
+	if result.RawCutInterval == nil || math.Abs(result.RawCutInterval.Lower-4.516) > 1e-3 || math.Abs(result.RawCutInterval.Upper-9.484) > 1e-3 {
+		t.Fatalf("expected raw cut interval (4.516, 9.484), got %+v", result.RawCutInterval)
+	}
+	if result.CutScoreInterval.Lower != 5 || result.CutScoreInterval.Upper != 9 {
+		t.Errorf("expected cut score interval (5, 9), got %+v", result.CutScoreInterval)
+	}
+	// Totals are 10, 7 and 0
+	if math.Abs(result.PassRate-2.0/3) > 1e-9 {
+		t.Errorf("expected pass rate 2/3, got %v", result.PassRate)
+	}
+	if math.Abs(result.PassRateInterval.Lower-1.0/3) > 1e-9 || math.Abs(result.PassRateInterval.Upper-2.0/3) > 1e-9 {
+		t.Errorf("expected pass rate interval (1/3, 2/3), got %+v", result.PassRateInterval)
+	}
+	if math.Abs(result.Items[0].MeanRating-0.7) > 1e-9 || math.Abs(result.Items[0].PValue-2.0/3) > 1e-9 {
+		t.Errorf("expected Q1 mean rating 0.7 and p-value 2/3, got %+v", result.Items[0])
+	}
+}
+
+// TestAngoffSingleJudge - 判定者が1人のときは区間が計算されないかのテスト
+func TestAngoffSingleJudge(t *testing.T) {
+	setupTestData()
+
+	var result AngoffResponse
+	serveJSON(t, postAngoff, "POST", "/api/standard-setting/angoff",
+		angoffRatingsBody(0.55), &result)
+
+	if result.CutScore != 6 || result.RawCutInterval != nil || result.PassRateInterval != nil {
+		t.Errorf("expected cut score 6 without intervals, got %d %+v %+v", result.CutScore, result.RawCutInterval, result.PassRateInterval)
+	}
+}
+
+// TestAngoffInvalidRatings - 不正な評定値のテスト
+func TestAngoffInvalidRatings(t *testing.T) {
+	setupTestData()
+
+	cases := map[string]string{
+		"out of range":  angoffRatingsBody(0.5, 1.5),
+		"short row":     `{"ratings": [[0.5, 0.5]]}`,
+		"no judges":     `{"ratings": []}`,
+		"unknown field": `{"ratings": [[0.5,0.5,0.5,0.5,0.5,0.5,0.5,0.5,0.5,0.5]], "judges": 1}`,
+	}
+	for name, body := range cases {
+		if rr := serveRequest(t, postAngoff, "POST", "/api/standard-setting/angoff", body); rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", name, rr.Code)
+		}
+	}
+	if rr := serveRequest(t, postAngoff, "POST", "/api/standard-setting/angoff?level=2", angoffRatingsBody(0.5)); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for level=2, got %d", rr.Code)
+	}
+}
+
+// TestBookmark - Bookmark法の項目冊子と合格点のテスト
+func TestBookmark(t *testing.T) {
+	setupRaschTestData(500, catTestDifficulties, 7)
+
+	var result BookmarkResponse
+	serveJSON(t, getBookmark, "GET", "/api/standard-setting/bookmark?bookmarks=5,6,7", "", &result)
+
+	if len(result.Booklet) != numQuestions {
+		t.Fatalf("expected %d pages, got %d", numQuestions, len(result.Booklet))
+	}
+	offset := math.Log(0.67 / 0.33)
+	for k, page := range result.Booklet {
+		if page.Page != k+1 || math.Abs(page.Location-page.Difficulty-offset) > 1e-9 {
+			t.Errorf("page %d: unexpected entry %+v", k+1, page)
+		}
+		if k > 0 && page.Difficulty < result.Booklet[k-1].Difficulty {
+			t.Errorf("booklet not ordered by difficulty at page %d", k+1)
+		}
+	}
+	wantTheta := (result.Booklet[4].Location + result.Booklet[5].Location + result.Booklet[6].Location) / 3
+	if math.Abs(result.ThetaCut-wantTheta) > 1e-9 {
+		t.Errorf("expected θ cut %v, got %v", wantTheta, result.ThetaCut)
+	}
+	if result.ThetaInterval == nil || result.RawCutInterval == nil ||
+		result.RawCutInterval.Lower > result.RawCut || result.RawCutInterval.Upper < result.RawCut {
+		t.Fatalf("expected the raw cut inside its interval, got %v %+v", result.RawCut, result.RawCutInterval)
+	}
+	if math.Abs(result.PassRate-passRate(result.CutScore)) > 1e-12 {
+		t.Errorf("pass rate %v does not match the cut score %d", result.PassRate, result.CutScore)
+	}
+
+	// A later bookmark gives a higher cut and a lower pass rate
+	var strict BookmarkResponse
+	serveJSON(t, getBookmark, "GET", "/api/standard-setting/bookmark?bookmarks=9", "", &strict)
+	if strict.RawCut <= result.RawCut || strict.PassRate > result.PassRate {
+		t.Errorf("expected bookmark 9 to raise the cut: %v vs %v, pass rate %v vs %v",
+			strict.RawCut, result.RawCut, strict.PassRate, result.PassRate)
+	}
+	if strict.ThetaInterval != nil {
+		t.Error("expected no interval for a single bookmark")
+	}
+}
+
+// TestBookmarkInvalidParams - 不正なパラメータのテスト
+func TestBookmarkInvalidParams(t *testing.T) {
+	setupRaschTestData(200, catTestDifficulties, 7)
+
+	for _, query := range []string{"", "bookmarks=0", "bookmarks=11", "bookmarks=a", "bookmarks=5&rp=1"} {
+		rr := serveRequest(t, getBookmark, "GET", "/api/standard-setting/bookmark?"+query, "")
+		var body ErrorResponse
+		if rr.Code != http.StatusBadRequest || json.Unmarshal(rr.Body.Bytes(), &body) != nil || body.Error == "" {
+			t.Errorf("%q: expected status 400 with a JSON error, got %d: %s", query, rr.Code, rr.Body.String())
+		}
+	}
+}
+
+// TestBookmarkNonBinary - 0/1以外の得点がエラーになるかのテスト
+func TestBookmarkNonBinary(t *testing.T) {
+	setupRaschTestData(200, catTestDifficulties, 7)
+	grades[0].Q4 = 2
+
+	rr := serveRequest(t, getBookmark, "GET", "/api/standard-setting/bookmark?bookmarks=3", "")
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("expected status 500 for a non-binary score, got %d", rr.Code)
+	}
+}
+
+// TestContrastingGroups - 対照群法の合格点とブートストラップ区間のテスト
+func TestContrastingGroups(t *testing.T) {
+	// Students 1-6 are non-masters (totals 2-5 with one 7), students 7-12 masters (totals 6-9 with one 4)
+	setupTotalsTestData(2, 3, 4, 5, 5, 7, 6, 7, 8, 9, 9, 4)
+
+	var result ContrastingGroupsResponse
+	serveJSON(t, postContrastingGroups, "POST",
+		"/api/standard-setting/contrasting-groups?replicates=500&seed=3",
+		`{"masters": [7, 8, 9, 10, 11, 12], "non_masters": [1, 2, 3, 4, 5, 6]}`, &result)
+
+	if result.Masters != 6 || result.NonMasters != 6 || len(result.Candidates) != numQuestions+1 {
+		t.Fatalf("unexpected group sizes or candidates: %d, %d, %d", result.Masters, result.NonMasters, len(result.Candidates))
+	}
+	// Cut 6: one non-master passes (7) and one master fails (4)
+	if result.CutScore != 6 {
+		t.Errorf("expected cut score 6, got %d", result.CutScore)
+	}
+	c := result.Candidates[6]
+	if math.Abs(c.FalsePassRate-1.0/6) > 1e-9 || math.Abs(c.FalseFailRate-1.0/6) > 1e-9 {
+		t.Errorf("expected false pass and fail rates of 1/6 at cut 6, got %+v", c)
+	}
+	if result.RawCutInterval == nil || result.RawCutInterval.Lower > 6 || result.RawCutInterval.Upper < 6 {
+		t.Errorf("expected the bootstrap interval to contain 6, got %+v", result.RawCutInterval)
+	}
+	if math.Abs(result.PassRate-0.5) > 1e-9 {
+		t.Errorf("expected pass rate 0.5, got %v", result.PassRate)
+	}
+}
+
+// TestContrastingGroupsInvalid - 不正な群指定のテスト
+func TestContrastingGroupsInvalid(t *testing.T) {
+	setupTotalsTestData(2, 3, 8, 9)
+
+	cases := map[string]string{
+		"unknown student": `{"masters": [3, 99], "non_masters": [1]}`,
+		"duplicate":       `{"masters": [3, 4], "non_masters": [1, 3]}`,
+		"empty group":     `{"masters": [3, 4], "non_masters": []}`,
+	}
+	for name, body := range cases {
+		rr := serveRequest(t, postContrastingGroups, "POST", "/api/standard-setting/contrasting-groups", body)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", name, rr.Code)
+		}
+	}
+	rr := serveRequest(t, postContrastingGroups, "POST", "/api/standard-setting/contrasting-groups?replicates=10",
+		`{"masters": [3, 4], "non_masters": [1, 2]}`)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for replicates=10, got %d", rr.Code)
+	}
+
+	// 100000 replicates of 600 students exceed the bootstrap work cap
+	totals := make([]int, 600)
+	masters, nonMasters := make([]int, 300), make([]int, 300)
+	for i := range masters {
+		masters[i], nonMasters[i] = i+301, i+1
+	}
+	setupTotalsTestData(totals...)
+	body, _ := json.Marshal(ContrastingGroups{Masters: masters, NonMasters: nonMasters})
+	rr = serveRequest(t, postContrastingGroups, "POST", "/api/standard-setting/contrasting-groups?replicates=100000", string(body))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 above the work cap, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// numQuestions is the number of items (Q1..Q10) in every Grade
+const numQuestions = 10
+
+// parseQuestion parses an item parameter of the form "q1".."q10" and returns its number
+func parseQuestion(s string) (int, error) {
+	if len(s) < 2 || s[0] != 'q' {
+		return 0, fmt.Errorf("invalid item %q (must be q1-q10)", s)
+	}
+	n, err := strconv.Atoi(s[1:])
+	if err != nil || n < 1 || n > numQuestions {
+		return 0, fmt.Errorf("invalid item %q (must be q1-q10)", s)
+	}
+	return n, nil
+}
+
+// parseQuestionList parses a comma separated list such as "q1,q3,q5" into sorted unique
+// question numbers
+func parseQuestionList(s string) ([]int, error) {
+	seen := make(map[int]bool)
+	var questions []int
+	for _, part := range strings.Split(s, ",") {
+		q, err := parseQuestion(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		if !seen[q] {
+			seen[q] = true
+			questions = append(questions, q)
+		}
+	}
+	sort.Ints(questions)
+	return questions, nil
+}
+
+// allQuestions returns 1..numQuestions
+func allQuestions() []int {
+	questions := make([]int, numQuestions)
+	for i := range questions {
+		questions[i] = i + 1
+	}
+	return questions
+}
+
+// floatParam reads an optional float query parameter, returning def when it is absent
+func floatParam(r *http.Request, name string, def float64) (float64, error) {
+	s := r.URL.Query().Get(name)
+	if s == "" {
+		return def, nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, fmt.Errorf("invalid '%s' parameter", name)
+	}
+	return v, nil
+}
+
+// intParam reads an optional integer query parameter, returning def when it is absent
+func intParam(r *http.Request, name string, def int) (int, error) {
+	s := r.URL.Query().Get(name)
+	if s == "" {
+		return def, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid '%s' parameter", name)
+	}
+	return v, nil
+}
+
+// questionLabels returns the display labels "Q1".."Q10"
+func questionLabels() []string {
+	labels := make([]string, numQuestions)
+	for i := range labels {
+		labels[i] = fmt.Sprintf("Q%d", i+1)
+	}
+	return labels
+}
+
+// itemColumn returns the responses of every student to question q as float64
+func itemColumn(q int) []float64 {
+	column := make([]float64, len(grades))
+	for i, g := range grades {
+		column[i] = float64(getQuestionValue(g, q))
+	}
+	return column
+}
+
+// totalColumn returns the Total score of every student as float64
+func totalColumn() []float64 {
+	column := make([]float64, len(grades))
+	for i, g := range grades {
+		column[i] = float64(g.Total)
+	}
+	return column
+}
+
+// responseMatrix returns the 0/1 responses of every student as responses[student][item]
+func responseMatrix() [][]int {
+	responses := make([][]int, len(grades))
+	for i, g := range grades {
+		responses[i] = make([]int, numQuestions)
+		for j := range responses[i] {
+			responses[i][j] = getQuestionValue(g, j+1)
+		}
+	}
+	return responses
+}
+
+// binaryResponseMatrix returns responseMatrix, or an error naming the first score that is not
+// 0 or 1, for models that are only defined for dichotomous items
+func binaryResponseMatrix() ([][]int, error) {
+	responses := responseMatrix()
+	for i, row := range responses {
+		for j, x := range row {
+			if x != 0 && x != 1 {
+				return nil, fmt.Errorf("student %d has non-binary Q%d", grades[i].StudentID, j+1)
+			}
+		}
+	}
+	return responses, nil
+}
+
+// pearson calculates the Pearson correlation of two equally long samples.
+// Unlike calculatePearsonCorrelation it returns 0 when either sample has no variance,
+// because derived statistics (partial correlations, loadings) treat that as "no information".
+func pearson(x, y []float64) float64 {
+	n := len(x)
+	if n == 0 || n != len(y) {
+		return 0.0
+	}
+
+	var meanX, meanY float64
+	for i := 0; i < n; i++ {
+		meanX += x[i]
+		meanY += y[i]
+	}
+	meanX /= float64(n)
+	meanY /= float64(n)
+
+	var sxy, sxx, syy float64
+	for i := 0; i < n; i++ {
+		dx := x[i] - meanX
+		dy := y[i] - meanY
+		sxy += dx * dy
+		sxx += dx * dx
+		syy += dy * dy
+	}
+	if sxx == 0 || syy == 0 {
+		return 0.0
+	}
+	return sxy / math.Sqrt(sxx*syy)
+}
+
+// normalCDF returns P(Z ≤ z) for a standard normal Z
+func normalCDF(z float64) float64 {
+	return 0.5 * math.Erfc(-z/math.Sqrt2)
+}
+
+// chiSquarePValue returns the upper tail probability P(X ≥ x) of a chi-square distribution
+func chiSquarePValue(x float64, df int) float64 {
+	if x <= 0 {
+		return 1.0
+	}
+	return regularizedGammaQ(float64(df)/2, x/2)
+}
+
+// regularizedGammaQ computes Q(a, x) = Γ(a, x) / Γ(a) using the series expansion for
+// small x and a continued fraction otherwise (Numerical Recipes, gammp/gammq)
+func regularizedGammaQ(a, x float64) float64 {
+	if x <= 0 {
+		return 1.0
+	}
+	lgammaA, _ := math.Lgamma(a)
+
+	if x < a+1 {
+		// Series representation of P(a, x)
+		sum := 1.0 / a
+		term := sum
+		for n := 1; n < 1000; n++ {
+			term *= x / (a + float64(n))
+			sum += term
+			if math.Abs(term) < math.Abs(sum)*1e-15 {
+				break
+			}
+		}
+		return 1.0 - sum*math.Exp(-x+a*math.Log(x)-lgammaA)
+	}
+
+	// Continued fraction representation of Q(a, x) (modified Lentz)
+	const tiny = 1e-300
+	b := x + 1 - a
+	c := 1.0 / tiny
+	d := 1.0 / b
+	h := d
+	for i := 1; i < 1000; i++ {
+		an := -float64(i) * (float64(i) - a)
+		b += 2
+		d = an*d + b
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = b + an/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1.0 / d
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < 1e-15 {
+			break
+		}
+	}
+	return math.Exp(-x+a*math.Log(x)-lgammaA) * h
+}
+
+// finiteOrNil returns a pointer to v, or nil when v is NaN or infinite.
+// encoding/json cannot encode non-finite numbers, so undefined statistics are sent as null.
+func finiteOrNil(v float64) *float64 {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil
+	}
+	return &v
+}
+
+// normalQuantile returns z such that P(Z ≤ z) = p for a standard normal Z
+// (Acklam's rational approximation refined with one Halley step)
+func normalQuantile(p float64) float64 {
+	if p <= 0 {
+		return math.Inf(-1)
+	}
+	if p >= 1 {
+		return math.Inf(1)
+	}
+
+	a := []float64{-3.969683028665376e+01, 2.209460984245205e+02, -2.759285104469687e+02,
+		1.383577518672690e+02, -3.066479806614716e+01, 2.506628277459239e+00}
+	b := []float64{-5.447609879822406e+01, 1.615858368580409e+02, -1.556989798598866e+02,
+		6.680131188771972e+01, -1.328068155288572e+01}
+	c := []float64{-7.784894002430293e-03, -3.223964580411365e-01, -2.400758277161838e+00,
+		-2.549732539343734e+00, 4.374664141464968e+00, 2.938163982698783e+00}
+	d := []float64{7.784695709041462e-03, 3.224671290700398e-01, 2.445134137142996e+00,
+		3.754408661907416e+00}
+
+	const pLow = 0.02425
+	var x float64
+	switch {
+	case p < pLow:
+		q := math.Sqrt(-2 * math.Log(p))
+		x = (((((c[0]*q+c[1])*q+c[2])*q+c[3])*q+c[4])*q + c[5]) /
+			((((d[0]*q+d[1])*q+d[2])*q+d[3])*q + 1)
+	case p <= 1-pLow:
+		q := p - 0.5
+		r := q * q
+		x = (((((a[0]*r+a[1])*r+a[2])*r+a[3])*r+a[4])*r + a[5]) * q /
+			(((((b[0]*r+b[1])*r+b[2])*r+b[3])*r+b[4])*r + 1)
+	default:
+		q := math.Sqrt(-2 * math.Log(1-p))
+		x = -(((((c[0]*q+c[1])*q+c[2])*q+c[3])*q+c[4])*q + c[5]) /
+			((((d[0]*q+d[1])*q+d[2])*q+d[3])*q + 1)
+	}
+
+	// Halley refinement
+	e := normalCDF(x) - p
+	u := e * math.Sqrt(2*math.Pi) * math.Exp(x*x/2)
+	return x - u/(1+x*u/2)
+}
+
+// logChoose returns log(n choose k)
+func logChoose(n, k int) float64 {
+	if k < 0 || k > n {
+		return math.Inf(-1)
+	}
+	a, _ := math.Lgamma(float64(n + 1))
+	b, _ := math.Lgamma(float64(k + 1))
+	c, _ := math.Lgamma(float64(n - k + 1))
+	return a - b - c
+}
+
+// quantile returns the p-quantile of an ascending sorted sample using linear interpolation
+func quantile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return math.NaN()
+	}
+	if len(sorted) == 1 {
+		return sorted[0]
+	}
+	pos := p * float64(len(sorted)-1)
+	lower := int(math.Floor(pos))
+	if lower >= len(sorted)-1 {
+		return sorted[len(sorted)-1]
+	}
+	frac := pos - float64(lower)
+	return sorted[lower] + frac*(sorted[lower+1]-sorted[lower])
+}
+
+// meanAndSD returns the sample mean and sample standard deviation (n-1 denominator)
+func meanAndSD(x []float64) (float64, float64) {
+	if len(x) == 0 {
+		return math.NaN(), math.NaN()
+	}
+	var sum float64
+	for _, v := range x {
+		sum += v
+	}
+	mean := sum / float64(len(x))
+	if len(x) == 1 {
+		return mean, 0.0
+	}
+	var ss float64
+	for _, v := range x {
+		ss += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(ss / float64(len(x)-1))
+}
+
+// sigmoid returns the logistic function 1 / (1 + e^-x)
+func sigmoid(x float64) float64 {
+	if x >= 0 {
+		return 1 / (1 + math.Exp(-x))
+	}
+	e := math.Exp(x)
+	return e / (1 + e)
+}
+
+// logSigmoid returns log(sigmoid(x)) without overflow
+func logSigmoid(x float64) float64 {
+	if x >= 0 {
+		return -math.Log1p(math.Exp(-x))
+	}
+	return x - math.Log1p(math.Exp(x))
+}
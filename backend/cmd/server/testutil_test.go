@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// serveRequest - ハンドラーにリクエストを送りレスポンスを返す
+func serveRequest(t *testing.T, handler http.HandlerFunc, method, url, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+// decodeJSON - ステータスコードを確認してレスポンスボディをvにデコードする
+func decodeJSON(t *testing.T, rr *httptest.ResponseRecorder, status int, v interface{}) {
+	t.Helper()
+	if rr.Code != status {
+		t.Fatalf("handler returned wrong status code: got %v want %v, body %s", rr.Code, status, rr.Body.String())
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), v); err != nil {
+		t.Fatalf("failed to decode response body: %v", err)
+	}
+}
+
+// serveJSON - ハンドラーにリクエストを送り、200を確認してレスポンスをvにデコードする
+func serveJSON(t *testing.T, handler http.HandlerFunc, method, url, body string, v interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	rr := serveRequest(t, handler, method, url, body)
+	decodeJSON(t, rr, http.StatusOK, v)
+	return rr
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Bayesian models for the distribution of Total scores out of numQuestions items.
+//
+//	binomial:      Total_i ~ Binomial(10, θ),   θ ~ Beta(1, 1)
+//	beta-binomial: Total_i ~ Binomial(10, p_i), p_i ~ Beta(μκ, (1-μ)κ),
+//	               μ ~ Uniform(0, 1), log κ ~ Uniform(log 0.1, log 10000)
+//
+// The Binomial posterior is conjugate. The Beta-Binomial posterior over (μ, log κ) is
+// evaluated on a grid, which is deterministic and cheap because Totals take only 11 values.
+
+const (
+	betaBinomialGridSize = 100
+	betaBinomialMinKappa = 0.1
+	betaBinomialMaxKappa = 10000.0
+)
+
+// TotalsPosteriorSummary represents the fitted posterior of a Totals model
+type TotalsPosteriorSummary struct {
+	Model                string   `json:"model"`
+	MeanCorrectRate      float64  `json:"mean_correct_rate"`
+	MeanCorrectRateLower float64  `json:"mean_correct_rate_lower"`
+	MeanCorrectRateUpper float64  `json:"mean_correct_rate_upper"`
+	Alpha                float64  `json:"alpha"`
+	Beta                 float64  `json:"beta"`
+	Kappa                *float64 `json:"kappa,omitempty"`
+}
+
+// totalsDraw is one posterior draw of a Totals model. For the Binomial model every student
+// shares theta; for the Beta-Binomial model student rates are Beta(alpha, beta).
+type totalsDraw struct {
+	binomial    bool
+	theta       float64
+	alpha, beta float64
+}
+
+// logPMF returns log P(Total = k | draw)
+func (d totalsDraw) logPMF(k int) float64 {
+	n := numQuestions
+	if d.binomial {
+		return logChoose(n, k) + float64(k)*math.Log(d.theta) + float64(n-k)*math.Log(1-d.theta)
+	}
+	return logChoose(n, k) + lbeta(float64(k)+d.alpha, float64(n-k)+d.beta) - lbeta(d.alpha, d.beta)
+}
+
+// sampleStudentRate draws the correct probability of a new student
+func (d totalsDraw) sampleStudentRate(rng *rand.Rand) float64 {
+	if d.binomial {
+		return d.theta
+	}
+	return sampleBeta(rng, d.alpha, d.beta)
+}
+
+// sampleTotal draws the Total of a new student
+func (d totalsDraw) sampleTotal(rng *rand.Rand) int {
+	return sampleBinomial(rng, numQuestions, d.sampleStudentRate(rng))
+}
+
+// totalsPosterior is a fitted posterior that can be sampled
+type totalsPosterior interface {
+	sample(rng *rand.Rand) totalsDraw
+	summary() TotalsPosteriorSummary
+}
+
+// binomialTotalsPosterior is the conjugate Beta(alpha, beta) posterior of θ
+type binomialTotalsPosterior struct {
+	alpha, beta float64
+}
+
+func (p binomialTotalsPosterior) sample(rng *rand.Rand) totalsDraw {
+	return totalsDraw{binomial: true, theta: sampleBeta(rng, p.alpha, p.beta)}
+}
+
+func (p binomialTotalsPosterior) summary() TotalsPosteriorSummary {
+	lower, upper := betaCredibleInterval(p.alpha, p.beta, 0.95)
+	return TotalsPosteriorSummary{
+		Model:                "binomial",
+		MeanCorrectRate:      p.alpha / (p.alpha + p.beta),
+		MeanCorrectRateLower: lower,
+		MeanCorrectRateUpper: upper,
+		Alpha:                p.alpha,
+		Beta:                 p.beta,
+	}
+}
+
+// betaBinomialTotalsPosterior is the grid posterior of (μ, log κ)
+type betaBinomialTotalsPosterior struct {
+	mu         []float64
+	logKappa   []float64
+	weights    []float64 // normalized, indexed [i*len(logKappa) + j]
+	cumulative []float64
+	// logEvidence is the grid quadrature of log ∫ p(Totals | μ, κ) p(μ, log κ)
+	logEvidence float64
+}
+
+func (p *betaBinomialTotalsPosterior) sample(rng *rand.Rand) totalsDraw {
+	u := rng.Float64()
+	idx := sort.SearchFloat64s(p.cumulative, u)
+	if idx >= len(p.weights) {
+		idx = len(p.weights) - 1
+	}
+	i := idx / len(p.logKappa)
+	j := idx % len(p.logKappa)
+
+	// Jitter uniformly within the grid cell so draws are continuous
+	muStep := 1.0 / float64(len(p.mu))
+	kappaStep := p.logKappa[1] - p.logKappa[0]
+	mu := p.mu[i] + (rng.Float64()-0.5)*muStep
+	kappa := math.Exp(p.logKappa[j] + (rng.Float64()-0.5)*kappaStep)
+	return totalsDraw{alpha: mu * kappa, beta: (1 - mu) * kappa}
+}
+
+func (p *betaBinomialTotalsPosterior) summary() TotalsPosteriorSummary {
+	var meanMu, meanKappa, meanAlpha, meanBeta float64
+	marginalMu := make([]float64, len(p.mu))
+	for i, mu := range p.mu {
+		for j, logKappa := range p.logKappa {
+			w := p.weights[i*len(p.logKappa)+j]
+			kappa := math.Exp(logKappa)
+			meanMu += w * mu
+			meanKappa += w * kappa
+			meanAlpha += w * mu * kappa
+			meanBeta += w * (1 - mu) * kappa
+			marginalMu[i] += w
+		}
+	}
+
+	// Equal-tailed 95% interval of μ from its marginal grid distribution
+	lower, upper := p.mu[0], p.mu[len(p.mu)-1]
+	var cumulative float64
+	lowerFound := false
+	for i, w := range marginalMu {
+		cumulative += w
+		if !lowerFound && cumulative >= 0.025 {
+			lower = p.mu[i]
+			lowerFound = true
+		}
+		if cumulative >= 0.975 {
+			upper = p.mu[i]
+			break
+		}
+	}
+
+	return TotalsPosteriorSummary{
+		Model:                "beta-binomial",
+		MeanCorrectRate:      meanMu,
+		MeanCorrectRateLower: lower,
+		MeanCorrectRateUpper: upper,
+		Alpha:                meanAlpha,
+		Beta:                 meanBeta,
+		Kappa:                &meanKappa,
+	}
+}
+
+// gradeTotals returns the Total of every student, checking they are valid scores
+func gradeTotals() ([]int, error) {
+	totals := make([]int, len(grades))
+	for i, g := range grades {
+		if g.Total < 0 || g.Total > numQuestions {
+			return nil, fmt.Errorf("student %d has Total %d outside 0-%d", g.StudentID, g.Total, numQuestions)
+		}
+		totals[i] = g.Total
+	}
+	return totals, nil
+}
+
+// parseTotalsModel validates a Totals model name
+func parseTotalsModel(s string) (string, bool) {
+	switch s {
+	case "binomial", "beta-binomial":
+		return s, true
+	}
+	return "", false
+}
+
+// fitTotalsModel fits the named model to a sample of Totals
+func fitTotalsModel(model string, totals []int) totalsPosterior {
+	if model == "binomial" {
+		var correct int
+		for _, k := range totals {
+			correct += k
+		}
+		incorrect := len(totals)*numQuestions - correct
+		return binomialTotalsPosterior{alpha: 1 + float64(correct), beta: 1 + float64(incorrect)}
+	}
+	return fitBetaBinomialGrid(totals)
+}
+
+// fitBetaBinomialGrid evaluates the Beta-Binomial posterior on a (μ, log κ) grid
+func fitBetaBinomialGrid(totals []int) *betaBinomialTotalsPosterior {
+	histogram := make([]int, numQuestions+1)
+	for _, k := range totals {
+		histogram[k]++
+	}
+
+	g := betaBinomialGridSize
+	p := &betaBinomialTotalsPosterior{
+		mu:       make([]float64, g),
+		logKappa: make([]float64, g),
+		weights:  make([]float64, g*g),
+	}
+	minLog, maxLog := math.Log(betaBinomialMinKappa), math.Log(betaBinomialMaxKappa)
+	for i := 0; i < g; i++ {
+		p.mu[i] = (float64(i) + 0.5) / float64(g)
+		p.logKappa[i] = minLog + (float64(i)+0.5)*(maxLog-minLog)/float64(g)
+	}
+
+	logPosterior := make([]float64, g*g)
+	for i, mu := range p.mu {
+		for j, logKappa := range p.logKappa {
+			kappa := math.Exp(logKappa)
+			draw := totalsDraw{alpha: mu * kappa, beta: (1 - mu) * kappa}
+			var ll float64
+			for k, count := range histogram {
+				if count > 0 {
+					ll += float64(count) * draw.logPMF(k)
+				}
+			}
+			logPosterior[i*g+j] = ll
+		}
+	}
+
+	normalizer := logSumExp(logPosterior)
+	// Each cell carries prior mass 1/g for μ and 1/g for log κ
+	p.logEvidence = normalizer - 2*math.Log(float64(g))
+	p.cumulative = make([]float64, g*g)
+	var cumulative float64
+	for idx, lp := range logPosterior {
+		p.weights[idx] = math.Exp(lp - normalizer)
+		cumulative += p.weights[idx]
+		p.cumulative[idx] = cumulative
+	}
+	return p
+}
+
+// betaCredibleInterval returns the equal-tailed credible interval of Beta(a, b)
+func betaCredibleInterval(a, b, level float64) (float64, float64) {
+	tail := (1 - level) / 2
+	return betaQuantile(tail, a, b), betaQuantile(1-tail, a, b)
+}
+
+// betaQuantile inverts the regularized incomplete beta function by bisection
+func betaQuantile(p, a, b float64) float64 {
+	lo, hi := 0.0, 1.0
+	for iter := 0; iter < 100; iter++ {
+		mid := (lo + hi) / 2
+		if regularizedIncompleteBeta(mid, a, b) < p {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return (lo + hi) / 2
+}
+
+// regularizedIncompleteBeta computes I_x(a, b) with a continued fraction (Numerical Recipes betai)
+func regularizedIncompleteBeta(x, a, b float64) float64 {
+	if x <= 0 {
+		return 0.0
+	}
+	if x >= 1 {
+		return 1.0
+	}
+	front := math.Exp(a*math.Log(x) + b*math.Log(1-x) - lbeta(a, b))
+	if x < (a+1)/(a+b+2) {
+		return front * betaContinuedFraction(x, a, b) / a
+	}
+	return 1 - front*betaContinuedFraction(1-x, b, a)/b
+}
+
+func betaContinuedFraction(x, a, b float64) float64 {
+	const tiny = 1e-300
+	qab := a + b
+	qap := a + 1
+	qam := a - 1
+	c := 1.0
+	d := 1 - qab*x/qap
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	h := d
+	for m := 1; m <= 300; m++ {
+		fm := float64(m)
+		m2 := 2 * fm
+		aa := fm * (b - fm) * x / ((qam + m2) * (a + m2))
+		d = 1 + aa*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + aa/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		h *= d * c
+		aa = -(a + fm) * (qab + fm) * x / ((a + m2) * (qap + m2))
+		d = 1 + aa*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + aa/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < 1e-15 {
+			break
+		}
+	}
+	return h
+}